@@ -9,6 +9,7 @@ import (
 	"github.com/konveyor/crane/internal/apply"
 	"github.com/konveyor/crane/internal/flags"
 	"github.com/konveyor/crane/internal/kustomize"
+	"github.com/konveyor/crane/internal/secrets"
 	internalTransform "github.com/konveyor/crane/internal/transform"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -40,6 +41,8 @@ type Flags struct {
 	Overwrite         bool `mapstructure:"overwrite"`
 	// Enable ordered resource filenames for dependency-aware kubectl apply
 	Ordered bool `mapstructure:"ordered"`
+	// Key file for Secrets encrypted by crane export --secrets-key-file
+	SecretsKeyFile string `mapstructure:"secrets-key-file"`
 }
 
 func (o *Options) Complete(c *cobra.Command, args []string) error {
@@ -132,6 +135,8 @@ func addFlagsForOptions(o *Flags, cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", false, "Overwrite the output directory if it already exists")
 	// Ordered resource filenames
 	cmd.Flags().BoolVar(&o.Ordered, "ordered", false, "Add ordering prefix to resource filenames (e.g., 300_Role_*, 310_RoleBinding_*) to ensure dependency-aware kubectl apply")
+	// Secret decryption
+	cmd.Flags().StringVar(&o.SecretsKeyFile, "secrets-key-file", "", "Decrypt Secrets that crane export encrypted with --secrets-key-file using the same key file")
 }
 
 func (o *Options) run() error {
@@ -173,6 +178,15 @@ func (o *Options) run() error {
 		return fmt.Errorf("invalid kustomize-args: %w", err)
 	}
 
+	var secretsKey []byte
+	if o.SecretsKeyFile != "" {
+		secretsKey, err = secrets.LoadKey(o.SecretsKeyFile)
+		if err != nil {
+			log.Errorf("Failed to load secrets key: %v", err)
+			return err
+		}
+	}
+
 	// Create applier
 	applier := &apply.KustomizeApplier{
 		Log:               log.WithField("command", "apply").Logger,
@@ -181,6 +195,7 @@ func (o *Options) run() error {
 		KustomizeArgs:     kustomizeArgs,
 		SkipClusterScoped: o.SkipClusterScoped,
 		Ordered:           o.Ordered,
+		SecretsKey:        secretsKey,
 	}
 
 	// Determine which stages to apply
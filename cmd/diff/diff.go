@@ -0,0 +1,235 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	internalDiff "github.com/konveyor/crane/internal/diff"
+	"github.com/konveyor/crane/internal/file"
+	"github.com/konveyor/crane/internal/flags"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// DiffOptions holds CLI flags and runtime state for a diff run.
+type DiffOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+
+	cobraGlobalFlags *flags.GlobalFlags
+	globalFlags      *flags.GlobalFlags
+	log              *logrus.Logger
+
+	live       bool
+	reportFile string
+
+	// left and right are the export directories to compare; right is empty with --live.
+	left  string
+	right string
+
+	// namespace, dynamicClient, and mapper are set by Complete with --live.
+	namespace     string
+	dynamicClient dynamic.Interface
+	mapper        meta.RESTMapper
+
+	genericclioptions.IOStreams
+}
+
+// Complete records the export directories and, with --live, resolves the namespace and
+// builds the clients used to list it.
+func (o *DiffOptions) Complete(c *cobra.Command, args []string) error {
+	o.log = o.globalFlags.GetLoggerOrDefault()
+
+	if len(args) > 0 {
+		o.left = args[0]
+	}
+	if len(args) > 1 {
+		o.right = args[1]
+	}
+	if !o.live {
+		return nil
+	}
+
+	kubeconfigFlag := c.Flags().Lookup("kubeconfig")
+	if kubeconfigFlag == nil || !kubeconfigFlag.Changed {
+		emptyStr := ""
+		o.configFlags.KubeConfig = &emptyStr
+	}
+	var err error
+	o.namespace, _, err = o.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		o.log.Errorf("Failed to resolve namespace: %v", err)
+		return err
+	}
+	restConfig, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		o.log.Errorf("Cannot create rest config: %v", err)
+		return err
+	}
+	if o.dynamicClient, err = dynamic.NewForConfig(restConfig); err != nil {
+		o.log.Errorf("Cannot create dynamic client: %v", err)
+		return err
+	}
+	if o.mapper, err = o.configFlags.ToRESTMapper(); err != nil {
+		o.log.Errorf("Cannot create REST mapper: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Validate checks that the right number of export directories was given and that they exist.
+func (o *DiffOptions) Validate(args []string) error {
+	if o.live && len(args) != 1 {
+		return fmt.Errorf("--live takes exactly one export directory, got %d", len(args))
+	}
+	if !o.live && len(args) != 2 {
+		return fmt.Errorf("expected two export directories, got %d; use --live to compare one with the cluster", len(args))
+	}
+	for _, dir := range args {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("export directory %q: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("export directory %q is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+// Run compares the two sides, prints the differences, and writes the JSON report. It
+// returns internalDiff.ErrDriftDetected when any object differs.
+func (o *DiffOptions) Run() error {
+	log := o.globalFlags.GetLoggerOrDefault()
+
+	left, err := readExport(o.left)
+	if err != nil {
+		return err
+	}
+	leftName, rightName := o.left, o.right
+	var right []unstructured.Unstructured
+	if o.live {
+		left = inNamespace(left, o.namespace)
+		rightName = fmt.Sprintf("live namespace %s", o.namespace)
+		right, err = listLive(context.Background(), o.dynamicClient, o.mapper, o.namespace, left, log)
+		if err != nil {
+			log.Errorf("Failed to list namespace %s: %v", o.namespace, err)
+			return err
+		}
+	} else if right, err = readExport(o.right); err != nil {
+		return err
+	}
+	log.Debugf("Comparing %d object(s) in %s with %d in %s", len(left), leftName, len(right), rightName)
+
+	report := internalDiff.Compare(left, right)
+	report.Left, report.Right = leftName, rightName
+	internalDiff.FormatText(o.Out, report)
+
+	if o.reportFile != "" {
+		if err := writeReport(o.reportFile, report); err != nil {
+			log.Errorf("Failed to write diff report %q: %v", o.reportFile, err)
+			return err
+		}
+		log.Infof("Wrote diff report to %s", o.reportFile)
+	}
+	if report.HasDrift() {
+		return internalDiff.ErrDriftDetected
+	}
+	return nil
+}
+
+// readExport reads the manifests of an export directory, skipping failures/, pruned/,
+// and the derived trees such as resources-clean/.
+func readExport(dir string) ([]unstructured.Unstructured, error) {
+	files, err := file.ReadFiles(context.Background(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading export %q: %w", dir, err)
+	}
+	objs := make([]unstructured.Unstructured, 0, len(files))
+	for _, f := range files {
+		objs = append(objs, f.Unstructured)
+	}
+	return objs, nil
+}
+
+// inNamespace returns the objects in namespace and the cluster-scoped objects.
+func inNamespace(objs []unstructured.Unstructured, namespace string) []unstructured.Unstructured {
+	out := make([]unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		if ns := obj.GetNamespace(); ns == "" || ns == namespace {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+func writeReport(path string, report *internalDiff.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := internalDiff.FormatJSON(f, report); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// NewDiffCommand builds the cobra diff command with flags and viper wiring.
+func NewDiffCommand(streams genericclioptions.IOStreams, f *flags.GlobalFlags) *cobra.Command {
+	o := &DiffOptions{
+		configFlags:      genericclioptions.NewConfigFlags(true),
+		IOStreams:        streams,
+		cobraGlobalFlags: f,
+	}
+	cmd := &cobra.Command{
+		Use:   "diff <export-dir-a> <export-dir-b> | --live -n <namespace> <export-dir>",
+		Short: "Compare two exports, or an export with a live namespace",
+		Long: `Diff compares the objects of two export directories, or of one export
+directory and the namespace it was exported from with --live.
+
+Objects are paired by their export filename (kind, group, version,
+namespace, and name). Fields populated by the API server, such as status,
+uid, resourceVersion, and managedFields, are ignored. Each object that
+exists on one side only, or whose remaining fields differ, is printed with
+its added (+), removed (-), and changed (~) fields.
+
+With --live, every object of the exported types in the namespace is
+listed, so objects created since the export are reported as added.
+
+A JSON report is written to --report.
+
+Exit code 0 means no drift; exit code 1 means one or more objects differ
+(or another error occurred).`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Validate(args); err != nil {
+				return err
+			}
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			c.SilenceUsage = true
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+			viper.Unmarshal(&o.globalFlags)
+			viper.Unmarshal(&o.configFlags)
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.live, "live", false, "Compare the export directory with the objects in the namespace selected by -n/--namespace or the kubeconfig context")
+	cmd.Flags().StringVar(&o.reportFile, "report", "diff-report.json", "Path of the JSON diff report; empty to skip writing it")
+	o.configFlags.AddFlags(cmd.Flags())
+	flags.SetGroupedHelp(cmd, flags.KubernetesClientInheritedFlagNames())
+	return cmd
+}
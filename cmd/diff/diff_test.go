@@ -0,0 +1,160 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internalDiff "github.com/konveyor/crane/internal/diff"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// writeExport writes docs as manifests under dir/resources/app, plus a failures/ file and
+// a resources-clean/ copy that diff must ignore.
+func writeExport(t *testing.T, dir string, docs map[string]string) {
+	t.Helper()
+	for name, doc := range docs {
+		p := filepath.Join(dir, "resources", "app", name)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(doc), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{
+		filepath.Join(dir, "failures", "app", "Foo_error.yaml"),
+		filepath.Join(dir, "resources-clean", "app", "ConfigMap__v1_app_stale.yaml"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata: {name: stale, namespace: app}\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const (
+	settings    = "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: settings, namespace: app, uid: a, resourceVersion: \"1\"}\ndata: {key: a}\n"
+	settingsNew = "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: settings, namespace: app, uid: b, resourceVersion: \"5\"}\ndata: {key: b}\n"
+	web         = "apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: web, namespace: app, uid: c}\nspec: {replicas: 1}\nstatus: {replicas: 1}\n"
+)
+
+func TestRun_Dirs(t *testing.T) {
+	a, b := filepath.Join(t.TempDir(), "a"), filepath.Join(t.TempDir(), "b")
+	writeExport(t, a, map[string]string{"settings.yaml": settings, "web.yaml": web})
+	writeExport(t, b, map[string]string{"settings.yaml": settingsNew, "web.yaml": strings.Replace(web, "uid: c", "uid: d", 1)})
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+
+	var out bytes.Buffer
+	o := &DiffOptions{left: a, right: b, reportFile: reportFile, IOStreams: genericclioptions.IOStreams{Out: &out}}
+	if err := o.Run(); !errors.Is(err, internalDiff.ErrDriftDetected) {
+		t.Fatalf("Run() error = %v, want ErrDriftDetected", err)
+	}
+	if !strings.Contains(out.String(), `~ data.key: "a" -> "b"`) || strings.Contains(out.String(), "Deployment") {
+		t.Errorf("output:\n%s", out.String())
+	}
+
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report internalDiff.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Left != a || report.Changed != 1 || report.Unchanged != 1 || report.Added != 0 || report.Removed != 0 {
+		t.Errorf("report = %+v", report)
+	}
+
+	o = &DiffOptions{left: a, right: a, IOStreams: genericclioptions.IOStreams{Out: &out}}
+	if err := o.Run(); err != nil {
+		t.Errorf("Run() on identical exports = %v", err)
+	}
+}
+
+func TestRun_Live(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "export")
+	writeExport(t, dir, map[string]string{"settings.yaml": settings, "web.yaml": web})
+
+	objs := []runtime.Object{}
+	for _, doc := range []string{
+		settingsNew,
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: created, namespace: app}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: elsewhere, namespace: other}\n",
+	} {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			t.Fatal(err)
+		}
+		objs = append(objs, obj)
+	}
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMaps: "ConfigMapList"}, objs...)
+	// Deployments are not served, so the exported web Deployment is reported as removed.
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+
+	var out bytes.Buffer
+	o := &DiffOptions{
+		live:          true,
+		left:          dir,
+		namespace:     "app",
+		dynamicClient: client,
+		mapper:        mapper,
+		IOStreams:     genericclioptions.IOStreams{Out: &out},
+	}
+	if err := o.Run(); !errors.Is(err, internalDiff.ErrDriftDetected) {
+		t.Fatalf("Run() error = %v, want ErrDriftDetected", err)
+	}
+	for _, want := range []string{
+		"added    ConfigMap__v1_app_created.yaml",
+		"changed  ConfigMap__v1_app_settings.yaml",
+		"removed  Deployment_apps_v1_app_web.yaml",
+		"1 added, 1 removed, 1 changed, 0 unchanged",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestValidate_Args(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		live    bool
+		args    []string
+		wantErr string
+	}{
+		{name: "two dirs", args: []string{dir, dir}},
+		{name: "one dir", args: []string{dir}, wantErr: "expected two export directories"},
+		{name: "live", live: true, args: []string{dir}},
+		{name: "live with two dirs", live: true, args: []string{dir, dir}, wantErr: "--live takes exactly one"},
+		{name: "missing dir", args: []string{dir, filepath.Join(dir, "missing")}, wantErr: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &DiffOptions{live: tt.live}
+			err := o.Validate(tt.args)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
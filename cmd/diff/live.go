@@ -0,0 +1,84 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// listLive returns the live counterparts of exported: every object in namespace of each
+// namespaced type that was exported, so objects created since the export show up as
+// added, and the exported cluster-scoped objects fetched by name, since listing every
+// cluster-scoped object would report the rest of the cluster as added. Types the cluster
+// no longer serves are skipped with a warning; their exported objects are reported as
+// removed.
+func listLive(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, namespace string, exported []unstructured.Unstructured, log logrus.FieldLogger) ([]unstructured.Unstructured, error) {
+	var kinds []schema.GroupVersionKind
+	clusterNames := map[schema.GroupVersionKind][]string{}
+	for _, obj := range exported {
+		gvk := obj.GroupVersionKind()
+		if _, seen := clusterNames[gvk]; !seen {
+			kinds = append(kinds, gvk)
+			clusterNames[gvk] = nil
+		}
+		if obj.GetNamespace() == "" {
+			clusterNames[gvk] = append(clusterNames[gvk], obj.GetName())
+		}
+	}
+
+	var live []unstructured.Unstructured
+	for _, gvk := range kinds {
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				log.Warnf("%s is not served by the cluster; its exported objects are reported as removed", gvk)
+				continue
+			}
+			return nil, fmt.Errorf("map %s: %w", gvk, err)
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			items, err := listNamespace(ctx, client.Resource(mapping.Resource).Namespace(namespace))
+			if err != nil {
+				return nil, fmt.Errorf("list %s in namespace %s: %w", mapping.Resource.GroupResource(), namespace, err)
+			}
+			log.Debugf("Listed %d %s in namespace %s", len(items), mapping.Resource.GroupResource(), namespace)
+			live = append(live, items...)
+			continue
+		}
+		for _, name := range clusterNames[gvk] {
+			obj, err := client.Resource(mapping.Resource).Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("get %s %s: %w", mapping.Resource.GroupResource(), name, err)
+			}
+			live = append(live, *obj)
+		}
+	}
+	return live, nil
+}
+
+// listNamespace lists every object of one type, following continue tokens.
+func listNamespace(ctx context.Context, c dynamic.ResourceInterface) ([]unstructured.Unstructured, error) {
+	var items []unstructured.Unstructured
+	opts := metav1.ListOptions{Limit: 500}
+	for {
+		list, err := c.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, list.Items...)
+		if list.GetContinue() == "" {
+			return items, nil
+		}
+		opts.Continue = list.GetContinue()
+	}
+}
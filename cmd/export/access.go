@@ -0,0 +1,247 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	errorsutil "k8s.io/apimachinery/pkg/util/errors"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// accessCheck is one permission export needs: verb on resource in namespace ("" for
+// cluster-scoped). purpose says which export step needs it.
+type accessCheck struct {
+	namespace string
+	gvr       schema.GroupVersionResource
+	verb      string
+	purpose   string
+
+	allowed bool
+	reason  string
+}
+
+// accessChecks returns the permissions an export of namespaces needs: list on every
+// admitted namespaced type in each namespace, list on the cluster-scoped RBAC kinds the
+// RBAC filter reads, get on CRDs for collectRelatedCRDs, and get on the kinds followed by
+// --follow-cluster-references.
+func (o *ExportOptions) accessChecks(namespaces []string, namespaced, clusterScoped []*groupResource) []*accessCheck {
+	var checks []*accessCheck
+	for _, namespace := range namespaces {
+		for _, g := range namespaced {
+			checks = append(checks, &accessCheck{namespace: namespace, gvr: g.gvr(), verb: "list"})
+		}
+	}
+	for _, g := range clusterScoped {
+		checks = append(checks, &accessCheck{gvr: g.gvr(), verb: "list", purpose: "RBAC filter"})
+	}
+	checks = append(checks, &accessCheck{gvr: crdGVR, verb: "get", purpose: "CRDs of exported custom resources"})
+	if o.followClusterReferences {
+		for _, k := range referencedKinds {
+			if reason, _ := o.resourceFilter.skipReason(k.gvr.GroupVersion(), metav1.APIResource{Name: k.gvr.Resource}); reason != "" {
+				continue
+			}
+			checks = append(checks, &accessCheck{gvr: k.gvr, verb: "get", purpose: "--follow-cluster-references"})
+		}
+	}
+	return checks
+}
+
+// gvr returns the GroupVersionResource of g.
+func (g *groupResource) gvr() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: g.APIGroup, Version: g.APIVersion, Resource: g.APIResource.Name}
+}
+
+// reviewAccess decides every check for the identity client authenticates as, including
+// impersonation. Namespaced checks are answered from one SelfSubjectRulesReview per
+// namespace; checks the rules do not allow are confirmed with a SelfSubjectAccessReview
+// when the rules are incomplete (e.g. a webhook authorizer is configured). Cluster-scoped
+// checks always use SelfSubjectAccessReview.
+func reviewAccess(ctx context.Context, client authorizationv1client.AuthorizationV1Interface, checks []*accessCheck, log logrus.FieldLogger) error {
+	rules := map[string]*authorizationv1.SubjectRulesReviewStatus{}
+	for _, c := range checks {
+		if c.namespace != "" {
+			status, ok := rules[c.namespace]
+			if !ok {
+				review, err := client.SelfSubjectRulesReviews().Create(ctx, &authorizationv1.SelfSubjectRulesReview{
+					Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: c.namespace},
+				}, metav1.CreateOptions{})
+				if err != nil {
+					log.Warnf("Cannot review rules in namespace %q, checking each resource instead: %v", c.namespace, err)
+				} else {
+					status = &review.Status
+					if status.Incomplete {
+						log.Debugf("Rules in namespace %q are incomplete (%s); confirming denials with access reviews", c.namespace, status.EvaluationError)
+					}
+				}
+				rules[c.namespace] = status
+			}
+			if status != nil && rulesAllow(status.ResourceRules, c.verb, c.gvr.GroupResource()) {
+				c.allowed = true
+				continue
+			}
+			if status != nil && !status.Incomplete {
+				c.reason = "no matching rule"
+				continue
+			}
+		}
+
+		review, err := client.SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: c.namespace,
+					Verb:      c.verb,
+					Group:     c.gvr.Group,
+					Version:   c.gvr.Version,
+					Resource:  c.gvr.Resource,
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("review %s on %s: %w", c.verb, groupResourceName(c.gvr.Group, c.gvr.Resource), err)
+		}
+		c.allowed = review.Status.Allowed && !review.Status.Denied
+		c.reason = review.Status.Reason
+		if review.Status.EvaluationError != "" {
+			c.reason = strings.TrimSpace(c.reason + " " + review.Status.EvaluationError)
+		}
+	}
+	return nil
+}
+
+// rulesAllow reports whether rules grant verb on every object of resource. Rules limited
+// to resourceNames do not count, since list and a CRD lookup by name are not covered.
+func rulesAllow(rules []authorizationv1.ResourceRule, verb string, resource schema.GroupResource) bool {
+	for _, r := range rules {
+		if len(r.ResourceNames) > 0 {
+			continue
+		}
+		if matchesRule(r.Verbs, verb) && matchesRule(r.APIGroups, resource.Group) && matchesRule(r.Resources, resource.Resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesRule(values []string, want string) bool {
+	for _, v := range values {
+		if v == "*" || v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// printAccessChecks writes one row per check, namespaced checks first.
+func printAccessChecks(out io.Writer, checks []*accessCheck) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tRESOURCE\tVERB\tACCESS\tNEEDED FOR\tREASON")
+	for _, clusterScoped := range []bool{false, true} {
+		for _, c := range checks {
+			if (c.namespace == "") != clusterScoped {
+				continue
+			}
+			namespace := c.namespace
+			if namespace == "" {
+				namespace = "(cluster)"
+			}
+			access := "denied"
+			if c.allowed {
+				access = "allowed"
+			}
+			purpose := c.purpose
+			if purpose == "" {
+				purpose = "export"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", namespace, groupResourceName(c.gvr.Group, c.gvr.Resource), c.verb, access, purpose, c.reason)
+		}
+	}
+	return w.Flush()
+}
+
+// namespaceAccess returns how many of the namespaced checks for namespace are allowed.
+func namespaceAccess(checks []*accessCheck, namespace string) (allowed, total int) {
+	for _, c := range checks {
+		if c.namespace == namespace {
+			total++
+			if c.allowed {
+				allowed++
+			}
+		}
+	}
+	return allowed, total
+}
+
+// accessFailures applies the --fail-on policy to the checks, as listFailures does to the
+// errors of a real export: forbidden-only fails namespaces where nothing can be listed,
+// any fails on every denied check, and none never fails.
+func accessFailures(policy string, namespaces []string, checks []*accessCheck) []error {
+	var errs []error
+	switch policy {
+	case failOnAny:
+		denied := 0
+		for _, c := range checks {
+			if !c.allowed {
+				denied++
+			}
+		}
+		if denied > 0 {
+			errs = append(errs, fmt.Errorf("%d of %d permission checks denied (--fail-on=%s)", denied, len(checks), failOnAny))
+		}
+	case failOnForbiddenOnly, "":
+		for _, namespace := range namespaces {
+			allowed, total := namespaceAccess(checks, namespace)
+			if total > 0 && allowed == 0 {
+				errs = append(errs, fmt.Errorf("no resource type can be listed in namespace %q -- verify the namespace exists and your user has list permissions", namespace))
+			}
+		}
+	}
+	return errs
+}
+
+// runAccessCheck is --check-access: it discovers what an export of namespaces would
+// list, reviews each permission, and prints the result without writing anything.
+func (o *ExportOptions) runAccessCheck(source exportSource, namespaces []string, log logrus.FieldLogger) error {
+	live, ok := source.(*liveSource)
+	if !ok {
+		return fmt.Errorf("--check-access needs a live cluster")
+	}
+	resourceLists, err := source.discover(log)
+	if err != nil {
+		return err
+	}
+	namespacedLists, clusterScopedLists := splitResourceListsByScope(resourceLists)
+	namespaced, _ := admittedGroupResources(namespacedLists, o.resourceFilter, log)
+	clusterScoped, _ := admittedGroupResources(clusterScopedLists, o.resourceFilter, log)
+
+	checks := o.accessChecks(namespaces, namespaced, clusterScoped)
+	if err := reviewAccess(context.Background(), live.kubeClient.AuthorizationV1(), checks, log); err != nil {
+		log.Errorf("Cannot review access: %v", err)
+		return err
+	}
+
+	out := o.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	if err := printAccessChecks(out, checks); err != nil {
+		return err
+	}
+	for _, namespace := range namespaces {
+		allowed, total := namespaceAccess(checks, namespace)
+		log.Infof("Namespace %q: %d of %d resource types can be exported", namespace, allowed, total)
+	}
+	for _, c := range checks {
+		if c.namespace == "" && !c.allowed {
+			log.Warnf("Cannot %s %s; %s will be incomplete", c.verb, groupResourceName(c.gvr.Group, c.gvr.Resource), c.purpose)
+		}
+	}
+	return errorsutil.NewAggregate(accessFailures(o.failOn, namespaces, checks))
+}
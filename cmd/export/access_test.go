@@ -0,0 +1,160 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+func TestRulesAllow(t *testing.T) {
+	rules := []authorizationv1.ResourceRule{
+		{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"configmaps"}},
+		{Verbs: []string{"*"}, APIGroups: []string{"apps"}, Resources: []string{"*"}},
+		{Verbs: []string{"list"}, APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"one"}},
+	}
+	tests := []struct {
+		verb     string
+		resource schema.GroupResource
+		want     bool
+	}{
+		{"list", schema.GroupResource{Resource: "configmaps"}, true},
+		{"list", schema.GroupResource{Group: "apps", Resource: "deployments"}, true},
+		{"list", schema.GroupResource{Resource: "secrets"}, false},
+		{"list", schema.GroupResource{Group: "batch", Resource: "jobs"}, false},
+		{"delete", schema.GroupResource{Resource: "configmaps"}, false},
+	}
+	for _, tt := range tests {
+		if got := rulesAllow(rules, tt.verb, tt.resource); got != tt.want {
+			t.Errorf("rulesAllow(%s %s) = %v, want %v", tt.verb, tt.resource, got, tt.want)
+		}
+	}
+}
+
+func TestReviewAccess(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectrulesreviews", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		review := action.(kubetesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectRulesReview)
+		review.Status.ResourceRules = []authorizationv1.ResourceRule{
+			{Verbs: []string{"list"}, APIGroups: []string{""}, Resources: []string{"configmaps"}},
+		}
+		review.Status.Incomplete = review.Spec.Namespace == "webhook"
+		return true, review, nil
+	})
+	var accessReviews []string
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		review := action.(kubetesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		accessReviews = append(accessReviews, attrs.Namespace+"/"+attrs.Resource)
+		review.Status.Allowed = attrs.Resource == "secrets" || attrs.Resource == "customresourcedefinitions"
+		if !review.Status.Allowed {
+			review.Status.Reason = "denied by test"
+		}
+		return true, review, nil
+	})
+
+	configMaps := &groupResource{APIVersion: "v1", APIResource: metav1.APIResource{Name: "configmaps", Namespaced: true}}
+	secrets := &groupResource{APIVersion: "v1", APIResource: metav1.APIResource{Name: "secrets", Namespaced: true}}
+	clusterRoles := &groupResource{APIGroup: "rbac.authorization.k8s.io", APIVersion: "v1", APIResource: metav1.APIResource{Name: "clusterroles"}}
+	o := &ExportOptions{}
+	checks := o.accessChecks([]string{"app", "webhook"}, []*groupResource{configMaps, secrets}, []*groupResource{clusterRoles})
+
+	if err := reviewAccess(context.Background(), client.AuthorizationV1(), checks, testLogger()); err != nil {
+		t.Fatalf("reviewAccess: %v", err)
+	}
+	got := map[string]bool{}
+	for _, c := range checks {
+		got[c.namespace+"/"+c.gvr.Resource] = c.allowed
+	}
+	want := map[string]bool{
+		"app/configmaps":             true,
+		"app/secrets":                false, // complete rules, no access review
+		"webhook/configmaps":         true,
+		"webhook/secrets":            true, // incomplete rules, confirmed by an access review
+		"/clusterroles":              false,
+		"/customresourcedefinitions": true,
+	}
+	for key, allowed := range want {
+		if got[key] != allowed {
+			t.Errorf("%s allowed = %v, want %v", key, got[key], allowed)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("checks = %v, want %v (no referenced kinds without --follow-cluster-references)", got, want)
+	}
+	if strings.Join(accessReviews, ",") != "webhook/secrets,/clusterroles,/customresourcedefinitions" {
+		t.Errorf("access reviews = %v", accessReviews)
+	}
+
+	var out bytes.Buffer
+	if err := printAccessChecks(&out, checks); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 7 || !strings.HasPrefix(lines[0], "NAMESPACE") {
+		t.Fatalf("table:\n%s", out.String())
+	}
+	if !strings.Contains(lines[5], "(cluster)") || !strings.Contains(lines[5], "clusterroles.rbac.authorization.k8s.io") || !strings.Contains(lines[5], "denied by test") {
+		t.Errorf("cluster row = %q", lines[5])
+	}
+}
+
+func TestAccessFailures(t *testing.T) {
+	checks := []*accessCheck{
+		{namespace: "a", allowed: false},
+		{namespace: "b", allowed: true},
+		{namespace: "b", allowed: false},
+		{allowed: false},
+	}
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{failOnForbiddenOnly, []string{`namespace "a"`}},
+		{"", []string{`namespace "a"`}},
+		{failOnAny, []string{"3 of 4 permission checks denied"}},
+		{failOnNone, nil},
+	}
+	for _, tt := range tests {
+		errs := accessFailures(tt.policy, []string{"a", "b"}, checks)
+		if len(errs) != len(tt.want) {
+			t.Fatalf("accessFailures(%q) = %v, want %v", tt.policy, errs, tt.want)
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(errs[i].Error(), want) {
+				t.Errorf("accessFailures(%q)[%d] = %v, want %q", tt.policy, i, errs[i], want)
+			}
+		}
+	}
+}
+
+func TestValidate_CheckAccessNeedsLiveCluster(t *testing.T) {
+	o := &ExportOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		checkAccess: true,
+		fromFile:    writeOfflineDump(t),
+	}
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "--check-access") {
+		t.Fatalf("expected --check-access error, got %v", err)
+	}
+}
+
+func TestValidate_CheckAccessWritesNoArchive(t *testing.T) {
+	o := &ExportOptions{
+		configFlags:   genericclioptions.NewConfigFlags(true),
+		checkAccess:   true,
+		outputArchive: filepath.Join(t.TempDir(), "export.tar.gz"),
+	}
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "--check-access with --output-archive") {
+		t.Fatalf("expected --check-access with --output-archive error, got %v", err)
+	}
+}
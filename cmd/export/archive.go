@@ -0,0 +1,39 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/konveyor/crane/internal/archive"
+	"github.com/konveyor/crane/internal/file"
+)
+
+// runToArchive exports into a temporary directory and packs it into outputArchive, so no
+// export tree is left on disk. The archive is written whenever the export got as far as
+// writing its manifest, including runs that end with non-fatal errors.
+func (o *ExportOptions) runToArchive() error {
+	log := o.globalFlags.GetLoggerOrDefault()
+
+	staging, err := os.MkdirTemp("", "crane-export-")
+	if err != nil {
+		log.Errorf("Cannot create staging directory: %v", err)
+		return err
+	}
+	defer os.RemoveAll(staging)
+	o.exportDir = filepath.Join(staging, "export")
+
+	runErr := o.run()
+	if _, err := os.Stat(filepath.Join(o.exportDir, file.ExportManifestFileName)); err != nil {
+		if runErr == nil {
+			runErr = fmt.Errorf("export did not write %s", file.ExportManifestFileName)
+		}
+		return runErr
+	}
+	if err := archive.Create(o.exportDir, o.outputArchive, o.archiveFormat); err != nil {
+		log.Errorf("Cannot write export archive: %v", err)
+		return err
+	}
+	log.Infof("Wrote %s export archive %s", o.archiveFormat, o.outputArchive)
+	return runErr
+}
@@ -0,0 +1,55 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/crane/internal/archive"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestValidate_OutputArchive(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "export.tar.gz")
+	if err := os.WriteFile(existing, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		archivePath string
+		format      string
+		incremental bool
+		overwrite   bool
+		wantErr     string
+	}{
+		{name: "tar.gz", archivePath: filepath.Join(t.TempDir(), "export.tar.gz"), format: archive.FormatTarGz},
+		{name: "oci", archivePath: filepath.Join(t.TempDir(), "export.tar"), format: archive.FormatOCI},
+		{name: "unknown format", archivePath: filepath.Join(t.TempDir(), "export.zip"), format: "zip", wantErr: "--archive-format"},
+		{name: "incremental", archivePath: filepath.Join(t.TempDir(), "export.tar.gz"), format: archive.FormatTarGz, incremental: true, wantErr: "--incremental"},
+		{name: "existing archive", archivePath: existing, format: archive.FormatTarGz, wantErr: "already exists"},
+		{name: "existing archive with overwrite", archivePath: existing, format: archive.FormatTarGz, overwrite: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &ExportOptions{
+				configFlags:   genericclioptions.NewConfigFlags(true),
+				outputArchive: tt.archivePath,
+				archiveFormat: tt.format,
+				incremental:   tt.incremental,
+				overwrite:     tt.overwrite,
+			}
+			err := o.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/konveyor/crane/internal/normalize"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// writeCleanResources is --normalize: it copies every manifest under resources/ to the
+// same path under resources-clean/ with the fields the API server populates removed, so
+// that diffs between two exports show only changes users made. It reads the files written
+// by this run, including streamed and unchanged incremental ones, and rebuilds the tree
+// each time. Files that cannot be copied are returned as errors and the rest are written.
+func writeCleanResources(exportDir string, log logrus.FieldLogger) (int, []error) {
+	cleanDir := filepath.Join(exportDir, file.CleanResourcesDirName)
+	if err := os.RemoveAll(cleanDir); err != nil {
+		return 0, []error{fmt.Errorf("clear %s: %w", cleanDir, err)}
+	}
+	resourcesDir := filepath.Join(exportDir, "resources")
+	written := 0
+	var errs []error
+	err := filepath.WalkDir(resourcesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(resourcesDir, path)
+		if err != nil {
+			return err
+		}
+		if err := writeCleanResource(path, filepath.Join(cleanDir, rel)); err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		written++
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	log.Debugf("Wrote %d normalized manifest(s) to %s", written, cleanDir)
+	return written, errs
+}
+
+// writeCleanResource writes the normalized form of the manifest at src to dest.
+func writeCleanResource(src, dest string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	obj := unstructured.Unstructured{}
+	if err := yaml.Unmarshal(data, &obj.Object); err != nil {
+		return fmt.Errorf("parse %s: %w", src, err)
+	}
+	normalize.Object(&obj)
+	if data, err = yaml.Marshal(obj.Object); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0666)
+}
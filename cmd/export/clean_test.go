@@ -0,0 +1,72 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/crane/internal/file"
+)
+
+func TestWriteCleanResources(t *testing.T) {
+	exportDir := t.TempDir()
+	raw := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: app\n  uid: abc\n  resourceVersion: \"7\"\ndata:\n  key: value\n"
+	rawPath := filepath.Join(exportDir, "resources", "app", "ConfigMap__v1_app_settings.yaml")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rawPath, []byte(raw), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// A stale file from a previous run is removed.
+	stale := filepath.Join(exportDir, file.CleanResourcesDirName, "app", "stale.yaml")
+	if err := os.MkdirAll(filepath.Dir(stale), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	count, errs := writeCleanResources(exportDir, testLogger())
+	if count != 1 || len(errs) != 0 {
+		t.Fatalf("writeCleanResources = %d, %v", count, errs)
+	}
+	clean, err := os.ReadFile(filepath.Join(exportDir, file.CleanResourcesDirName, "app", "ConfigMap__v1_app_settings.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(clean), "uid") || strings.Contains(string(clean), "resourceVersion") || !strings.Contains(string(clean), "key: value") {
+		t.Errorf("clean manifest:\n%s", clean)
+	}
+	if got, _ := os.ReadFile(rawPath); string(got) != raw {
+		t.Errorf("raw manifest changed:\n%s", got)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale clean manifest was kept: %v", err)
+	}
+}
+
+func TestRun_Normalize(t *testing.T) {
+	filter, err := newResourceFilter(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportDir := filepath.Join(t.TempDir(), "export")
+	o := &ExportOptions{
+		exportDir:      exportDir,
+		fromFile:       writeOfflineDump(t),
+		namespaces:     []string{"app"},
+		resourceFilter: filter,
+		normalize:      true,
+	}
+	if err := o.run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	raw := exportedFiles(t, filepath.Join(exportDir, "resources"))
+	clean := exportedFiles(t, filepath.Join(exportDir, file.CleanResourcesDirName))
+	if len(raw) == 0 || !reflect.DeepEqual(raw, clean) {
+		t.Errorf("resources-clean files = %v\nwant %v", clean, raw)
+	}
+}
@@ -3,10 +3,12 @@ package export
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/konveyor/crane-lib/apigroups"
+	"github.com/konveyor/crane/internal/file"
 	"github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -85,8 +87,9 @@ func getOperatorManager(obj *unstructured.Unstructured) string {
 // collectRelatedCRDs returns synthetic groupResource rows for CRDs backing custom
 // API types that appear in resources (deduplicated by plural.group). Built-in API
 // groups are skipped. Failed GETs are returned as groupResourceError entries for
-// the same failures directory as list errors.
-func collectRelatedCRDs(requestTimeout time.Duration, resources []*groupResource, dynamicClient dynamic.Interface, log logrus.FieldLogger, userSkipGroups, userIncludeGroups []string) ([]*groupResource, []*groupResourceError) {
+// the same failures directory as list errors. Operator-managed CRDs are returned as
+// skipped, sorted by name.
+func collectRelatedCRDs(requestTimeout time.Duration, resources []*groupResource, dynamicClient dynamic.Interface, log logrus.FieldLogger, userSkipGroups, userIncludeGroups []string) ([]*groupResource, []*groupResourceError, []file.SkippedResource) {
 	skipSet := normalizeGroupSet(userSkipGroups)
 	includeSet := normalizeGroupSet(userIncludeGroups)
 
@@ -107,13 +110,14 @@ func collectRelatedCRDs(requestTimeout time.Duration, resources []*groupResource
 
 	if len(seen) == 0 {
 		log.Debug("No eligible custom resource groups found, skipping CRD collection")
-		return nil, nil
+		return nil, nil, nil
 	}
 	log.Debugf("Collecting CRDs for %d custom resource groups", len(seen))
 
 	crdClient := dynamicClient.Resource(crdGVR)
 	out := make([]*groupResource, 0, len(seen))
 	var outErrs []*groupResourceError
+	var skipped []file.SkippedResource
 	for crdName := range seen {
 		// Create fresh context with timeout for each CRD Get request
 		ctx := context.Background()
@@ -149,6 +153,15 @@ func collectRelatedCRDs(requestTimeout time.Duration, resources []*groupResource
 
 		if manager := getOperatorManager(obj); manager != "" {
 			log.Warnf("Skipping CRD %q: managed by %s; install the operator on the target cluster instead", crdName, manager)
+			skipped = append(skipped, file.SkippedResource{
+				Group:    crdGVR.Group,
+				Version:  crdGVR.Version,
+				Resource: crdGVR.Resource,
+				Kind:     "CustomResourceDefinition",
+				Name:     crdName,
+				Reason:   file.SkipReasonOperatorManagedCRD,
+				Message:  "managed by " + manager,
+			})
 			continue
 		}
 
@@ -169,5 +182,6 @@ func collectRelatedCRDs(requestTimeout time.Duration, resources []*groupResource
 			},
 		})
 	}
-	return out, outErrs
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Name < skipped[j].Name })
+	return out, outErrs, skipped
 }
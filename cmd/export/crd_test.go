@@ -4,6 +4,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/konveyor/crane/internal/file"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -88,7 +89,7 @@ func TestCollectRelatedCRDs_customResourceOneCRD(t *testing.T) {
 	client := fake.NewSimpleDynamicClient(scheme, crdUnstructured("widgets.example.com"))
 	log := testLogger()
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -121,7 +122,7 @@ func TestCollectRelatedCRDs_builtinGroupNoFetch(t *testing.T) {
 			Items: []unstructured.Unstructured{{}},
 		},
 	}
-	got, errs := collectRelatedCRDs(0, []*groupResource{gr}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{gr}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -139,7 +140,7 @@ func TestCollectRelatedCRDs_dedupePluralGroup(t *testing.T) {
 	w2 := widgetGroupResource()
 	w2.objects.Items[0].SetName("w2")
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{w1, w2}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{w1, w2}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -156,7 +157,7 @@ func TestCollectRelatedCRDs_skipsSubresourceName(t *testing.T) {
 	gr := widgetGroupResource()
 	gr.APIResource.Name = "widgets/status"
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{gr}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{gr}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -187,7 +188,7 @@ func TestCollectRelatedCRDs_multipleDistinctCRDs(t *testing.T) {
 		},
 	}
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{widgetGroupResource(), gadget}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{widgetGroupResource(), gadget}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -206,7 +207,7 @@ func TestCollectRelatedCRDs_getFailureReturnsGroupResourceError(t *testing.T) {
 	client := fake.NewSimpleDynamicClient(scheme)
 	log := testLogger()
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
 	if len(got) != 0 {
 		t.Fatalf("expected no CRD rows, got %d", len(got))
 	}
@@ -243,7 +244,7 @@ func TestCollectRelatedCRDs_IncludeOverridesBuiltinGroup(t *testing.T) {
 		},
 	}
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{gr}, client, log, nil, []string{"route.openshift.io"})
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{gr}, client, log, nil, []string{"route.openshift.io"})
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -261,7 +262,7 @@ func TestCollectRelatedCRDs_skipsOLMManagedCRD(t *testing.T) {
 	client := fake.NewSimpleDynamicClient(scheme, crd)
 	log := testLogger()
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -276,7 +277,7 @@ func TestCollectRelatedCRDs_skipsManagedByLabel(t *testing.T) {
 	client := fake.NewSimpleDynamicClient(scheme, crd)
 	log := testLogger()
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -293,7 +294,7 @@ func TestCollectRelatedCRDs_skipsOperatorFrameworkAnnotation(t *testing.T) {
 	client := fake.NewSimpleDynamicClient(scheme, crd)
 	log := testLogger()
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -308,13 +309,16 @@ func TestCollectRelatedCRDs_skipsOwnerReferenceCRD(t *testing.T) {
 	client := fake.NewSimpleDynamicClient(scheme, crd)
 	log := testLogger()
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
+	got, errs, skipped := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
 	if len(got) != 0 {
 		t.Fatalf("expected owner-referenced CRD to be skipped, got %d", len(got))
 	}
+	if len(skipped) != 1 || skipped[0].Name != "widgets.example.com" || skipped[0].Reason != file.SkipReasonOperatorManagedCRD {
+		t.Fatalf("expected operator-managed skip record, got %+v", skipped)
+	}
 }
 
 func TestCollectRelatedCRDs_exportsUnmanagedCRD(t *testing.T) {
@@ -323,7 +327,7 @@ func TestCollectRelatedCRDs_exportsUnmanagedCRD(t *testing.T) {
 	client := fake.NewSimpleDynamicClient(scheme, crd)
 	log := testLogger()
 
-	got, errs := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(0, []*groupResource{widgetGroupResource()}, client, log, nil, nil)
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errors: %v", errs)
 	}
@@ -345,7 +349,7 @@ func TestCollectRelatedCRDs_timeoutError(t *testing.T) {
 	gr := widgetGroupResource()
 
 	// Pass non-zero timeout
-	got, errs := collectRelatedCRDs(100, []*groupResource{gr}, client, log, nil, nil)
+	got, errs, _ := collectRelatedCRDs(100, []*groupResource{gr}, client, log, nil, nil)
 
 	if len(got) != 0 {
 		t.Fatalf("expected 0 CRDs on timeout, got %d", len(got))
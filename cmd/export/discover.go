@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/konveyor/crane/internal/file"
@@ -29,6 +31,15 @@ type groupResource struct {
 	APIGroupVersion string
 	APIResource     metav1.APIResource
 	objects         *unstructured.UnstructuredList
+	// streamed is set when the objects were written while listing (--stream); objects
+	// then holds only index entries, and refs the cluster references found in them.
+	streamed bool
+	refs     []clusterReference
+}
+
+// skipped describes g as a resource type that was not exported.
+func (g *groupResource) skipped(reason, message string) file.SkippedResource {
+	return newSkippedResource(schema.GroupVersion{Group: g.APIGroup, Version: g.APIVersion}, g.APIResource, reason, message)
 }
 
 type groupResourceError struct {
@@ -83,10 +94,20 @@ func prepareFailuresDir(failuresDir string) error {
 }
 
 // writeResources writes each object in resources to a YAML file under resourceDir
-// or clusterResourceDir when the object has no namespace.
+// or clusterResourceDir when the object has no namespace. Streamed resources were
+// already written while listing and are skipped.
 func writeResources(resources []*groupResource, clusterResourceDir string, resourceDir string, log logrus.FieldLogger) []error {
+	return writeObservedResources(resources, clusterResourceDir, resourceDir, nil, log)
+}
+
+// writeObservedResources is writeResources calling written, if set, after each file is
+// written, so progress can be reported.
+func writeObservedResources(resources []*groupResource, clusterResourceDir string, resourceDir string, written func(path string, obj unstructured.Unstructured), log logrus.FieldLogger) []error {
 	errs := []error{}
 	for _, r := range resources {
+		if r.streamed {
+			continue
+		}
 		log.Debugf("Writing objects of resource: %s to the output directory", r.APIResource.Name)
 
 		kind := r.APIResource.Kind
@@ -124,7 +145,9 @@ func writeResources(resources []*groupResource, clusterResourceDir string, resou
 				errs = append(errs, err)
 				continue
 			}
-
+			if written != nil {
+				written(path, obj)
+			}
 		}
 	}
 
@@ -203,13 +226,37 @@ func discoverPreferredResources(
 	return lists, nil
 }
 
-// resourceToExtract lists objects for each admitted API type in namespace (or cluster-wide
-// for allowed cluster-scoped kinds: ClusterRoleBinding, ClusterRole, SCC). It returns resources
-// with non-empty lists and a parallel slice of per-type list errors.
-func resourceToExtract(requestTimeout time.Duration, namespace string, labelSelector string, dynamicClient dynamic.Interface, lists []*metav1.APIResourceList, log logrus.FieldLogger) ([]*groupResource, []*groupResourceError) {
-	resources := []*groupResource{}
-	errors := []*groupResourceError{}
+// splitResourceListsByScope separates each API resource list into its namespaced and
+// cluster-scoped resources, so cluster-scoped types are listed once per export run
+// regardless of how many namespaces are exported. Lists left empty are dropped.
+func splitResourceListsByScope(lists []*metav1.APIResourceList) (namespaced, clusterScoped []*metav1.APIResourceList) {
+	for _, list := range lists {
+		nsList := &metav1.APIResourceList{TypeMeta: list.TypeMeta, GroupVersion: list.GroupVersion}
+		clusterList := &metav1.APIResourceList{TypeMeta: list.TypeMeta, GroupVersion: list.GroupVersion}
+		for _, resource := range list.APIResources {
+			if resource.Namespaced {
+				nsList.APIResources = append(nsList.APIResources, resource)
+			} else {
+				clusterList.APIResources = append(clusterList.APIResources, resource)
+			}
+		}
+		if len(nsList.APIResources) > 0 {
+			namespaced = append(namespaced, nsList)
+		}
+		if len(clusterList.APIResources) > 0 {
+			clusterScoped = append(clusterScoped, clusterList)
+		}
+	}
+	return namespaced, clusterScoped
+}
 
+// admittedGroupResources returns a groupResource for every listable, admitted API type
+// in lists, in discovery order. Types without verbs, cluster-scoped types outside the
+// RBAC/SCC allowlist, and types rejected by filter are skipped; the latter two are returned
+// as skipped so they can be recorded in the export manifest.
+func admittedGroupResources(lists []*metav1.APIResourceList, filter resourceFilter, log logrus.FieldLogger) ([]*groupResource, []file.SkippedResource) {
+	candidates := []*groupResource{}
+	skipped := []file.SkippedResource{}
 	for _, list := range lists {
 		if len(list.APIResources) == 0 {
 			log.Debugf("Skipping group version %q: no API resources", list.GroupVersion)
@@ -226,59 +273,178 @@ func resourceToExtract(requestTimeout time.Duration, namespace string, labelSele
 				continue
 			}
 
-			// TODO: alpatel: put this behing a flag
-			if resource.Kind == "Event" {
-				log.Debugf("Skipping extracting events")
-				continue
-			}
-
 			if !isAdmittedResource(gv, resource) {
 				log.Debugf("Resource: %s.%s is clusterscoped or not admitted kind, skipping", gv.String(), resource.Kind)
+				skipped = append(skipped, newSkippedResource(gv, resource, file.SkipReasonNotAdmitted, ""))
 				continue
 			}
 
-			log.Debugf("Processing resource: %s.%s", gv.String(), resource.Kind)
+			if reason, message := filter.skipReason(gv, resource); reason != "" {
+				log.Debugf("Skipping resource %s: %s", groupResourceName(gv.Group, resource.Name), message)
+				skipped = append(skipped, newSkippedResource(gv, resource, reason, message))
+				continue
+			}
 
-			g := &groupResource{
+			candidates = append(candidates, &groupResource{
 				APIGroup:        gv.Group,
 				APIVersion:      gv.Version,
 				APIGroupVersion: gv.String(),
 				APIResource:     resource,
-			}
+			})
+		}
+	}
+	return candidates, skipped
+}
 
-			objs, err := getObjects(requestTimeout, g, namespace, labelSelector, dynamicClient, log)
-			if err != nil {
-				// Check if error is due to timeout/deadline exceeded - fail fast
-				if apierrors.IsTimeout(err) || strings.Contains(err.Error(), "context deadline exceeded") {
-					log.Errorf("Request timeout exceeded for groupVersion %s, resource: %s, kind: %s: %v", g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind, err)
-					return nil, []*groupResourceError{{resource, err}}
+// newSkippedResource describes a discovered API type that was not exported.
+func newSkippedResource(gv schema.GroupVersion, resource metav1.APIResource, reason, message string) file.SkippedResource {
+	return file.SkippedResource{
+		Group:    gv.Group,
+		Version:  gv.Version,
+		Resource: resource.Name,
+		Kind:     resource.Kind,
+		Reason:   reason,
+		Message:  message,
+	}
+}
+
+// listResult is the outcome of listing a single groupResource. skipped is set when
+// the list was never attempted because an earlier request timed out.
+type listResult struct {
+	objects *unstructured.UnstructuredList
+	err     error
+	skipped bool
+}
+
+// isTimeoutError reports whether err is an API timeout or an expired client-side deadline.
+func isTimeoutError(err error) bool {
+	return apierrors.IsTimeout(err) || strings.Contains(err.Error(), "context deadline exceeded")
+}
+
+// timeoutError returns the first error in errs that is a timeout, or nil.
+func timeoutError(errs []*groupResourceError) *groupResourceError {
+	for _, resErr := range errs {
+		if resErr != nil && resErr.Error != nil && isTimeoutError(resErr.Error) {
+			return resErr
+		}
+	}
+	return nil
+}
+
+// listFunc lists the objects of one groupResource.
+type listFunc func(g *groupResource) (*unstructured.UnstructuredList, error)
+
+// listGroupResources lists every candidate with at most concurrency requests in flight.
+// Results are indexed like candidates so callers can assemble them in discovery order.
+// Once any list times out, candidates that have not started yet are marked skipped.
+func listGroupResources(concurrency int, candidates []*groupResource, list listFunc, log logrus.FieldLogger) []listResult {
+	results := make([]listResult, len(candidates))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(candidates) {
+		concurrency = len(candidates)
+	}
+
+	var timedOut atomic.Bool
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if timedOut.Load() {
+					results[i] = listResult{skipped: true}
+					continue
 				}
-				switch {
-				case apierrors.IsForbidden(err):
-					log.Debugf("Access denied for groupVersion %s, resource: %s, kind: %s (expected for namespace-admin users)", g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind)
-				case apierrors.IsMethodNotSupported(err):
-					log.Warnf("List method not supported on the groupVersion %s, resource: %s, kind: %s", g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind)
-				case apierrors.IsNotFound(err):
-					log.Debugf("Resource not found (virtual resource), groupVersion %s, resource: %s, kind: %s", g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind)
-				default:
-					log.Errorf("Error listing objects: %v, groupVersion %s, resource: %s, kind: %s", err, g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind)
+				g := candidates[i]
+				log.Debugf("Processing resource: %s.%s", g.APIGroupVersion, g.APIResource.Kind)
+				objs, err := list(g)
+				if err != nil && isTimeoutError(err) {
+					timedOut.Store(true)
 				}
-				errors = append(errors, &groupResourceError{resource, err})
-				continue
+				results[i] = listResult{objects: objs, err: err}
 			}
+		}()
+	}
+	for i := range candidates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
 
-			if len(objs.Items) > 0 {
-				g.objects = objs
-				log.Infof("Adding resource: %s to the list of GVRs to be extracted", resource.Name)
-				resources = append(resources, g)
-				continue
+// resourceToExtract lists objects for each admitted API type in namespace (or cluster-wide
+// for allowed cluster-scoped kinds: ClusterRoleBinding, ClusterRole, SCC), running up to
+// concurrency list calls in parallel. It returns resources with non-empty lists and a
+// parallel slice of per-type list errors, both in discovery order. A timeout on any type
+// fails fast with only that error.
+func resourceToExtract(requestTimeout time.Duration, concurrency int, namespace string, labelSelector string, dynamicClient dynamic.Interface, lists []*metav1.APIResourceList, filter resourceFilter, log logrus.FieldLogger) ([]*groupResource, []*groupResourceError, []file.SkippedResource) {
+	return extractResources(concurrency, namespace, lists, filter, objectLister(requestTimeout, namespace, labelSelector, dynamicClient, log), log)
+}
+
+// objectLister returns the listFunc resourceToExtract uses: getObjects in namespace.
+func objectLister(requestTimeout time.Duration, namespace string, labelSelector string, dynamicClient dynamic.Interface, log logrus.FieldLogger) listFunc {
+	return func(g *groupResource) (*unstructured.UnstructuredList, error) {
+		return getObjects(requestTimeout, g, namespace, labelSelector, dynamicClient, log)
+	}
+}
+
+// extractResources is resourceToExtract with the list call supplied by the caller, so
+// --stream can write objects while they are listed.
+func extractResources(concurrency int, namespace string, lists []*metav1.APIResourceList, filter resourceFilter, list listFunc, log logrus.FieldLogger) ([]*groupResource, []*groupResourceError, []file.SkippedResource) {
+	resources := []*groupResource{}
+	errors := []*groupResourceError{}
+
+	candidates, skipped := admittedGroupResources(lists, filter, log)
+	results := listGroupResources(concurrency, candidates, list, log)
+
+	for i, g := range candidates {
+		result := results[i]
+		if result.skipped {
+			continue
+		}
+		resource := g.APIResource
+		if err := result.err; err != nil {
+			// Check if error is due to timeout/deadline exceeded - fail fast
+			if isTimeoutError(err) {
+				log.Errorf("Request timeout exceeded for groupVersion %s, resource: %s, kind: %s: %v", g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind, err)
+				return nil, []*groupResourceError{{resource, err}}, nil
 			}
+			reason := file.SkipReasonListError
+			switch {
+			case apierrors.IsForbidden(err):
+				log.Debugf("Access denied for groupVersion %s, resource: %s, kind: %s (expected for namespace-admin users)", g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind)
+				reason = file.SkipReasonForbidden
+			case apierrors.IsMethodNotSupported(err):
+				log.Warnf("List method not supported on the groupVersion %s, resource: %s, kind: %s", g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind)
+			case apierrors.IsNotFound(err):
+				log.Debugf("Resource not found (virtual resource), groupVersion %s, resource: %s, kind: %s", g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind)
+			default:
+				log.Errorf("Error listing objects: %v, groupVersion %s, resource: %s, kind: %s", err, g.APIGroupVersion, g.APIResource.Name, g.APIResource.Kind)
+			}
+			errors = append(errors, &groupResourceError{resource, err})
+			skipped = append(skipped, g.skipped(reason, err.Error()))
+			continue
+		}
 
-			log.Debugf("0 objects found for resource %s, skipping", resource.Name)
+		if len(result.objects.Items) > 0 {
+			g.objects = result.objects
+			log.Infof("Adding resource: %s to the list of GVRs to be extracted", resource.Name)
+			resources = append(resources, g)
+			continue
 		}
+
+		log.Debugf("0 objects found for resource %s, skipping", resource.Name)
+		skipped = append(skipped, g.skipped(file.SkipReasonNoObjects, ""))
 	}
 
-	return resources, errors
+	for i := range skipped {
+		skipped[i].Namespace = namespace
+	}
+	return resources, errors, skipped
 }
 
 // isAdmittedResource returns whether resource should be listed: all namespaced types,
@@ -331,14 +497,20 @@ func getObjects(requestTimeout time.Duration, g *groupResource, namespace string
 	if err != nil {
 		return nil, err
 	}
+	var unstructuredList *unstructured.UnstructuredList
 	if g.APIResource.Name == "imagestreamtags" || g.APIResource.Name == "imagetags" {
-		unstructuredList, err := iterateItemsByGet(requestTimeout, c, g, list, namespace, logger)
-		if err != nil {
-			return nil, err
-		}
-		return unstructuredList, nil
+		unstructuredList, err = iterateItemsByGet(requestTimeout, c, g, list, namespace, logger)
+	} else {
+		unstructuredList, err = iterateItemsInList(list, g, logger)
 	}
-	return iterateItemsInList(list, g, logger)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the list resourceVersion so the export manifest can record a watermark per resource.
+	if listMeta, err := meta.ListAccessor(list); err == nil {
+		unstructuredList.SetResourceVersion(listMeta.GetResourceVersion())
+	}
+	return unstructuredList, nil
 }
 
 // iterateItemsByGet builds a full UnstructuredList by Get-ing each item name from list
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -17,6 +18,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/discovery"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
@@ -774,9 +776,13 @@ func TestWriteErrors_jsonMarshalFails(t *testing.T) {
 
 // ---------- resourceToExtract ----------
 
-func TestResourceToExtract_SkipsEvents(t *testing.T) {
+func TestResourceToExtract_SkipsEventsWithDefaultProfile(t *testing.T) {
 	scheme := runtime.NewScheme()
 	client := dynamicfake.NewSimpleDynamicClient(scheme)
+	filter, err := newResourceFilter(nil, defaultExcludedResources)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	lists := []*metav1.APIResourceList{
 		{
@@ -787,10 +793,10 @@ func TestResourceToExtract_SkipsEvents(t *testing.T) {
 		},
 	}
 
-	resources, _ := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, _, _ := resourceToExtract(0, 1, "default", "", client, lists, filter, testLogger())
 	for _, r := range resources {
 		if r.APIResource.Kind == "Event" {
-			t.Fatal("Event resources should be skipped")
+			t.Fatal("Event resources should be skipped by the default exclusion profile")
 		}
 	}
 }
@@ -808,7 +814,7 @@ func TestResourceToExtract_SkipsClusterScopedNonAdmitted(t *testing.T) {
 		},
 	}
 
-	resources, _ := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, _, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	for _, r := range resources {
 		if r.APIResource.Kind == "Namespace" {
 			t.Fatal("Namespace resources should be skipped (not admitted)")
@@ -829,7 +835,7 @@ func TestResourceToExtract_SkipsEmptyVerbs(t *testing.T) {
 		},
 	}
 
-	resources, _ := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, _, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(resources) > 0 {
 		t.Fatal("resources with empty verbs should be skipped")
 	}
@@ -846,12 +852,52 @@ func TestResourceToExtract_SkipsEmptyAPIResources(t *testing.T) {
 		},
 	}
 
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(resources) != 0 || len(errs) != 0 {
 		t.Fatal("empty APIResources list should produce no resources or errors")
 	}
 }
 
+func TestResourceToExtract_recordsSkippedResources(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme)
+	client.PrependReactor("list", "secrets", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", errors.New("no"))
+	})
+	lists := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "events", Kind: "Event", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "secrets", Kind: "Secret", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "namespaces", Kind: "Namespace", Namespaced: false, Verbs: stdVerbs()},
+			},
+		},
+	}
+
+	filter, err := newResourceFilter(nil, []string{"events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, skipped := resourceToExtract(0, 1, "default", "", client, lists, filter, testLogger())
+	got := map[string]string{}
+	for _, s := range skipped {
+		if s.Namespace != "default" {
+			t.Errorf("skipped %s: namespace = %q, want default", s.Resource, s.Namespace)
+		}
+		got[s.Resource] = s.Reason
+	}
+	want := map[string]string{
+		"events":     file.SkipReasonExcluded,
+		"namespaces": file.SkipReasonNotAdmitted,
+		"configmaps": file.SkipReasonNoObjects,
+		"secrets":    file.SkipReasonForbidden,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("skipped reasons = %v, want %v", got, want)
+	}
+}
+
 // ---------- test discovery mock (implements discovery.DiscoveryInterface) ----------
 
 type testDiscovery struct {
@@ -1188,7 +1234,7 @@ func TestResourceToExtract_loadsConfigMaps(t *testing.T) {
 			},
 		},
 	}
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errs: %v", errs)
 	}
@@ -1211,7 +1257,7 @@ func TestResourceToExtract_loadsClusterRoles(t *testing.T) {
 			},
 		},
 	}
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(errs) != 0 {
 		t.Fatalf("unexpected errs: %v", errs)
 	}
@@ -1233,7 +1279,7 @@ func TestResourceToExtract_listForbidden(t *testing.T) {
 			},
 		},
 	}
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(resources) != 0 {
 		t.Fatalf("expected no resources, got %d", len(resources))
 	}
@@ -1255,7 +1301,7 @@ func TestResourceToExtract_listNotFound(t *testing.T) {
 			},
 		},
 	}
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(resources) != 0 || len(errs) != 1 || !apierrors.IsNotFound(errs[0].Error) {
 		t.Fatalf("resources=%d errs=%v", len(resources), errs)
 	}
@@ -1276,7 +1322,7 @@ func TestResourceToExtract_timeoutFailsFast(t *testing.T) {
 		},
 	}
 	// Pass non-zero timeout to enable timeout detection
-	resources, errs := resourceToExtract(100, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(100, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	// Expect: nil resources, exactly 1 timeout error, and no processing of services
 	if resources != nil {
 		t.Fatalf("expected nil resources on timeout, got %d resources", len(resources))
@@ -1290,6 +1336,29 @@ func TestResourceToExtract_timeoutFailsFast(t *testing.T) {
 	}
 }
 
+func TestTimeoutError(t *testing.T) {
+	notFound := &groupResourceError{APIResource: metav1.APIResource{Kind: "ConfigMap"}, Error: apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "cm")}
+	timeout := &groupResourceError{APIResource: metav1.APIResource{Kind: "Service"}, Error: fmt.Errorf("context deadline exceeded")}
+	serverTimeout := &groupResourceError{APIResource: metav1.APIResource{Kind: "Secret"}, Error: apierrors.NewTimeoutError("list", 1)}
+	tests := []struct {
+		name string
+		errs []*groupResourceError
+		want *groupResourceError
+	}{
+		{name: "none", errs: nil, want: nil},
+		{name: "no timeout", errs: []*groupResourceError{nil, notFound}, want: nil},
+		{name: "client deadline", errs: []*groupResourceError{notFound, timeout, serverTimeout}, want: timeout},
+		{name: "server timeout", errs: []*groupResourceError{serverTimeout}, want: serverTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := timeoutError(tt.errs); got != tt.want {
+				t.Fatalf("timeoutError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestResourceToExtract_listMethodNotSupported(t *testing.T) {
 	client := dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme)
 	client.PrependReactor("list", "configmaps", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
@@ -1303,7 +1372,7 @@ func TestResourceToExtract_listMethodNotSupported(t *testing.T) {
 			},
 		},
 	}
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(resources) != 0 || len(errs) != 1 || !apierrors.IsMethodNotSupported(errs[0].Error) {
 		t.Fatalf("resources=%d errs=%v", len(resources), errs)
 	}
@@ -1322,7 +1391,7 @@ func TestResourceToExtract_listGenericError(t *testing.T) {
 			},
 		},
 	}
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(resources) != 0 || len(errs) != 1 || errs[0].Error.Error() != "upstream timeout" {
 		t.Fatalf("resources=%d errs=%v", len(resources), errs)
 	}
@@ -1338,7 +1407,7 @@ func TestResourceToExtract_zeroObjectsNotAdded(t *testing.T) {
 			},
 		},
 	}
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(resources) != 0 || len(errs) != 0 {
 		t.Fatalf("empty list should skip resource (no error), got resources=%d errs=%v", len(resources), errs)
 	}
@@ -1355,7 +1424,7 @@ func TestResourceToExtract_skipsUnparseableGroupVersion(t *testing.T) {
 			},
 		},
 	}
-	resources, errs := resourceToExtract(0, "default", "", client, lists, testLogger())
+	resources, errs, _ := resourceToExtract(0, 1, "default", "", client, lists, resourceFilter{}, testLogger())
 	if len(resources) != 0 || len(errs) != 0 {
 		t.Fatalf("got resources %d errs %d", len(resources), len(errs))
 	}
@@ -1363,17 +1432,59 @@ func TestResourceToExtract_skipsUnparseableGroupVersion(t *testing.T) {
 
 // ---------- helpers ----------
 
-func namespacedObj(ns, name string) unstructured.Unstructured {
-	u := unstructured.Unstructured{}
+// objOption sets a field of an object built by namespacedObj or clusterScopedObj.
+type objOption func(u *unstructured.Unstructured)
+
+func withKind(apiVersion, kind string) objOption {
+	return func(u *unstructured.Unstructured) {
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+	}
+}
+
+func withUID(uid string) objOption {
+	return func(u *unstructured.Unstructured) { u.SetUID(types.UID(uid)) }
+}
+
+func withResourceVersion(resourceVersion string) objOption {
+	return func(u *unstructured.Unstructured) { u.SetResourceVersion(resourceVersion) }
+}
+
+func withOwners(owners ...metav1.OwnerReference) objOption {
+	return func(u *unstructured.Unstructured) { u.SetOwnerReferences(owners) }
+}
+
+// withFields sets top-level fields other than metadata, such as spec.
+func withFields(fields map[string]interface{}) objOption {
+	return func(u *unstructured.Unstructured) {
+		for k, v := range fields {
+			u.Object[k] = v
+		}
+	}
+}
+
+func namespacedObj(ns, name string, opts ...objOption) unstructured.Unstructured {
+	u := unstructured.Unstructured{Object: map[string]interface{}{}}
 	u.SetNamespace(ns)
 	u.SetName(name)
+	for _, opt := range opts {
+		opt(&u)
+	}
 	return u
 }
 
-func clusterScopedObj(name string) unstructured.Unstructured {
-	u := unstructured.Unstructured{}
-	u.SetName(name)
-	return u
+func clusterScopedObj(name string, opts ...objOption) unstructured.Unstructured {
+	return namespacedObj("", name, opts...)
+}
+
+// testGroupResource returns a core/v1 groupResource of the resource name listing objs.
+func testGroupResource(name, kind string, namespaced bool, objs ...unstructured.Unstructured) *groupResource {
+	return &groupResource{
+		APIVersion:      "v1",
+		APIGroupVersion: "v1",
+		APIResource:     metav1.APIResource{Name: name, Kind: kind, Namespaced: namespaced},
+		objects:         &unstructured.UnstructuredList{Items: objs},
+	}
 }
 
 // crdObj returns a cluster-scoped CRD-shaped object (empty namespace), matching collectRelatedCRDs output.
@@ -1384,3 +1495,101 @@ func crdObj(name string) unstructured.Unstructured {
 	u.SetAPIVersion("apiextensions.k8s.io/v1")
 	return u
 }
+
+func TestSplitResourceListsByScope(t *testing.T) {
+	lists := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+				{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+			},
+		},
+		{
+			GroupVersion: "rbac.authorization.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "clusterroles", Kind: "ClusterRole", Namespaced: false},
+			},
+		},
+	}
+	namespaced, clusterScoped := splitResourceListsByScope(lists)
+	if len(namespaced) != 1 || namespaced[0].GroupVersion != "v1" || len(namespaced[0].APIResources) != 1 {
+		t.Fatalf("namespaced = %#v", namespaced)
+	}
+	if len(clusterScoped) != 2 {
+		t.Fatalf("want 2 cluster-scoped lists, got %d", len(clusterScoped))
+	}
+	if clusterScoped[1].APIResources[0].Kind != "ClusterRole" {
+		t.Fatalf("clusterScoped = %#v", clusterScoped)
+	}
+	if len(lists[0].APIResources) != 2 {
+		t.Fatal("input lists must not be modified")
+	}
+}
+
+func TestResourceToExtract_concurrentKeepsDiscoveryOrder(t *testing.T) {
+	objs := []runtime.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cm1"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "s1"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc1"}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sa1"}},
+	}
+	client := dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme, objs...)
+	client.PrependReactor("list", "pods", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New("no"))
+	})
+	client.PrependReactor("list", "endpoints", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "endpoints"}, "", errors.New("no"))
+	})
+	lists := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "services", Kind: "Service", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "endpoints", Kind: "Endpoints", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "serviceaccounts", Kind: "ServiceAccount", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "secrets", Kind: "Secret", Namespaced: true, Verbs: stdVerbs()},
+			},
+		},
+	}
+
+	for i := 0; i < 5; i++ {
+		resources, errs, _ := resourceToExtract(0, 4, "default", "", client, lists, resourceFilter{}, testLogger())
+		var gotResources []string
+		for _, r := range resources {
+			gotResources = append(gotResources, r.APIResource.Name)
+		}
+		if want := "services,configmaps,serviceaccounts,secrets"; strings.Join(gotResources, ",") != want {
+			t.Fatalf("resources = %v, want %s", gotResources, want)
+		}
+		if len(errs) != 2 || errs[0].APIResource.Name != "pods" || errs[1].APIResource.Name != "endpoints" {
+			t.Fatalf("errs = %#v", errs)
+		}
+	}
+}
+
+func TestResourceToExtract_concurrentTimeoutFailsFast(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme)
+	client.PrependReactor("list", "configmaps", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, fmt.Errorf("context deadline exceeded")
+	})
+	lists := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "services", Kind: "Service", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: stdVerbs()},
+				{Name: "secrets", Kind: "Secret", Namespaced: true, Verbs: stdVerbs()},
+			},
+		},
+	}
+	resources, errs, _ := resourceToExtract(0, 3, "default", "", client, lists, resourceFilter{}, testLogger())
+	if resources != nil {
+		t.Fatalf("expected nil resources on timeout, got %d resources", len(resources))
+	}
+	if len(errs) != 1 || errs[0].APIResource.Name != "configmaps" {
+		t.Fatalf("expected only the configmaps timeout error, got %#v", errs)
+	}
+}
@@ -1,5 +1,5 @@
 // Package export implements the crane export subcommand: discover API types,
-// list objects in a namespace and related cluster-scoped RBAC (CRB, CR, SCC),
+// list objects in one or more namespaces and related cluster-scoped RBAC (CRB, CR, SCC),
 // and write manifests and list failures under an export directory.
 package export
 
@@ -7,10 +7,16 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/konveyor/crane/internal/archive"
+	"github.com/konveyor/crane/internal/buildinfo"
+	"github.com/konveyor/crane/internal/file"
 	"github.com/konveyor/crane/internal/flags"
+	"github.com/konveyor/crane/internal/helm"
+	"github.com/konveyor/crane/internal/secrets"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -19,10 +25,8 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	errorsutil "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd/api"
-
 )
 
 // ExportOptions holds CLI flags and runtime state for a single export run.
@@ -36,17 +40,48 @@ type ExportOptions struct {
 	globalFlags      *flags.GlobalFlags
 	log              *logrus.Logger
 
-	rawConfig              api.Config
-	exportDir              string
-	labelSelector          string
-	userSpecifiedNamespace string
-	crdSkipGroups          []string
-	crdIncludeGroups       []string
-	asExtras               string
-	extras                 map[string][]string
-	QPS                    float32
-	Burst                  int
-	overwrite              bool
+	rawConfig               api.Config
+	exportDir               string
+	labelSelector           string
+	userSpecifiedNamespace  string
+	namespaces              []string
+	namespaceSelector       string
+	includeResources        []string
+	excludeResources        []string
+	resourceFilter          resourceFilter
+	crdSkipGroups           []string
+	crdIncludeGroups        []string
+	asExtras                string
+	extras                  map[string][]string
+	QPS                     float32
+	Burst                   int
+	concurrency             int
+	stream                  bool
+	checkAccess             bool
+	maxRetries              int
+	retryBackoff            time.Duration
+	failOn                  string
+	overwrite               bool
+	incremental             bool
+	skipOwned               bool
+	keepOwnedKinds          []string
+	followClusterReferences bool
+	alternateVersions       []string
+	normalize               bool
+	objectsFile             string
+	appRoots                []string
+	objectRefs              []objectRef
+	rootRefs                []objectRef
+	outputArchive           string
+	archiveFormat           string
+	secretsKeyFile          string
+	secretsKey              []byte
+	redactSecrets           bool
+	fromFile                string
+	fromVeleroBackup        string
+	explicitNamespace       bool
+	progress                bool
+	eventsJSON              string
 
 	genericclioptions.IOStreams
 }
@@ -85,6 +120,36 @@ func (o *ExportOptions) Complete(c *cobra.Command, args []string) error {
 				log.Debugf("Namespace flag was set to empty string")
 				return fmt.Errorf("namespace cannot be empty; omit -n/--namespace to use your kubeconfig context default")
 			}
+			if len(o.namespaces) > 0 || o.namespaceSelector != "" {
+				log.Debugf("Namespace flag was combined with --namespaces or --namespace-selector")
+				return fmt.Errorf("cannot use -n/--namespace with --namespaces or --namespace-selector")
+			}
+			o.explicitNamespace = true
+		}
+	}
+
+	if len(o.namespaces) > 0 {
+		o.namespaces, err = normalizeNamespaces(o.namespaces)
+		if err != nil {
+			return err
+		}
+	}
+
+	o.resourceFilter, err = newResourceFilter(o.includeResources, o.excludeResources)
+	if err != nil {
+		return err
+	}
+
+	if err = o.loadObjectSelection(); err != nil {
+		log.Errorf("Cannot load the objects to export: %v", err)
+		return err
+	}
+
+	if o.secretsKeyFile != "" {
+		o.secretsKey, err = secrets.LoadKey(o.secretsKeyFile)
+		if err != nil {
+			log.Errorf("Cannot load --secrets-key-file: %v", err)
+			return err
 		}
 	}
 
@@ -128,12 +193,100 @@ func (o *ExportOptions) Validate() error {
 		log.Debugf("--as-extras requires specifying a user or group to impersonate")
 		return fmt.Errorf("extras requires specifying a user or group to impersonate")
 	}
+	if len(o.namespaces) > 0 && o.namespaceSelector != "" {
+		log.Debugf("--namespaces and --namespace-selector are mutually exclusive")
+		return fmt.Errorf("cannot use --namespaces with --namespace-selector")
+	}
+	if o.namespaceSelector != "" {
+		if _, err := labels.Parse(o.namespaceSelector); err != nil {
+			log.Debugf("Invalid --namespace-selector %q: %v", o.namespaceSelector, err)
+			return fmt.Errorf("invalid --namespace-selector: %w", err)
+		}
+	}
 	if o.labelSelector != "" {
 		if _, err := labels.Parse(o.labelSelector); err != nil {
 			log.Debugf("Invalid --label-selector %q: %v", o.labelSelector, err)
 			return fmt.Errorf("invalid --label-selector: %w", err)
 		}
 	}
+	if o.incremental && o.overwrite {
+		log.Debugf("--incremental and --overwrite are mutually exclusive")
+		return fmt.Errorf("cannot use --incremental with --overwrite")
+	}
+	if o.stream && o.selectsObjects() {
+		log.Debugf("--stream cannot be combined with --objects-file or --app-root")
+		return fmt.Errorf("cannot use --stream with --objects-file or --app-root; objects are selected after listing")
+	}
+	if o.checkAccess && o.offline() {
+		log.Debugf("--check-access needs a live cluster")
+		return fmt.Errorf("cannot use --check-access with --from-file or --from-velero-backup")
+	}
+	if o.checkAccess && o.outputArchive != "" {
+		log.Debugf("--check-access writes no export")
+		return fmt.Errorf("cannot use --check-access with --output-archive; --check-access only reports permissions")
+	}
+	if o.fromFile != "" && o.fromVeleroBackup != "" {
+		log.Debugf("--from-file and --from-velero-backup are mutually exclusive")
+		return fmt.Errorf("cannot use --from-file with --from-velero-backup")
+	}
+	for _, f := range []struct {
+		flag string
+		path string
+	}{
+		{"--from-file", o.fromFile},
+		{"--from-velero-backup", o.fromVeleroBackup},
+	} {
+		if f.path == "" {
+			continue
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			log.Debugf("Cannot access %s %q: %v", f.flag, f.path, err)
+			return fmt.Errorf("%s %q: %w", f.flag, f.path, err)
+		}
+		if !info.Mode().IsRegular() {
+			log.Debugf("%s %q is not a regular file", f.flag, f.path)
+			return fmt.Errorf("%s %q is not a regular file", f.flag, f.path)
+		}
+	}
+	if o.secretsKeyFile != "" && o.redactSecrets {
+		log.Debugf("--secrets-key-file and --redact-secrets are mutually exclusive")
+		return fmt.Errorf("cannot use --secrets-key-file with --redact-secrets")
+	}
+	if o.outputArchive != "" {
+		if o.incremental {
+			log.Debugf("--incremental and --output-archive are mutually exclusive")
+			return fmt.Errorf("cannot use --incremental with --output-archive")
+		}
+		if !archive.ValidFormat(o.archiveFormat) {
+			log.Debugf("Invalid --archive-format %q", o.archiveFormat)
+			return fmt.Errorf("--archive-format must be one of %s, got %q", strings.Join(archive.Formats, ", "), o.archiveFormat)
+		}
+		if _, err := os.Stat(o.outputArchive); err == nil && !o.overwrite {
+			log.Debugf("Output archive %q already exists", o.outputArchive)
+			return fmt.Errorf("output archive %q already exists; use --overwrite to replace it", o.outputArchive)
+		}
+	}
+	if o.concurrency < 0 {
+		log.Debugf("Invalid --concurrency %d", o.concurrency)
+		return fmt.Errorf("--concurrency must not be negative, got %d", o.concurrency)
+	}
+	if o.maxRetries < 0 {
+		log.Debugf("Invalid --max-retries %d", o.maxRetries)
+		return fmt.Errorf("--max-retries must not be negative, got %d", o.maxRetries)
+	}
+	if o.maxRetries > 0 && o.retryBackoff <= 0 {
+		log.Debugf("Invalid --retry-backoff %s", o.retryBackoff)
+		return fmt.Errorf("--retry-backoff must be positive when --max-retries is set, got %s", o.retryBackoff)
+	}
+	if _, err := parseAlternateVersions(o.alternateVersions); err != nil {
+		log.Debugf("Invalid --alternate-versions %v: %v", o.alternateVersions, err)
+		return err
+	}
+	if o.failOn != "" && !slices.Contains(failOnPolicies, o.failOn) {
+		log.Debugf("Invalid --fail-on %q", o.failOn)
+		return fmt.Errorf("--fail-on must be one of %s, got %q", strings.Join(failOnPolicies, ", "), o.failOn)
+	}
 	if len(o.crdSkipGroups) > 0 && len(o.crdIncludeGroups) > 0 {
 		includeSet := make(map[string]bool, len(o.crdIncludeGroups))
 		for _, g := range o.crdIncludeGroups {
@@ -185,6 +338,18 @@ func allResourceListsForbidden(resources []*groupResource, resourceErrs []*group
 	return true
 }
 
+// currentContext returns the kubeconfig context the export runs against, or "" for an
+// offline export.
+func (o *ExportOptions) currentContext() string {
+	if o.offline() {
+		return ""
+	}
+	if o.configFlags.Context != nil && *o.configFlags.Context != "" {
+		return *o.configFlags.Context
+	}
+	return o.rawConfig.CurrentContext
+}
+
 // mergeImpersonationExtras merges src into dest, appending values for shared keys.
 // This preserves extras already set by ToRESTConfig() alongside --as-extras flag values.
 func mergeImpersonationExtras(dest, src map[string][]string) map[string][]string {
@@ -200,138 +365,379 @@ func mergeImpersonationExtras(dest, src map[string][]string) map[string][]string
 	return dest
 }
 
-// Run performs discovery, lists resources, filters cluster-scoped RBAC to related
-// ServiceAccounts, writes YAML under exportDir, and returns an aggregate of non-fatal write errors.
+// Run exports to exportDir, or to outputArchive when it is set.
 func (o *ExportOptions) Run() error {
+	if o.outputArchive != "" {
+		return o.runToArchive()
+	}
+	return o.run()
+}
+
+// run performs discovery once, lists resources in every selected namespace, filters
+// cluster-scoped RBAC to ServiceAccounts from all exported namespaces, writes YAML under
+// exportDir, and returns an aggregate of non-fatal write errors.
+func (o *ExportOptions) run() error {
 	var err error
 
 	log := o.globalFlags.GetLoggerOrDefault()
-	log.Infof("Starting export for namespace %q", o.userSpecifiedNamespace)
 
-	restConfig, err := o.configFlags.ToRESTConfig()
+	var source exportSource
+	if o.offline() {
+		source, err = o.newOfflineSource(log)
+	} else {
+		source, err = newLiveSource(o, log)
+	}
 	if err != nil {
-		log.Errorf("Cannot create rest config: %v", err)
 		return err
 	}
-
-	restConfig.Impersonate.Extra = mergeImpersonationExtras(restConfig.Impersonate.Extra, o.extras)
-	restConfig.Burst = o.Burst
-	restConfig.QPS = o.QPS
-
-	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	namespaces, err := source.resolveNamespaces(o, log)
 	if err != nil {
-		log.Errorf("Cannot create kubernetes client: %v", err)
+		log.Errorf("Cannot resolve namespaces to export: %v", err)
 		return err
 	}
-	if err := validateExportNamespace(context.Background(), kubeClient, o.userSpecifiedNamespace, log); err != nil {
-		log.Errorf("Namespace validation failed for %q: %v", o.userSpecifiedNamespace, err)
+	if len(namespaces) == 1 {
+		log.Infof("Starting export for namespace %q", namespaces[0])
+	} else {
+		log.Infof("Starting export for %d namespaces: %s", len(namespaces), strings.Join(namespaces, ", "))
+	}
+	for _, namespace := range namespaces {
+		if err := source.checkNamespace(namespace, log); err != nil {
+			log.Errorf("Namespace validation failed for %q: %v", namespace, err)
+			return err
+		}
+	}
+	if o.checkAccess {
+		return o.runAccessCheck(source, namespaces, log)
+	}
+	progress, closeEvents, err := o.newExportProgress()
+	if err != nil {
+		log.Errorf("Cannot write export events: %v", err)
 		return err
 	}
+	defer closeEvents()
 
+	var previousManifest *file.ExportManifest
 	if _, err := os.Stat(o.exportDir); err == nil {
-		if !o.overwrite {
-			return fmt.Errorf("export directory %q already exists; use --overwrite to replace it", o.exportDir)
+		switch {
+		case o.incremental:
+			previousManifest, err = file.ReadExportManifest(o.exportDir)
+			if err != nil {
+				log.Errorf("Cannot load previous export manifest: %v", err)
+				return err
+			}
+			if previousManifest == nil {
+				log.Warnf("No %s found in %q; writing every object", file.ExportManifestFileName, o.exportDir)
+			} else if previousManifest.Secrets != o.secretsMode() {
+				log.Debugf("Previous export stored Secrets %q, this run %q", previousManifest.Secrets, o.secretsMode())
+				return fmt.Errorf("cannot change how Secrets are stored in an incremental export; use --overwrite to re-export %q", o.exportDir)
+			}
+		case !o.overwrite:
+			return fmt.Errorf("export directory %q already exists; use --overwrite to replace it or --incremental to update it", o.exportDir)
+		default:
+			if err = os.RemoveAll(o.exportDir); err != nil {
+				log.Errorf("Error clearing export directory: %v", err)
+				return err
+			}
 		}
-		if err = os.RemoveAll(o.exportDir); err != nil {
-			log.Errorf("Error clearing export directory: %v", err)
+	}
+	layout := exportLayout{exportDir: o.exportDir, namespaces: namespaces}
+	for _, namespace := range namespaces {
+		if err = os.MkdirAll(layout.resourceDir(namespace), 0700); err != nil {
+			log.Errorf("Error creating the resources directory: %v", err)
+			return err
+		}
+		// Failures always reflect the current run, including incremental updates.
+		if err = prepareFailuresDir(layout.failuresDir(namespace)); err != nil {
+			log.Errorf("Error creating the failures directory: %v", err)
 			return err
 		}
 	}
-	resourceDir := filepath.Join(o.exportDir, "resources", o.userSpecifiedNamespace)
-	if err = os.MkdirAll(resourceDir, 0700); err != nil {
-		log.Errorf("Error creating the resources directory: %v", err)
-		return err
-	}
-	failuresDir := filepath.Join(o.exportDir, "failures", o.userSpecifiedNamespace)
-	if err = os.MkdirAll(failuresDir, 0700); err != nil {
+	if err = prepareFailuresDir(layout.clusterFailuresDir()); err != nil {
 		log.Errorf("Error creating the failures directory: %v", err)
 		return err
 	}
 
-	discoveryClient, err := o.configFlags.ToDiscoveryClient()
+	progress.start("Discovering API resources")
+	resourceLists, err := source.discover(log)
 	if err != nil {
-		log.Errorf("Cannot create discovery client: %v", err)
 		return err
 	}
+	// Offline sources never fail transiently, so wrapping them is harmless.
+	dynamicClient := newRetryingClient(source.client(), retryPolicy{maxRetries: o.maxRetries, backoff: o.retryBackoff}, log)
+	log.Debugf("Discovered %d API resource lists", len(resourceLists))
+	namespacedLists, clusterScopedLists := splitResourceListsByScope(resourceLists)
+	if progress != nil {
+		total := listableResourceCount(namespacedLists, o.resourceFilter)*len(namespaces) + listableResourceCount(clusterScopedLists, o.resourceFilter)
+		progress.discoveryDone(resourceLists, total)
+		progress.start("Listing resources")
+	}
 
-	// Always request fresh data from the server
-	discoveryClient.Invalidate()
+	var errs []error
 
-	dynamicClient, err := dynamic.NewForConfig(restConfig)
-	if err != nil {
-		log.Errorf("Cannot create dynamic client: %v", err)
-		return err
+	requestTimeout := source.requestTimeout()
+
+	// Cluster-scoped kinds are listed once; the RBAC filter below keeps only
+	// objects related to ServiceAccounts from any of the exported namespaces.
+	clusterList := progress.observeList("", objectLister(requestTimeout, "", o.labelSelector, dynamicClient, log))
+	clusterResources, clusterErrs, skipped := extractResources(o.concurrency, "", clusterScopedLists, o.resourceFilter, clusterList, log)
+	log.Debugf("Extracted %d cluster-scoped resources (%d errors)", len(clusterResources), len(clusterErrs))
+	if resErr := timeoutError(clusterErrs); resErr != nil {
+		log.Errorf("Timeout listing resource %q: %v", resErr.APIResource.Kind, resErr.Error)
+		return resErr.Error
+	}
+
+	var stream *streamWriter
+	if o.stream {
+		stream = o.streamWriter(layout, previousManifest, log)
+		stream.progress = progress
+	}
+	nsResources := make(map[string][]*groupResource, len(namespaces))
+	nsErrs := make(map[string][]*groupResourceError, len(namespaces))
+	allResources := []*groupResource{}
+	allErrs := append([]*groupResourceError{}, clusterErrs...)
+	for _, namespace := range namespaces {
+		var resources []*groupResource
+		var resourceErrs []*groupResourceError
+		var resourceSkipped []file.SkippedResource
+		list := objectLister(requestTimeout, namespace, o.labelSelector, dynamicClient, log)
+		if stream != nil {
+			list = stream.listFunc(requestTimeout, namespace, o.labelSelector, dynamicClient, log)
+		}
+		resources, resourceErrs, resourceSkipped = extractResources(o.concurrency, namespace, namespacedLists, o.resourceFilter, progress.observeList(namespace, list), log)
+		log.Debugf("Extracted %d resources (%d errors) in namespace %q", len(resources), len(resourceErrs), namespace)
+		// A timeout persisted after retries; fail fast rather than listing the remaining namespaces.
+		if resErr := timeoutError(resourceErrs); resErr != nil {
+			log.Errorf("Timeout listing resource %q: %v", resErr.APIResource.Kind, resErr.Error)
+			return resErr.Error
+		}
+		if o.selectsObjects() {
+			kept := selectObjects(namespace, resources, o.objectRefs, o.rootRefs, log)
+			log.Infof("Selected %d object(s) in namespace %q", kept, namespace)
+		}
+		nsResources[namespace] = resources
+		nsErrs[namespace] = resourceErrs
+		allResources = append(allResources, resources...)
+		allErrs = append(allErrs, resourceErrs...)
+		skipped = append(skipped, resourceSkipped...)
+	}
+	allResources = append(allResources, clusterResources...)
+	if stream != nil {
+		if err := stream.err(); err != nil {
+			log.Errorf("Cannot protect Secret values: %v", err)
+			return err
+		}
+		log.Infof("Streamed %d object(s) to %s (%d already up to date)", stream.written, o.exportDir, stream.upToDate)
+		if stream.secrets > 0 {
+			log.Infof("Stored %d Secret(s) %s", stream.secrets, o.secretsMode())
+		}
 	}
 
-	resourceLists, err := discoverPreferredResources(discoveryClient, log)
-	if err != nil {
-		return err
+	progress.listingDone()
+
+	clusterScopeHandler := NewClusterScopeHandler()
+	allResources = clusterScopeHandler.filterRbacResources(allResources, log)
+	log.Debugf("Resources after RBAC filter: %d", len(allResources))
+
+	var pruned []prunedObject
+	if o.skipOwned {
+		pruned = pruneOwnedObjects(allResources, newOwnedKindAllowlist(o.keepOwnedKinds), log)
+		log.Infof("Pruned %d object(s) owned by other exported objects; see %s/", len(pruned), file.PrunedDirName)
+		if stream != nil {
+			for _, e := range removePrunedFiles(layout, pruned) {
+				log.Warnf("Error removing pruned manifest: %v, continuing", e)
+				errs = append(errs, e)
+			}
+		}
 	}
-	log.Debugf("Discovered %d API resource lists", len(resourceLists))
 
-	var errs []error
+	progress.start("Collecting CRDs and referenced cluster-scoped objects")
+	crdResources, crdErrs, crdSkipped := collectRelatedCRDs(requestTimeout, allResources, dynamicClient, log, o.crdSkipGroups, o.crdIncludeGroups)
+	clusterErrs = append(clusterErrs, crdErrs...)
+	skipped = append(skipped, crdSkipped...)
+	allErrs = append(allErrs, crdErrs...)
+
+	var referencedResources []*groupResource
+	if o.followClusterReferences {
+		var referenceErrs []*groupResourceError
+		referencedResources, referenceErrs = clusterScopeHandler.collectReferencedResources(requestTimeout, allResources, dynamicClient, o.resourceFilter, log)
+		clusterErrs = append(clusterErrs, referenceErrs...)
+		allErrs = append(allErrs, referenceErrs...)
+	}
+	progress.crdsCollected(crdResources, referencedResources)
+
+	// Check if any resource errors are timeout errors that persisted after retries and fail
+	// fast with exit code 1. Listing timeouts were caught above, so this catches the CRD and
+	// reference lookups. Without --stream no files are written yet, so a timeout leaves no
+	// partial export; with --stream the objects listed so far are already on disk.
+	if resErr := timeoutError(allErrs); resErr != nil {
+		log.Errorf("Timeout listing resource %q: %v", resErr.APIResource.Kind, resErr.Error)
+		return resErr.Error
+	}
+
+	// Helm releases are decoded before protectSecrets redacts or encrypts their Secrets.
+	var streamedReleases []*helm.Release
+	if stream != nil {
+		streamedReleases = stream.helmReleases()
+	}
+	releases := captureHelmReleases(allResources, streamedReleases, log)
+	if len(releases) > 0 {
+		owned := append(append(append([]*groupResource{}, allResources...), crdResources...), referencedResources...)
+		annotated := annotateHelmObjects(owned, releases)
+		if stream != nil {
+			streamedAnnotated, annotateErrs := annotateStreamedHelmObjects(layout, owned, releases)
+			for _, e := range annotateErrs {
+				log.Warnf("Error annotating streamed object: %v, continuing", e)
+				errs = append(errs, e)
+			}
+			annotated += streamedAnnotated
+		}
+		log.Infof("Captured %d Helm release(s) to %s/ and annotated %d object(s) with %s", len(releases), file.HelmDirName, annotated, helm.ReleaseAnnotation)
+	}
 
-	// Pass restConfig.Timeout to child functions for per-request timeout enforcement
-	requestTimeout := restConfig.Timeout
+	for _, namespace := range namespaces {
+		if err := o.protectSecrets(nsResources[namespace], log); err != nil {
+			log.Errorf("Cannot protect Secret values: %v", err)
+			return err
+		}
+	}
 
-	resources, resourceErrs := resourceToExtract(requestTimeout, o.userSpecifiedNamespace, o.labelSelector, dynamicClient, resourceLists, log)
-	log.Debugf("Extracted %d resources (%d errors)", len(resources), len(resourceErrs))
-	clusterScopeHandler := NewClusterScopeHandler()
-	resources = clusterScopeHandler.filterRbacResources(resources, log)
-	log.Debugf("Resources after RBAC filter: %d", len(resources))
-
-	clusterResourceDir := filepath.Join(o.exportDir, "resources", o.userSpecifiedNamespace, "_cluster")
-
-	crdResources, crdErrs := collectRelatedCRDs(requestTimeout, resources, dynamicClient, log, o.crdSkipGroups, o.crdIncludeGroups)
-	resourceErrs = append(resourceErrs, crdErrs...)
-	resources = append(resources, crdResources...)
-
-	// Check if any resource errors are timeout errors and fail fast with exit code 1
-	// Do this before writing any files to avoid partial exports
-	for _, resErr := range resourceErrs {
-		if resErr != nil && resErr.Error != nil {
-			if apierrors.IsTimeout(resErr.Error) || strings.Contains(resErr.Error.Error(), "context deadline exceeded") {
-				log.Errorf("Timeout listing resource %q: %v", resErr.APIResource.Kind, resErr.Error)
-				return resErr.Error
-			}
+	acceptedClusterResources := []*groupResource{}
+	for _, r := range allResources {
+		if !r.APIResource.Namespaced {
+			acceptedClusterResources = append(acceptedClusterResources, r)
 		}
 	}
+	acceptedClusterResources = append(acceptedClusterResources, crdResources...)
+	acceptedClusterResources = append(acceptedClusterResources, referencedResources...)
 
-	// After merging CRDs: prepare _cluster so hasClusterScopedManifests sees cluster-scoped CRD objects.
-	if err = prepareClusterResourceDir(clusterResourceDir, resources); err != nil {
+	// After merging CRDs and referenced objects: prepare _cluster so hasClusterScopedManifests sees them.
+	// Incremental runs keep the directory; stale files are removed from the manifest diff below.
+	clusterResourceDir := layout.clusterResourceDir()
+	if o.incremental {
+		if hasClusterScopedManifests(acceptedClusterResources) {
+			err = os.MkdirAll(clusterResourceDir, 0700)
+		}
+	} else {
+		err = prepareClusterResourceDir(clusterResourceDir, acceptedClusterResources)
+	}
+	if err != nil {
 		log.Errorf("Error preparing cluster resources directory: %v", err)
 		return err
 	}
 
+	exported := append([]*groupResource{}, acceptedClusterResources...)
+	exportedByNamespace := map[string][]*groupResource{"": acceptedClusterResources}
+	for _, namespace := range namespaces {
+		exported = append(exported, nsResources[namespace]...)
+		exportedByNamespace[namespace] = nsResources[namespace]
+	}
+	manifest := &file.ExportManifest{
+		CraneVersion:  buildinfo.Version,
+		BuildCommit:   buildinfo.BuildCommit,
+		Server:        source.server(),
+		Source:        source.description(),
+		Context:       o.currentContext(),
+		Namespaces:    namespaces,
+		LabelSelector: o.labelSelector,
+		Resources:     buildExportedResources(layout, exported),
+		Skipped:       skipped,
+		Secrets:       o.secretsMode(),
+	}
+	recordAttempts(manifest, dynamicClient)
+	if o.incremental && previousManifest != nil {
+		failures := map[string][]*groupResourceError{"": clusterErrs}
+		for _, namespace := range namespaces {
+			failures[namespace] = nsErrs[namespace]
+		}
+		manifest.Resources = retainFailedResources(previousManifest.Resources, manifest.Resources, failures)
+		delta := diffExportedResources(o.exportDir, previousManifest.Resources, manifest.Resources)
+		log.Infof("Incremental export: %d added, %d changed, %d removed, %d unchanged", delta.added, delta.changed, delta.removed, delta.unchanged)
+		acceptedClusterResources = withoutUpToDate(layout, acceptedClusterResources, delta.upToDate)
+		for _, namespace := range namespaces {
+			nsResources[namespace] = withoutUpToDate(layout, nsResources[namespace], delta.upToDate)
+		}
+		for _, e := range removeStaleManifests(o.exportDir, delta.stale) {
+			log.Warnf("Error removing stale manifest: %v, continuing", e)
+			errs = append(errs, e)
+		}
+	}
+
 	//count and log the no of crds
 	crdCount := len(crdResources)
 	if crdCount > 0 {
 		log.Infof("Collected %d CRDs for referenced custom resources", crdCount)
 	}
 
-	writeResourcesErrors := writeResources(resources, clusterResourceDir, resourceDir, log)
+	toWrite := [][]*groupResource{acceptedClusterResources}
+	for _, namespace := range namespaces {
+		toWrite = append(toWrite, nsResources[namespace])
+	}
+	progress.startWriting(toWrite...)
+	writeResourcesErrors := writeObservedResources(acceptedClusterResources, clusterResourceDir, clusterResourceDir, progress.fileWritten, log)
+	writeErrorsErrors := writeErrors(clusterErrs, layout.clusterFailuresDir(), log)
+	for _, namespace := range namespaces {
+		writeResourcesErrors = append(writeResourcesErrors, writeObservedResources(nsResources[namespace], clusterResourceDir, layout.resourceDir(namespace), progress.fileWritten, log)...)
+		writeErrorsErrors = append(writeErrorsErrors, writeErrors(nsErrs[namespace], layout.failuresDir(namespace), log)...)
+	}
+	if stream != nil {
+		writeResourcesErrors = append(writeResourcesErrors, stream.errors()...)
+	}
+	if err := progress.writingDone(); err != nil {
+		log.Warnf("Error writing export events: %v, continuing", err)
+		errs = append(errs, err)
+	}
+	if len(releases) > 0 && o.secretsMode() != "" {
+		log.Infof("Helm release values and manifests are not written because Secrets are %s", o.secretsMode())
+	}
+	writeResourcesErrors = append(writeResourcesErrors, writeHelmReleases(layout, releases, o.secretsMode() == "")...)
+	alternates, alternatesSkipped, alternatesErrs := o.exportAlternateVersions(source, dynamicClient, namespaces, exportedByNamespace, log)
+	if len(alternates) > 0 {
+		log.Infof("Exported %d resource type(s) at alternate API versions to %s/", len(alternates), file.VersionsDirName)
+	}
+	manifest.AlternateVersions = alternates
+	manifest.Skipped = append(manifest.Skipped, alternatesSkipped...)
+	skipped = append(skipped, alternatesSkipped...)
+	writeResourcesErrors = append(writeResourcesErrors, alternatesErrs...)
 	for _, e := range writeResourcesErrors {
 		log.Warnf("Error writing manifests to file: %v, continuing", e)
 	}
-
-	writeErrorsErrors := writeErrors(resourceErrs, failuresDir, log)
 	for _, e := range writeErrorsErrors {
 		log.Warnf("Error writing errors to file: %v, continuing", e)
 	}
 
 	errs = append(errs, writeResourcesErrors...)
 	errs = append(errs, writeErrorsErrors...)
-	if allResourceListsForbidden(resources, resourceErrs) {
-		log.Warnf("All resource types returned Forbidden for namespace %q", o.userSpecifiedNamespace)
-		errs = append(errs, fmt.Errorf(
-			"all resource types returned Forbidden for namespace %q -- verify the namespace exists and your user has list permissions",
-			o.userSpecifiedNamespace,
-		))
+	if err := writePrunedReport(layout.prunedDir(), pruned); err != nil {
+		log.Warnf("Error writing pruned report: %v, continuing", err)
+		errs = append(errs, err)
+	}
+	if o.normalize {
+		count, cleanErrs := writeCleanResources(o.exportDir, log)
+		for _, e := range cleanErrs {
+			log.Warnf("Error writing normalized manifest: %v, continuing", e)
+		}
+		errs = append(errs, cleanErrs...)
+		log.Infof("Wrote %d manifest(s) without server-populated fields to %s/", count, file.CleanResourcesDirName)
+	}
+	for _, line := range summarizeSkipped(skipped) {
+		log.Infof("Skipped resource types %s", line)
+	}
+	if manifest.Files, err = file.DigestExportFiles(o.exportDir); err == nil {
+		err = file.WriteExportManifest(o.exportDir, manifest)
+	}
+	if err != nil {
+		log.Warnf("Error writing export manifest: %v, continuing", err)
+		errs = append(errs, err)
+	}
+	nsResources[""], nsErrs[""] = acceptedClusterResources, clusterErrs
+	for _, e := range listFailures(o.failOn, namespaces, nsResources, nsErrs) {
+		log.Warnf("%v", e)
+		errs = append(errs, e)
 	}
 	if len(errs) > 0 {
-		log.Warnf("Export completed with %d error(s) for namespace %q", len(errs), o.userSpecifiedNamespace)
+		log.Warnf("Export completed with %d error(s) for namespace(s) %s", len(errs), strings.Join(namespaces, ", "))
 	} else {
-		log.Infof("Export complete for namespace %q", o.userSpecifiedNamespace)
+		log.Infof("Export complete for namespace(s) %s", strings.Join(namespaces, ", "))
 	}
 	return errorsutil.NewAggregate(errs)
 }
@@ -370,17 +776,46 @@ func NewExportCommand(streams genericclioptions.IOStreams, f *flags.GlobalFlags)
 			viper.Unmarshal(&o.globalFlags)
 			viper.Unmarshal(&o.configFlags)
 			viper.UnmarshalKey("export-dir", &o.exportDir)
+			// Explicit flags win over the flags file, which wins over the default profile.
+			o.includeResources = viper.GetStringSlice("include-resources")
+			o.excludeResources = viper.GetStringSlice("exclude-resources")
 		},
 	}
 
 	cmd.Flags().StringVarP(&o.exportDir, "export-dir", "e", "export", "The path where files are to be exported")
 	cmd.Flags().StringVarP(&o.labelSelector, "label-selector", "l", "", "Restrict export to resources matching a label selector")
+	cmd.Flags().StringSliceVar(&o.namespaces, "namespaces", nil, "Comma-separated list of namespaces to export in a single run (cannot be combined with -n/--namespace)")
+	cmd.Flags().StringVar(&o.namespaceSelector, "namespace-selector", "", "Export every namespace matching this label selector (cannot be combined with -n/--namespace)")
+	cmd.Flags().StringVar(&o.objectsFile, "objects-file", "", "Export only the objects listed in this file, one Kind.group/name per line (Kind/name matches any group)")
+	cmd.Flags().StringSliceVar(&o.appRoots, "app-root", nil, "Export only this object (Kind.group/name, e.g. Deployment/web) and the ConfigMaps, Secrets, PVCs, ServiceAccounts, RoleBindings, and Services it depends on (repeatable)")
+	cmd.Flags().StringSliceVar(&o.includeResources, "include-resources", nil, "Only export resource types matching these resource.group patterns, e.g. deployments.apps,configmaps,*.example.com")
+	cmd.Flags().StringSliceVar(&o.excludeResources, "exclude-resources", defaultExcludedResources, "Skip resource types matching these resource.group patterns; replaces the default exclusion profile (pass an empty value to export everything)")
+	cmd.Flags().BoolVar(&o.skipOwned, "skip-owned", false, "Skip objects whose ownerReferences point at another exported object (e.g. ReplicaSets and Pods of a Deployment) and list them under pruned/")
+	cmd.Flags().BoolVar(&o.followClusterReferences, "follow-cluster-references", false, "Also export PriorityClasses, IngressClasses, RuntimeClasses, StorageClasses, and PersistentVolumes referenced by exported objects to _cluster/")
+	cmd.Flags().BoolVar(&o.normalize, "normalize", false, "Also write every manifest to resources-clean/ without status, managedFields, resourceVersion, uid, and other server-populated fields, for reviewing drift between exports")
+	cmd.Flags().StringSliceVar(&o.alternateVersions, "alternate-versions", nil, "Also export every object at these other served API versions (group/version, e.g. autoscaling/v2, or all) under versions/<group>/<version>/")
+	cmd.Flags().StringSliceVar(&o.keepOwnedKinds, "keep-owned-kinds", defaultKeepOwnedKinds, "Kinds (Kind or Kind.group) to keep even when owned, used with --skip-owned")
 	cmd.Flags().StringSliceVar(&o.crdSkipGroups, "crd-skip-group", nil, "Additional API groups to skip for CRD export (repeatable)")
 	cmd.Flags().StringSliceVar(&o.crdIncludeGroups, "crd-include-group", nil, "API groups to force-include for CRD export, even if default-built-in (repeatable)")
 	cmd.Flags().StringVar(&o.asExtras, "as-extras", "", "The extra info for impersonation can only be used with User or Group but is not required. An example is --as-extras key=string1,string2;key2=string3")
 	cmd.Flags().Float32VarP(&o.QPS, "qps", "q", 100, "Query Per Second Rate.")
 	cmd.Flags().IntVarP(&o.Burst, "burst", "b", 1000, "API Burst Rate.")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 1, "Number of resource types to list in parallel (0 or 1 lists sequentially)")
+	cmd.Flags().BoolVar(&o.progress, "progress", false, "Show each export phase and the resource types listed, objects found, and files written so far, with an ETA, on standard error")
+	cmd.Flags().StringVar(&o.eventsJSON, "events-json", "", "Write one JSON event per line (discovery-done, gvr-listed, gvr-failed, file-written, crd-collected) to this file, or to standard output with -")
+	cmd.Flags().BoolVar(&o.checkAccess, "check-access", false, "Print which discovered resource types the current identity (including impersonation) can list and which cluster-scoped lookups would fail, without exporting anything")
+	cmd.Flags().BoolVar(&o.stream, "stream", false, "Write namespaced objects to disk page by page while listing and keep only a lightweight index in memory, for very large namespaces")
+	cmd.Flags().IntVar(&o.maxRetries, "max-retries", 3, "Retries for a list or get request that fails with a transient error (429, 503, server timeout, connection reset); 0 disables retries")
+	cmd.Flags().DurationVar(&o.retryBackoff, "retry-backoff", time.Second, "Delay before the first retry; doubled for each further retry up to 30s, with jitter")
+	cmd.Flags().StringVar(&o.failOn, "fail-on", failOnForbiddenOnly, "When list or get errors make the export exit non-zero: any, forbidden-only (every resource type in a namespace returned Forbidden), or none")
 	cmd.Flags().BoolVar(&o.overwrite, "overwrite", false, "Overwrite the export directory if it already exists")
+	cmd.Flags().StringVar(&o.outputArchive, "output-archive", "", "Write the export to this archive instead of a directory tree; --export-dir is not used")
+	cmd.Flags().StringVar(&o.archiveFormat, "archive-format", archive.FormatTarGz, "Format of --output-archive: tar.gz, or oci for an OCI image layout tarball that can be pushed to a registry")
+	cmd.Flags().StringVar(&o.fromFile, "from-file", "", "Export from a YAML or JSON dump (e.g. kubectl get -o yaml output) instead of a live cluster")
+	cmd.Flags().StringVar(&o.fromVeleroBackup, "from-velero-backup", "", "Export from a Velero backup tarball (velero backup download) instead of a live cluster")
+	cmd.Flags().StringVar(&o.secretsKeyFile, "secrets-key-file", "", "Encrypt Secret data and stringData values with AES-256-GCM using the 256-bit key in this file; only crane apply with the same key can decrypt them (sops cannot)")
+	cmd.Flags().BoolVar(&o.redactSecrets, "redact-secrets", false, "Replace Secret data and stringData values with empty strings and list them in the "+secrets.RedactedFieldsAnnotation+" annotation")
+	cmd.Flags().BoolVar(&o.incremental, "incremental", false, "Update an existing export directory in place, rewriting only objects whose resourceVersion changed and removing deleted ones")
 	o.configFlags.AddFlags(cmd.Flags())
 	flags.SetGroupedHelp(cmd, flags.KubernetesClientInheritedFlagNames())
 	return cmd
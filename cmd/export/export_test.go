@@ -383,6 +383,12 @@ func TestNewExportCommand(t *testing.T) {
 	if d := cmd.Flags().Lookup("burst").DefValue; d != "1000" {
 		t.Errorf("burst default = %q, want %q", d, "1000")
 	}
+	if d := cmd.Flags().Lookup("progress").DefValue; d != "false" {
+		t.Errorf("progress default = %q, want %q", d, "false")
+	}
+	if d := cmd.Flags().Lookup("follow-cluster-references").DefValue; d != "false" {
+		t.Errorf("follow-cluster-references default = %q, want %q", d, "false")
+	}
 }
 
 func TestValidateExportNamespace(t *testing.T) {
@@ -0,0 +1,129 @@
+package export
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/crane/internal/file"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// defaultExcludedResources is the exclusion profile used when --exclude-resources is not
+// set on the command line or in the flags file. These types are recreated by controllers
+// on the target cluster or only describe runtime state of the source cluster.
+var defaultExcludedResources = []string{
+	"events",
+	"events.events.k8s.io",
+	"endpointslices.discovery.k8s.io",
+	"leases.coordination.k8s.io",
+	"pods.metrics.k8s.io",
+}
+
+// resourceFilter decides which API types are listed based on --include-resources and
+// --exclude-resources. Patterns are matched against "resource.group" ("resource" for the
+// core group) with path.Match syntax, case-insensitively. The zero value admits everything.
+type resourceFilter struct {
+	include []string
+	exclude []string
+}
+
+// newResourceFilter normalizes and validates include and exclude patterns.
+func newResourceFilter(include, exclude []string) (resourceFilter, error) {
+	var f resourceFilter
+	var err error
+	if f.include, err = normalizeResourcePatterns("--include-resources", include); err != nil {
+		return resourceFilter{}, err
+	}
+	if f.exclude, err = normalizeResourcePatterns("--exclude-resources", exclude); err != nil {
+		return resourceFilter{}, err
+	}
+	return f, nil
+}
+
+func normalizeResourcePatterns(flag string, patterns []string) ([]string, error) {
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", flag, p, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// groupResourceName returns "resource.group", or "resource" for the core group.
+func groupResourceName(group, resource string) string {
+	if group == "" {
+		return resource
+	}
+	return resource + "." + group
+}
+
+// skipReason returns the manifest skip reason and message for a filtered-out type, or an
+// empty reason when the type should be listed. Exclusions win over inclusions.
+func (f resourceFilter) skipReason(gv schema.GroupVersion, resource metav1.APIResource) (string, string) {
+	name := strings.ToLower(groupResourceName(gv.Group, resource.Name))
+	if p, ok := matchResourcePattern(f.exclude, name); ok {
+		return file.SkipReasonExcluded, fmt.Sprintf("matches --exclude-resources pattern %q", p)
+	}
+	if len(f.include) > 0 {
+		if _, ok := matchResourcePattern(f.include, name); !ok {
+			return file.SkipReasonNotIncluded, "does not match --include-resources"
+		}
+	}
+	return "", ""
+}
+
+func matchResourcePattern(patterns []string, name string) (string, bool) {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// summarizeSkipped returns one line per skip reason with the number of distinct resource
+// types, listing them by name for reasons the user is likely to act on.
+func summarizeSkipped(skipped []file.SkippedResource) []string {
+	byReason := map[string]map[string]struct{}{}
+	for _, s := range skipped {
+		name := groupResourceName(s.Group, s.Resource)
+		if s.Name != "" {
+			name += "/" + s.Name
+		}
+		if byReason[s.Reason] == nil {
+			byReason[s.Reason] = map[string]struct{}{}
+		}
+		byReason[s.Reason][name] = struct{}{}
+	}
+
+	reasons := make([]string, 0, len(byReason))
+	for reason := range byReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	lines := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		names := make([]string, 0, len(byReason[reason]))
+		for name := range byReason[reason] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		switch reason {
+		case file.SkipReasonNoObjects, file.SkipReasonNotAdmitted:
+			lines = append(lines, fmt.Sprintf("%s: %d", reason, len(names)))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %d (%s)", reason, len(names), strings.Join(names, ", ")))
+		}
+	}
+	return lines
+}
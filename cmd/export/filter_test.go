@@ -0,0 +1,87 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/crane/internal/file"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNewResourceFilter(t *testing.T) {
+	f, err := newResourceFilter([]string{" Deployments.Apps ", ""}, []string{"*.metrics.k8s.io"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(f.include, []string{"deployments.apps"}) {
+		t.Errorf("include = %v", f.include)
+	}
+	if !reflect.DeepEqual(f.exclude, []string{"*.metrics.k8s.io"}) {
+		t.Errorf("exclude = %v", f.exclude)
+	}
+
+	if _, err := newResourceFilter([]string{"[pods"}, nil); err == nil {
+		t.Error("expected error for malformed include pattern")
+	}
+	if _, err := newResourceFilter(nil, []string{"[pods"}); err == nil {
+		t.Error("expected error for malformed exclude pattern")
+	}
+}
+
+func TestResourceFilter_skipReason(t *testing.T) {
+	core := schema.GroupVersion{Version: "v1"}
+	apps := schema.GroupVersion{Group: "apps", Version: "v1"}
+	metrics := schema.GroupVersion{Group: "metrics.k8s.io", Version: "v1beta1"}
+	events := schema.GroupVersion{Group: "events.k8s.io", Version: "v1"}
+
+	tests := []struct {
+		name       string
+		include    []string
+		exclude    []string
+		gv         schema.GroupVersion
+		resource   string
+		wantReason string
+	}{
+		{name: "zero filter admits everything", gv: core, resource: "events"},
+		{name: "default profile skips core events", exclude: defaultExcludedResources, gv: core, resource: "events", wantReason: file.SkipReasonExcluded},
+		{name: "default profile skips events.k8s.io events", exclude: defaultExcludedResources, gv: events, resource: "events", wantReason: file.SkipReasonExcluded},
+		{name: "default profile keeps pods", exclude: defaultExcludedResources, gv: core, resource: "pods"},
+		{name: "group wildcard", exclude: []string{"*.metrics.k8s.io"}, gv: metrics, resource: "pods", wantReason: file.SkipReasonExcluded},
+		{name: "core pattern does not match other groups", exclude: []string{"pods"}, gv: metrics, resource: "pods"},
+		{name: "include admits match", include: []string{"deployments.apps"}, gv: apps, resource: "deployments"},
+		{name: "include rejects non-match", include: []string{"deployments.apps"}, gv: apps, resource: "statefulsets", wantReason: file.SkipReasonNotIncluded},
+		{name: "exclude wins over include", include: []string{"*.apps"}, exclude: []string{"statefulsets.apps"}, gv: apps, resource: "statefulsets", wantReason: file.SkipReasonExcluded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newResourceFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reason, _ := f.skipReason(tt.gv, metav1.APIResource{Name: tt.resource})
+			if reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSummarizeSkipped(t *testing.T) {
+	skipped := []file.SkippedResource{
+		{Namespace: "a", Resource: "events", Reason: file.SkipReasonExcluded},
+		{Namespace: "b", Resource: "events", Reason: file.SkipReasonExcluded},
+		{Namespace: "a", Group: "coordination.k8s.io", Resource: "leases", Reason: file.SkipReasonExcluded},
+		{Namespace: "a", Resource: "configmaps", Reason: file.SkipReasonNoObjects},
+		{Namespace: "a", Resource: "secrets", Reason: file.SkipReasonNoObjects},
+		{Group: crdGVR.Group, Resource: crdGVR.Resource, Name: "widgets.example.com", Reason: file.SkipReasonOperatorManagedCRD},
+	}
+	want := []string{
+		"Excluded: 2 (events, leases.coordination.k8s.io)",
+		"NoObjects: 2",
+		"OperatorManagedCRD: 1 (customresourcedefinitions.apiextensions.k8s.io/widgets.example.com)",
+	}
+	if got := summarizeSkipped(skipped); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/konveyor/crane/internal/helm"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// captureHelmReleases decodes the Helm release Secrets among resources and returns the
+// latest revision of each release, sorted by namespace and name. Secrets that cannot be
+// decoded are logged and skipped; the Secrets themselves are exported either way.
+// Streamed resources are no longer in memory; their releases were decoded as they were
+// written and are passed in streamed.
+func captureHelmReleases(resources []*groupResource, streamed []*helm.Release, log logrus.FieldLogger) []*helm.Release {
+	latest := map[string]*helm.Release{}
+	keepLatest := func(r *helm.Release) {
+		if prev, ok := latest[r.Key()]; !ok || r.Revision > prev.Revision {
+			latest[r.Key()] = r
+		}
+	}
+	for _, r := range streamed {
+		keepLatest(r)
+	}
+	for _, g := range resources {
+		if g.streamed {
+			continue
+		}
+		for _, obj := range g.objects.Items {
+			if !helm.IsReleaseSecret(obj) {
+				continue
+			}
+			r, err := helm.DecodeSecret(obj)
+			if err != nil {
+				log.Warnf("Cannot decode Helm release: %v, continuing", err)
+				continue
+			}
+			keepLatest(r)
+		}
+	}
+	releases := make([]*helm.Release, 0, len(latest))
+	for _, r := range latest {
+		releases = append(releases, r)
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Key() < releases[j].Key() })
+	return releases
+}
+
+// helmReleaseFinder tells which of the captured releases an object belongs to: the
+// release Helm recorded on it, or else the release whose rendered manifest contains it.
+// The release Secrets belong to their release as well.
+type helmReleaseFinder struct {
+	captured map[string]bool
+	rendered map[helm.ObjectKey]string
+}
+
+func newHelmReleaseFinder(releases []*helm.Release) helmReleaseFinder {
+	f := helmReleaseFinder{captured: map[string]bool{}, rendered: map[helm.ObjectKey]string{}}
+	for _, r := range releases {
+		f.captured[r.Key()] = true
+		for _, key := range r.ManifestObjects() {
+			f.rendered[key] = r.Key()
+		}
+	}
+	return f
+}
+
+// releaseOf returns the "<namespace>/<release>" obj belongs to, or "" for none.
+func (f helmReleaseFinder) releaseOf(obj unstructured.Unstructured) string {
+	release := helm.RecordedRelease(obj)
+	if !f.captured[release] {
+		release = f.rendered[helm.KeyOf(obj)]
+	}
+	return release
+}
+
+// setHelmReleaseAnnotation sets helm.ReleaseAnnotation on obj to release.
+func setHelmReleaseAnnotation(obj *unstructured.Unstructured, release string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[helm.ReleaseAnnotation] = release
+	obj.SetAnnotations(annotations)
+}
+
+// annotateHelmObjects sets helm.ReleaseAnnotation on every object in resources that
+// belongs to one of releases, and returns how many it annotated. Streamed resources are
+// annotated on disk by annotateStreamedHelmObjects.
+func annotateHelmObjects(resources []*groupResource, releases []*helm.Release) int {
+	finder := newHelmReleaseFinder(releases)
+	count := 0
+	for _, g := range resources {
+		if g.streamed {
+			continue
+		}
+		for i := range g.objects.Items {
+			obj := &g.objects.Items[i]
+			if release := finder.releaseOf(*obj); release != "" {
+				setHelmReleaseAnnotation(obj, release)
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// annotateStreamedHelmObjects sets helm.ReleaseAnnotation in the files of the streamed
+// objects in resources that belong to one of releases, and returns how many it
+// annotated. Their index entries keep what helm.RecordedRelease reads. Secrets were
+// already encrypted or redacted; the SOPS MAC only covers encrypted values, so the
+// annotation can still be added. Files removed since, such as pruned objects, are skipped.
+func annotateStreamedHelmObjects(layout exportLayout, resources []*groupResource, releases []*helm.Release) (int, []error) {
+	finder := newHelmReleaseFinder(releases)
+	count := 0
+	var errs []error
+	for _, g := range resources {
+		if !g.streamed {
+			continue
+		}
+		for _, entry := range g.objects.Items {
+			release := finder.releaseOf(entry)
+			if release == "" {
+				continue
+			}
+			path := filepath.Join(layout.exportDir, filepath.FromSlash(layout.objectPath(entry)))
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			obj := unstructured.Unstructured{}
+			if err == nil {
+				err = yaml.Unmarshal(data, &obj.Object)
+			}
+			if err == nil {
+				setHelmReleaseAnnotation(&obj, release)
+				data, err = yaml.Marshal(obj.Object)
+			}
+			if err == nil {
+				err = os.WriteFile(path, data, 0666)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("annotate %s with its Helm release: %w", path, err))
+				continue
+			}
+			count++
+		}
+	}
+	return count, errs
+}
+
+// writeHelmReleases rebuilds the helm directory with one file per release. Values and the
+// rendered manifest can hold credentials, so they are left out unless withContent is set,
+// which run does only when Secrets are exported as they are.
+func writeHelmReleases(layout exportLayout, releases []*helm.Release, withContent bool) []error {
+	dir := filepath.Join(layout.exportDir, file.HelmDirName)
+	if err := os.RemoveAll(dir); err != nil {
+		return []error{fmt.Errorf("clear %s: %w", dir, err)}
+	}
+	var errs []error
+	for _, r := range releases {
+		out := *r
+		if !withContent {
+			out.Values, out.Manifest = nil, ""
+		}
+		path := layout.helmReleasePath(r.Namespace, r.Name)
+		data, err := yaml.Marshal(out)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+		}
+		if err == nil {
+			err = os.WriteFile(path, data, 0600)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("write Helm release %s: %w", r.Key(), err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/konveyor/crane/internal/helm"
+	"sigs.k8s.io/yaml"
+)
+
+// helmReleaseSecret returns the YAML of the Secret Helm stores revision of the web release
+// in, installed from chart web-1.2.0 with values replicas: <revision>.
+func helmReleaseSecret(t *testing.T, revision int) string {
+	t.Helper()
+	record := fmt.Sprintf(`{"name":"web","namespace":"app","version":%d,"info":{"status":"deployed"},
+"chart":{"metadata":{"name":"web","version":"1.2.0"}},"config":{"replicas":%d},
+"manifest":"---\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"}`, revision, revision)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(record)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := base64.StdEncoding.EncodeToString([]byte(base64.StdEncoding.EncodeToString(buf.Bytes())))
+	return fmt.Sprintf(`apiVersion: v1
+kind: Secret
+type: helm.sh/release.v1
+metadata:
+  name: sh.helm.release.v1.web.v%d
+  namespace: app
+  labels: {name: web, owner: helm, version: "%d"}
+data:
+  release: %s
+`, revision, revision, data)
+}
+
+func TestHelmReleases(t *testing.T) {
+	resources := selectionResources(t,
+		helmReleaseSecret(t, 1),
+		helmReleaseSecret(t, 2),
+		"apiVersion: v1\nkind: Secret\ntype: helm.sh/release.v1\nmetadata: {name: sh.helm.release.v1.broken.v1, namespace: app}\ndata: {release: '!!'}\n",
+		"apiVersion: v1\nkind: Service\nmetadata: {name: web, namespace: app}\n",
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: app\n  annotations: {meta.helm.sh/release-name: web, meta.helm.sh/release-namespace: app}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: unrelated, namespace: app}\n",
+	)
+	releases := captureHelmReleases(resources, nil, testLogger())
+	if len(releases) != 1 || releases[0].Revision != 2 || releases[0].Chart.Version != "1.2.0" {
+		t.Fatalf("captureHelmReleases = %+v, want revision 2 of app/web", releases)
+	}
+
+	if n := annotateHelmObjects(resources, releases); n != 4 {
+		t.Errorf("annotated %d object(s), want 4 (two release Secrets, the Service, and the Deployment)", n)
+	}
+	for _, g := range resources {
+		for _, obj := range g.objects.Items {
+			got := obj.GetAnnotations()[helm.ReleaseAnnotation]
+			want := "app/web"
+			if obj.GetName() == "unrelated" || strings.Contains(obj.GetName(), "broken") {
+				want = ""
+			}
+			if got != want {
+				t.Errorf("%s/%s annotation = %q, want %q", obj.GetKind(), obj.GetName(), got, want)
+			}
+		}
+	}
+
+	layout := exportLayout{exportDir: t.TempDir(), namespaces: []string{"app"}}
+	for _, withContent := range []bool{true, false} {
+		if errs := writeHelmReleases(layout, releases, withContent); len(errs) != 0 {
+			t.Fatal(errs)
+		}
+		data, err := os.ReadFile(filepath.Join(layout.exportDir, file.HelmDirName, "web.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var written helm.Release
+		if err := yaml.Unmarshal(data, &written); err != nil {
+			t.Fatal(err)
+		}
+		if written.Chart.Name != "web" || (written.Values != nil) != withContent || (written.Manifest != "") != withContent {
+			t.Errorf("withContent=%v: wrote\n%s", withContent, data)
+		}
+	}
+}
+
+func TestRun_HelmReleaseWithRedactedSecrets(t *testing.T) {
+	dump := filepath.Join(t.TempDir(), "dump.yaml")
+	docs := []string{
+		helmReleaseSecret(t, 1),
+		"apiVersion: v1\nkind: Service\nmetadata: {name: web, namespace: app}\n",
+	}
+	if err := os.WriteFile(dump, []byte(strings.Join(docs, "---\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	filter, err := newResourceFilter(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With --stream, objects are written as they are listed, before the releases are
+	// known; they are annotated on disk afterwards.
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			exportDir := filepath.Join(t.TempDir(), "export")
+			o := &ExportOptions{
+				exportDir:      exportDir,
+				fromFile:       dump,
+				namespaces:     []string{"app"},
+				resourceFilter: filter,
+				redactSecrets:  true,
+				stream:         stream,
+			}
+			if err := o.run(); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(exportDir, file.HelmDirName, "web.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), "version: 1.2.0") || strings.Contains(string(data), "replicas") {
+				t.Errorf("helm/web.yaml:\n%s", data)
+			}
+			for _, name := range []string{"Service__v1_app_web.yaml", "Secret__v1_app_sh.helm.release.v1.web.v1.yaml"} {
+				obj, err := os.ReadFile(filepath.Join(exportDir, "resources", "app", name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(obj), helm.ReleaseAnnotation+": app/web") {
+					t.Errorf("%s is not annotated:\n%s", name, obj)
+				}
+			}
+		})
+	}
+}
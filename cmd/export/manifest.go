@@ -0,0 +1,232 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/crane/internal/file"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// incrementalDelta is the outcome of comparing this run's exported resources with the
+// previous export manifest.
+type incrementalDelta struct {
+	added, changed, removed, unchanged int
+	// upToDate holds paths whose file already matches the cluster and need not be rewritten.
+	upToDate map[string]bool
+	// stale holds paths of previously exported objects that no longer exist.
+	stale []string
+}
+
+func resourceKey(r file.ExportedResource) string {
+	return strings.Join([]string{r.Namespace, r.Group, r.Version, r.Resource}, "/")
+}
+
+// objectPath returns the path of obj relative to the export directory, matching
+// the location writeResources uses.
+func (l exportLayout) objectPath(obj unstructured.Unstructured) string {
+	dir := l.clusterResourceDir()
+	if ns := obj.GetNamespace(); ns != "" {
+		dir = l.resourceDir(ns)
+	}
+	rel, err := filepath.Rel(l.exportDir, filepath.Join(dir, file.GetResourceFilename(obj)))
+	if err != nil {
+		rel = filepath.Join(dir, file.GetResourceFilename(obj))
+	}
+	return filepath.ToSlash(rel)
+}
+
+// buildExportedResources records every object in resources with its UID and resourceVersion,
+// grouped by namespace and GVR and sorted so the manifest is stable between runs.
+func buildExportedResources(layout exportLayout, resources []*groupResource) []file.ExportedResource {
+	byKey := map[string]*file.ExportedResource{}
+	for _, g := range resources {
+		if g == nil || g.objects == nil || g.APIResource.Kind == "" {
+			continue
+		}
+		for _, obj := range g.objects.Items {
+			r := file.ExportedResource{
+				Namespace: obj.GetNamespace(),
+				Group:     g.APIGroup,
+				Version:   g.APIVersion,
+				Resource:  g.APIResource.Name,
+				Kind:      g.APIResource.Kind,
+			}
+			existing, ok := byKey[resourceKey(r)]
+			if !ok {
+				existing = &r
+				byKey[resourceKey(r)] = existing
+			}
+			if existing.ResourceVersion == "" {
+				existing.ResourceVersion = g.objects.GetResourceVersion()
+			}
+			existing.Count++
+			existing.Objects = append(existing.Objects, file.ExportedObject{
+				Path:            layout.objectPath(obj),
+				UID:             string(obj.GetUID()),
+				ResourceVersion: obj.GetResourceVersion(),
+			})
+		}
+	}
+	out := make([]file.ExportedResource, 0, len(byKey))
+	for _, r := range byKey {
+		sortExportedObjects(r.Objects)
+		out = append(out, *r)
+	}
+	sortExportedResources(out)
+	return out
+}
+
+func sortExportedResources(resources []file.ExportedResource) {
+	sort.Slice(resources, func(i, j int) bool { return resourceKey(resources[i]) < resourceKey(resources[j]) })
+}
+
+func sortExportedObjects(objects []file.ExportedObject) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Path < objects[j].Path })
+}
+
+// failedResourceName maps a list or CRD failure to the resource name used in the manifest.
+func failedResourceName(e *groupResourceError) string {
+	if strings.HasPrefix(e.APIResource.Name, crdFailureAPIResourceName("")) {
+		return crdGVR.Resource
+	}
+	for _, k := range referencedKinds {
+		if strings.HasPrefix(e.APIResource.Name, referenceFailureAPIResourceName(k, "")) {
+			return k.gvr.Resource
+		}
+	}
+	return e.APIResource.Name
+}
+
+// retainFailedResources carries over previously exported objects for resources that could
+// not be read in this run, so an incremental export never deletes manifests because of a
+// transient list error. failures maps a namespace ("" for cluster-scoped) to its errors.
+func retainFailedResources(previous, current []file.ExportedResource, failures map[string][]*groupResourceError) []file.ExportedResource {
+	failed := map[string]bool{}
+	for namespace, errs := range failures {
+		for _, e := range errs {
+			if e == nil {
+				continue
+			}
+			failed[namespace+"/"+failedResourceName(e)] = true
+		}
+	}
+	if len(failed) == 0 {
+		return current
+	}
+
+	byKey := make(map[string]int, len(current))
+	for i, r := range current {
+		byKey[resourceKey(r)] = i
+	}
+	for _, r := range previous {
+		if !failed[r.Namespace+"/"+r.Resource] {
+			continue
+		}
+		i, ok := byKey[resourceKey(r)]
+		if !ok {
+			current = append(current, r)
+			byKey[resourceKey(r)] = len(current) - 1
+			continue
+		}
+		known := map[string]bool{}
+		for _, o := range current[i].Objects {
+			known[o.Path] = true
+		}
+		for _, o := range r.Objects {
+			if !known[o.Path] {
+				current[i].Objects = append(current[i].Objects, o)
+			}
+		}
+		current[i].Count = len(current[i].Objects)
+		sortExportedObjects(current[i].Objects)
+	}
+	sortExportedResources(current)
+	return current
+}
+
+// diffExportedResources compares current with previous. An object is up to date when its UID
+// and resourceVersion are unchanged and its file still exists under exportDir.
+func diffExportedResources(exportDir string, previous, current []file.ExportedResource) incrementalDelta {
+	prevObjects := map[string]file.ExportedObject{}
+	for _, w := range previous {
+		for _, o := range w.Objects {
+			prevObjects[o.Path] = o
+		}
+	}
+
+	delta := incrementalDelta{upToDate: map[string]bool{}}
+	seen := map[string]bool{}
+	for _, w := range current {
+		for _, o := range w.Objects {
+			seen[o.Path] = true
+			prev, ok := prevObjects[o.Path]
+			switch {
+			case !ok:
+				delta.added++
+			case prev.UID == o.UID && prev.ResourceVersion == o.ResourceVersion && fileExists(filepath.Join(exportDir, filepath.FromSlash(o.Path))):
+				delta.unchanged++
+				delta.upToDate[o.Path] = true
+			default:
+				delta.changed++
+			}
+		}
+	}
+	for path := range prevObjects {
+		if !seen[path] {
+			delta.stale = append(delta.stale, path)
+		}
+	}
+	sort.Strings(delta.stale)
+	delta.removed = len(delta.stale)
+	return delta
+}
+
+// withoutUpToDate returns copies of resources that omit objects whose files are up to date.
+func withoutUpToDate(layout exportLayout, resources []*groupResource, upToDate map[string]bool) []*groupResource {
+	if len(upToDate) == 0 {
+		return resources
+	}
+	out := make([]*groupResource, 0, len(resources))
+	for _, g := range resources {
+		if g == nil || g.objects == nil {
+			out = append(out, g)
+			continue
+		}
+		items := make([]unstructured.Unstructured, 0, len(g.objects.Items))
+		for _, obj := range g.objects.Items {
+			if !upToDate[layout.objectPath(obj)] {
+				items = append(items, obj)
+			}
+		}
+		copied := *g
+		copied.objects = &unstructured.UnstructuredList{Object: g.objects.Object, Items: items}
+		out = append(out, &copied)
+	}
+	return out
+}
+
+// removeStaleManifests deletes the files of objects that no longer exist in the cluster.
+// Paths that would escape exportDir are rejected.
+func removeStaleManifests(exportDir string, stale []string) []error {
+	errs := []error{}
+	for _, path := range stale {
+		if !filepath.IsLocal(filepath.FromSlash(path)) {
+			errs = append(errs, fmt.Errorf("refusing to remove %q: path is outside the export directory", path))
+			continue
+		}
+		if err := os.Remove(filepath.Join(exportDir, filepath.FromSlash(path))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
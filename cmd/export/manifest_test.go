@@ -0,0 +1,181 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/crane/internal/file"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestBuildExportedResources(t *testing.T) {
+	layout := exportLayout{exportDir: "export", namespaces: []string{"app"}}
+	configMaps := testGroupResource("configmaps", "ConfigMap", true,
+		namespacedObj("app", "b", withKind("v1", "ConfigMap"), withUID("uid-b"), withResourceVersion("12")),
+		namespacedObj("app", "a", withKind("v1", "ConfigMap"), withUID("uid-a"), withResourceVersion("11")),
+	)
+	configMaps.objects.SetResourceVersion("100")
+	clusterRoles := testGroupResource("clusterroles", "ClusterRole", false,
+		clusterScopedObj("reader", withKind("v1", "ClusterRole"), withUID("uid-r"), withResourceVersion("7")),
+	)
+	clusterRoles.objects.SetResourceVersion("200")
+	resources := []*groupResource{configMaps, clusterRoles}
+
+	got := buildExportedResources(layout, resources)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 watermarks, got %d: %+v", len(got), got)
+	}
+	// Cluster-scoped watermark sorts first (empty namespace).
+	if got[0].Resource != "clusterroles" || got[0].Namespace != "" || got[0].ResourceVersion != "200" {
+		t.Errorf("unexpected cluster watermark: %+v", got[0])
+	}
+	wantPath := filepath.ToSlash(filepath.Join("resources", "app", clusterScopeDirName, file.GetResourceFilename(resources[1].objects.Items[0])))
+	if got[0].Objects[0].Path != wantPath {
+		t.Errorf("cluster object path = %q, want %q", got[0].Objects[0].Path, wantPath)
+	}
+	cm := got[1]
+	if cm.Resource != "configmaps" || cm.Namespace != "app" || cm.ResourceVersion != "100" {
+		t.Errorf("unexpected configmap watermark: %+v", cm)
+	}
+	if len(cm.Objects) != 2 || cm.Objects[0].UID != "uid-a" || cm.Objects[1].UID != "uid-b" {
+		t.Errorf("expected objects sorted by path, got %+v", cm.Objects)
+	}
+	if cm.Kind != "ConfigMap" || cm.Count != 2 {
+		t.Errorf("kind/count = %q/%d, want ConfigMap/2", cm.Kind, cm.Count)
+	}
+	if cm.Objects[0].ResourceVersion != "11" {
+		t.Errorf("object resourceVersion = %q, want 11", cm.Objects[0].ResourceVersion)
+	}
+}
+
+func TestDiffExportedResources(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"resources/app/same.yaml", "resources/app/changed.yaml", "resources/app/gone.yaml"} {
+		path := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous := []file.ExportedResource{{
+		Namespace: "app", Version: "v1", Resource: "configmaps",
+		Objects: []file.ExportedObject{
+			{Path: "resources/app/changed.yaml", UID: "u1", ResourceVersion: "1"},
+			{Path: "resources/app/deleted-file.yaml", UID: "u2", ResourceVersion: "1"},
+			{Path: "resources/app/gone.yaml", UID: "u3", ResourceVersion: "1"},
+			{Path: "resources/app/recreated.yaml", UID: "u4", ResourceVersion: "1"},
+			{Path: "resources/app/same.yaml", UID: "u5", ResourceVersion: "1"},
+		},
+	}}
+	current := []file.ExportedResource{{
+		Namespace: "app", Version: "v1", Resource: "configmaps",
+		Objects: []file.ExportedObject{
+			{Path: "resources/app/changed.yaml", UID: "u1", ResourceVersion: "2"},
+			// Unchanged in the cluster but the file was deleted locally.
+			{Path: "resources/app/deleted-file.yaml", UID: "u2", ResourceVersion: "1"},
+			{Path: "resources/app/new.yaml", UID: "u6", ResourceVersion: "1"},
+			// Same name, new UID: the object was deleted and recreated.
+			{Path: "resources/app/recreated.yaml", UID: "u7", ResourceVersion: "1"},
+			{Path: "resources/app/same.yaml", UID: "u5", ResourceVersion: "1"},
+		},
+	}}
+
+	delta := diffExportedResources(dir, previous, current)
+	if delta.added != 1 || delta.changed != 3 || delta.removed != 1 || delta.unchanged != 1 {
+		t.Errorf("unexpected counts: added=%d changed=%d removed=%d unchanged=%d", delta.added, delta.changed, delta.removed, delta.unchanged)
+	}
+	if !reflect.DeepEqual(delta.upToDate, map[string]bool{"resources/app/same.yaml": true}) {
+		t.Errorf("unexpected upToDate: %v", delta.upToDate)
+	}
+	if !reflect.DeepEqual(delta.stale, []string{"resources/app/gone.yaml"}) {
+		t.Errorf("unexpected stale: %v", delta.stale)
+	}
+}
+
+func TestRetainFailedResources(t *testing.T) {
+	previous := []file.ExportedResource{
+		{Namespace: "app", Version: "v1", Resource: "secrets", Objects: []file.ExportedObject{{Path: "resources/app/s.yaml"}}},
+		{Namespace: "app", Version: "v1", Resource: "configmaps", Objects: []file.ExportedObject{{Path: "resources/app/c.yaml"}}},
+		{Group: crdGVR.Group, Version: crdGVR.Version, Resource: crdGVR.Resource, Objects: []file.ExportedObject{
+			{Path: "resources/app/_cluster/a.yaml"},
+			{Path: "resources/app/_cluster/b.yaml"},
+		}},
+	}
+	current := []file.ExportedResource{
+		{Group: crdGVR.Group, Version: crdGVR.Version, Resource: crdGVR.Resource, Objects: []file.ExportedObject{
+			{Path: "resources/app/_cluster/a.yaml"},
+		}},
+	}
+	failures := map[string][]*groupResourceError{
+		"":    {{APIResource: metav1.APIResource{Name: crdFailureAPIResourceName("b.example.com")}}},
+		"app": {{APIResource: metav1.APIResource{Name: "secrets"}}},
+	}
+
+	got := retainFailedResources(previous, current, failures)
+	if len(got) != 2 {
+		t.Fatalf("expected CRD and secrets watermarks, got %+v", got)
+	}
+	if got[0].Resource != crdGVR.Resource || len(got[0].Objects) != 2 || got[0].Count != 2 {
+		t.Errorf("expected failed CRD to be retained alongside fetched one, got %+v", got[0])
+	}
+	if got[1].Resource != "secrets" {
+		t.Errorf("expected secrets watermark to be retained, got %+v", got[1])
+	}
+}
+
+func TestWithoutUpToDate(t *testing.T) {
+	layout := exportLayout{exportDir: "export", namespaces: []string{"app"}}
+	keep := namespacedObj("app", "keep", withKind("v1", "ConfigMap"), withUID("u1"), withResourceVersion("2"))
+	skip := namespacedObj("app", "skip", withKind("v1", "ConfigMap"), withUID("u2"), withResourceVersion("1"))
+	configMaps := testGroupResource("configmaps", "ConfigMap", true, keep, skip)
+	configMaps.objects.SetResourceVersion("5")
+	resources := []*groupResource{configMaps}
+
+	got := withoutUpToDate(layout, resources, map[string]bool{layout.objectPath(skip): true})
+	if len(got) != 1 || len(got[0].objects.Items) != 1 || got[0].objects.Items[0].GetName() != "keep" {
+		t.Fatalf("expected only the changed object, got %+v", got[0].objects.Items)
+	}
+	if len(resources[0].objects.Items) != 2 {
+		t.Error("input resources must not be modified")
+	}
+}
+
+func TestRemoveStaleManifests(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "resources", "app", "gone.yaml")
+	if err := os.MkdirAll(filepath.Dir(stale), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := removeStaleManifests(dir, []string{"resources/app/gone.yaml", "resources/app/already-gone.yaml", "../outside.yaml"})
+	if len(errs) != 1 {
+		t.Fatalf("expected only the escaping path to fail, got %v", errs)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected stale manifest to be removed, stat err = %v", err)
+	}
+}
+
+func TestValidate_IncrementalConflictsWithOverwrite(t *testing.T) {
+	o := &ExportOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		incremental: true,
+		overwrite:   true,
+	}
+	if err := o.Validate(); err == nil {
+		t.Fatal("expected error for --incremental with --overwrite")
+	}
+	o.overwrite = false
+	if err := o.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clusterScopeDirName is the directory used for cluster-scoped manifests and failures.
+const clusterScopeDirName = "_cluster"
+
+// normalizeNamespaces trims, drops duplicates, and rejects empty entries from a
+// --namespaces list while preserving the order given by the user.
+func normalizeNamespaces(namespaces []string) ([]string, error) {
+	out := make([]string, 0, len(namespaces))
+	seen := make(map[string]struct{}, len(namespaces))
+	for i, ns := range namespaces {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			return nil, fmt.Errorf("--namespaces entry %d is empty", i+1)
+		}
+		if _, ok := seen[ns]; ok {
+			continue
+		}
+		seen[ns] = struct{}{}
+		out = append(out, ns)
+	}
+	return out, nil
+}
+
+// resolveExportNamespaces returns the namespaces to export: the explicit --namespaces list,
+// the namespaces matching --namespace-selector (sorted by name), or the single namespace
+// from -n/--namespace or the kubeconfig context.
+func (o *ExportOptions) resolveExportNamespaces(ctx context.Context, client kubernetes.Interface, log logrus.FieldLogger) ([]string, error) {
+	if len(o.namespaces) > 0 {
+		return o.namespaces, nil
+	}
+	if o.namespaceSelector == "" {
+		return []string{o.userSpecifiedNamespace}, nil
+	}
+
+	list, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: o.namespaceSelector})
+	if err != nil {
+		return nil, fmt.Errorf("list namespaces matching --namespace-selector %q: %w", o.namespaceSelector, err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		names = append(names, ns.Name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no namespaces match --namespace-selector %q", o.namespaceSelector)
+	}
+	sort.Strings(names)
+	log.Debugf("Namespace selector %q matched %d namespace(s): %s", o.namespaceSelector, len(names), strings.Join(names, ", "))
+	return names, nil
+}
+
+// exportLayout resolves the on-disk directories for an export run. A single-namespace
+// export keeps cluster-scoped manifests under resources/<ns>/_cluster; a multi-namespace
+// export writes them once under resources/_cluster and failures/_cluster.
+type exportLayout struct {
+	exportDir  string
+	namespaces []string
+}
+
+func (l exportLayout) multiNamespace() bool {
+	return len(l.namespaces) > 1
+}
+
+// resourceDir returns resources/<ns>.
+func (l exportLayout) resourceDir(namespace string) string {
+	return filepath.Join(l.exportDir, "resources", namespace)
+}
+
+// failuresDir returns failures/<ns>.
+func (l exportLayout) failuresDir(namespace string) string {
+	return filepath.Join(l.exportDir, file.FailuresDirName, namespace)
+}
+
+// clusterResourceDir returns the directory for cluster-scoped manifests.
+func (l exportLayout) clusterResourceDir() string {
+	if l.multiNamespace() {
+		return filepath.Join(l.exportDir, "resources", clusterScopeDirName)
+	}
+	return filepath.Join(l.resourceDir(l.namespaces[0]), clusterScopeDirName)
+}
+
+// clusterFailuresDir returns the directory for cluster-scoped list and CRD failures.
+func (l exportLayout) clusterFailuresDir() string {
+	if l.multiNamespace() {
+		return filepath.Join(l.exportDir, file.FailuresDirName, clusterScopeDirName)
+	}
+	return l.failuresDir(l.namespaces[0])
+}
+
+// prunedDir returns the directory for the --skip-owned report.
+func (l exportLayout) prunedDir() string {
+	return filepath.Join(l.exportDir, file.PrunedDirName)
+}
+
+// helmReleasePath returns helm/<release>.yaml, or helm/<ns>/<release>.yaml in a
+// multi-namespace export, where release names may repeat across namespaces.
+func (l exportLayout) helmReleasePath(namespace, release string) string {
+	if l.multiNamespace() {
+		return filepath.Join(l.exportDir, file.HelmDirName, namespace, release+".yaml")
+	}
+	return filepath.Join(l.exportDir, file.HelmDirName, release+".yaml")
+}
@@ -0,0 +1,179 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+func TestNormalizeNamespaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "keeps order", in: []string{"b", "a", "c"}, want: []string{"b", "a", "c"}},
+		{name: "trims and dedupes", in: []string{" a", "b ", "a"}, want: []string{"a", "b"}},
+		{name: "empty entry", in: []string{"a", " "}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeNamespaces(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveExportNamespaces(t *testing.T) {
+	labeled := func(name string, labels map[string]string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	client := fake.NewClientset(
+		labeled("payments-db", map[string]string{"team": "payments"}),
+		labeled("payments-api", map[string]string{"team": "payments"}),
+		labeled("search", map[string]string{"team": "search"}),
+	)
+
+	t.Run("explicit list wins", func(t *testing.T) {
+		o := &ExportOptions{namespaces: []string{"x", "y"}, userSpecifiedNamespace: "ctx"}
+		got, err := o.resolveExportNamespaces(context.Background(), client, testLogger())
+		if err != nil || !reflect.DeepEqual(got, []string{"x", "y"}) {
+			t.Fatalf("got %v, %v", got, err)
+		}
+	})
+
+	t.Run("single namespace fallback", func(t *testing.T) {
+		o := &ExportOptions{userSpecifiedNamespace: "ctx"}
+		got, err := o.resolveExportNamespaces(context.Background(), client, testLogger())
+		if err != nil || !reflect.DeepEqual(got, []string{"ctx"}) {
+			t.Fatalf("got %v, %v", got, err)
+		}
+	})
+
+	t.Run("selector sorted by name", func(t *testing.T) {
+		o := &ExportOptions{namespaceSelector: "team=payments"}
+		got, err := o.resolveExportNamespaces(context.Background(), client, testLogger())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, []string{"payments-api", "payments-db"}) {
+			t.Fatalf("got %v", got)
+		}
+	})
+
+	t.Run("selector without matches", func(t *testing.T) {
+		o := &ExportOptions{namespaceSelector: "team=none"}
+		_, err := o.resolveExportNamespaces(context.Background(), client, testLogger())
+		if err == nil || !strings.Contains(err.Error(), "no namespaces match") {
+			t.Fatalf("got %v", err)
+		}
+	})
+
+	t.Run("selector list forbidden", func(t *testing.T) {
+		forbidden := fake.NewClientset()
+		forbidden.PrependReactor("list", "namespaces", func(action kubetesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "", errors.New("no"))
+		})
+		o := &ExportOptions{namespaceSelector: "team=payments"}
+		_, err := o.resolveExportNamespaces(context.Background(), forbidden, testLogger())
+		if err == nil || !apierrors.IsForbidden(errors.Unwrap(err)) {
+			t.Fatalf("got %v", err)
+		}
+	})
+}
+
+func TestValidate_NamespaceSelection(t *testing.T) {
+	tests := []struct {
+		name              string
+		namespaces        []string
+		namespaceSelector string
+		wantErr           bool
+	}{
+		{name: "namespaces only - ok", namespaces: []string{"a", "b"}},
+		{name: "selector only - ok", namespaceSelector: "team=payments"},
+		{name: "both - error", namespaces: []string{"a"}, namespaceSelector: "team=payments", wantErr: true},
+		{name: "invalid selector - error", namespaceSelector: "team in (x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &ExportOptions{
+				configFlags:       genericclioptions.NewConfigFlags(true),
+				namespaces:        tt.namespaces,
+				namespaceSelector: tt.namespaceSelector,
+			}
+			err := o.Validate()
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestComplete_NamespaceFlagConflictsWithNamespaces(t *testing.T) {
+	o := &ExportOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		namespaces:  []string{"a", "b"},
+	}
+	emptyKube := ""
+	o.configFlags.KubeConfig = &emptyKube
+
+	cmd := &cobra.Command{}
+	o.configFlags.AddFlags(cmd.Flags())
+	if err := cmd.ParseFlags([]string{"--namespace", "a"}); err != nil {
+		t.Fatalf("ParseFlags: %v", err)
+	}
+
+	err := o.Complete(cmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "cannot use -n/--namespace with --namespaces") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestExportLayout(t *testing.T) {
+	single := exportLayout{exportDir: "export", namespaces: []string{"app"}}
+	if got, want := single.clusterResourceDir(), filepath.Join("export", "resources", "app", "_cluster"); got != want {
+		t.Errorf("single clusterResourceDir = %q, want %q", got, want)
+	}
+	if got, want := single.clusterFailuresDir(), filepath.Join("export", "failures", "app"); got != want {
+		t.Errorf("single clusterFailuresDir = %q, want %q", got, want)
+	}
+
+	multi := exportLayout{exportDir: "export", namespaces: []string{"a", "b"}}
+	if got, want := multi.resourceDir("b"), filepath.Join("export", "resources", "b"); got != want {
+		t.Errorf("resourceDir = %q, want %q", got, want)
+	}
+	if got, want := multi.clusterResourceDir(), filepath.Join("export", "resources", "_cluster"); got != want {
+		t.Errorf("multi clusterResourceDir = %q, want %q", got, want)
+	}
+	if got, want := multi.clusterFailuresDir(), filepath.Join("export", "failures", "_cluster"); got != want {
+		t.Errorf("multi clusterFailuresDir = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,442 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/konveyor/crane/internal/archive"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// irregularResources maps kinds whose plural resource name is not guessed correctly
+// from the kind.
+var irregularResources = map[string]string{
+	"Endpoints":                  "endpoints",
+	"SecurityContextConstraints": "securitycontextconstraints",
+}
+
+// apiResourceHint is what a dump tells us about the API resource of a kind, when it
+// tells us anything: Velero backups name resources in their paths, and CRDs in a dump
+// give the plural and scope of their custom resources.
+type apiResourceHint struct {
+	resource   string
+	namespaced bool
+}
+
+// offline reports whether the export reads from a dump instead of a live cluster.
+func (o *ExportOptions) offline() bool {
+	return o.fromFile != "" || o.fromVeleroBackup != ""
+}
+
+func (o *ExportOptions) newOfflineSource(log logrus.FieldLogger) (*offlineSource, error) {
+	var (
+		objects []unstructured.Unstructured
+		hints   map[schema.GroupKind]apiResourceHint
+		name    string
+		err     error
+	)
+	if o.fromVeleroBackup != "" {
+		name = "velero-backup:" + o.fromVeleroBackup
+		objects, hints, err = readVeleroBackup(o.fromVeleroBackup, log)
+	} else {
+		name = "file:" + o.fromFile
+		objects, err = readObjectDump(o.fromFile)
+	}
+	if err != nil {
+		log.Errorf("Cannot read offline source: %v", err)
+		return nil, err
+	}
+	log.Infof("Loaded %d objects from %s", len(objects), name)
+	return newOfflineSource(name, objects, hints, log), nil
+}
+
+// readObjectDump reads every object in a multi-document YAML or JSON file, such as the
+// output of kubectl get -o yaml. List objects are flattened into their items.
+func readObjectDump(filePath string) ([]unstructured.Unstructured, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := decodeObjects(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: %w", filePath, err)
+	}
+	return objects, nil
+}
+
+func decodeObjects(data []byte) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if len(doc) == 0 {
+			continue
+		}
+		objects = appendFlattened(objects, unstructured.Unstructured{Object: doc})
+	}
+}
+
+// appendFlattened appends obj, or the items of obj when it is a List.
+func appendFlattened(objects []unstructured.Unstructured, obj unstructured.Unstructured) []unstructured.Unstructured {
+	if !obj.IsList() {
+		return append(objects, obj)
+	}
+	items, _, _ := unstructured.NestedSlice(obj.Object, "items")
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			objects = appendFlattened(objects, unstructured.Unstructured{Object: m})
+		}
+	}
+	return objects
+}
+
+// readVeleroBackup reads the objects in a Velero backup tarball. Objects are stored as
+// resources/<resource>.<group>/[<version>/](namespaces/<ns>|cluster)/<name>.json. When a
+// resource was backed up in several API versions, only the preferred version is read.
+func readVeleroBackup(backupPath string, log logrus.FieldLogger) ([]unstructured.Unstructured, map[schema.GroupKind]apiResourceHint, error) {
+	fsys, err := archive.Open(backupPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type entry struct {
+		file       string
+		resource   schema.GroupResource
+		versionDir string
+		namespaced bool
+	}
+	var entries []entry
+	preferred := map[schema.GroupResource]bool{}
+	err = fs.WalkDir(fsys, "resources", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".json" {
+			return nil
+		}
+		parts := strings.Split(name, "/")[1:]
+		e := entry{file: name, resource: schema.ParseGroupResource(parts[0])}
+		rest := parts[1:]
+		if len(rest) > 0 && rest[0] != "namespaces" && rest[0] != "cluster" {
+			e.versionDir, rest = rest[0], rest[1:]
+			if strings.HasSuffix(e.versionDir, "-preferredversion") {
+				preferred[e.resource] = true
+			}
+		}
+		switch {
+		case len(rest) == 3 && rest[0] == "namespaces":
+			e.namespaced = true
+		case len(rest) == 2 && rest[0] == "cluster":
+		default:
+			log.Debugf("Skipping unrecognized Velero backup entry %s", name)
+			return nil
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%q is not a Velero backup: no resources/ directory", backupPath)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("read Velero backup %q: %w", backupPath, err)
+	}
+
+	var objects []unstructured.Unstructured
+	hints := map[schema.GroupKind]apiResourceHint{}
+	for _, e := range entries {
+		if preferred[e.resource] && !strings.HasSuffix(e.versionDir, "-preferredversion") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, e.file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read Velero backup %q: %w", backupPath, err)
+		}
+		obj := unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return nil, nil, fmt.Errorf("parse %s in Velero backup %q: %w", e.file, backupPath, err)
+		}
+		objects = append(objects, obj)
+		hints[obj.GroupVersionKind().GroupKind()] = apiResourceHint{resource: e.resource.Resource, namespaced: e.namespaced}
+	}
+	return objects, hints, nil
+}
+
+// crdHints returns the plural and scope of every custom resource kind defined by a CRD
+// in objects.
+func crdHints(objects []unstructured.Unstructured) map[schema.GroupKind]apiResourceHint {
+	hints := map[schema.GroupKind]apiResourceHint{}
+	for _, obj := range objects {
+		if obj.GroupVersionKind().GroupKind() != (schema.GroupKind{Group: crdGVR.Group, Kind: "CustomResourceDefinition"}) {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+		plural, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "plural")
+		scope, _, _ := unstructured.NestedString(obj.Object, "spec", "scope")
+		if kind == "" || plural == "" {
+			continue
+		}
+		hints[schema.GroupKind{Group: group, Kind: kind}] = apiResourceHint{resource: plural, namespaced: scope != "Cluster"}
+	}
+	return hints
+}
+
+// offlineSource serves the objects of a dump through a read-only dynamic client, so the
+// export pipeline runs unchanged: listing honours label selectors, and the CRD and
+// cluster reference lookups find their objects in the dump instead of the cluster.
+type offlineSource struct {
+	name      string
+	resources map[schema.GroupVersionResource]metav1.APIResource
+	objects   map[schema.GroupVersionResource][]unstructured.Unstructured
+}
+
+func newOfflineSource(name string, objects []unstructured.Unstructured, hints map[schema.GroupKind]apiResourceHint, log logrus.FieldLogger) *offlineSource {
+	s := &offlineSource{
+		name:      name,
+		resources: map[schema.GroupVersionResource]metav1.APIResource{},
+		objects:   map[schema.GroupVersionResource][]unstructured.Unstructured{},
+	}
+	for gk, hint := range crdHints(objects) {
+		if _, ok := hints[gk]; !ok {
+			if hints == nil {
+				hints = map[schema.GroupKind]apiResourceHint{}
+			}
+			hints[gk] = hint
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		if gvk.Kind == "" || gvk.Version == "" || obj.GetName() == "" {
+			log.Warnf("Skipping object without apiVersion, kind, or name in %s", name)
+			continue
+		}
+		hint, ok := hints[gvk.GroupKind()]
+		if !ok {
+			hint = apiResourceHint{resource: guessResource(gvk), namespaced: obj.GetNamespace() != ""}
+		}
+		gvr := gvk.GroupVersion().WithResource(hint.resource)
+		key := strings.Join([]string{gvr.String(), obj.GetNamespace(), obj.GetName()}, "/")
+		if seen[key] {
+			log.Debugf("Skipping duplicate %s %s/%s in %s", gvk.Kind, obj.GetNamespace(), obj.GetName(), name)
+			continue
+		}
+		seen[key] = true
+
+		r, ok := s.resources[gvr]
+		if !ok {
+			r = metav1.APIResource{
+				Name:         gvr.Resource,
+				SingularName: strings.ToLower(gvk.Kind),
+				Kind:         gvk.Kind,
+				Verbs:        metav1.Verbs{"create", "delete", "get", "list"},
+			}
+		}
+		r.Namespaced = r.Namespaced || hint.namespaced
+		s.resources[gvr] = r
+		s.objects[gvr] = append(s.objects[gvr], obj)
+	}
+	return s
+}
+
+func guessResource(gvk schema.GroupVersionKind) string {
+	if r, ok := irregularResources[gvk.Kind]; ok {
+		return r
+	}
+	plural, _ := meta.UnsafeGuessKindToResource(gvk)
+	return plural.Resource
+}
+
+func (s *offlineSource) server() string                { return "" }
+func (s *offlineSource) description() string           { return s.name }
+func (s *offlineSource) requestTimeout() time.Duration { return 0 }
+func (s *offlineSource) client() dynamic.Interface     { return &offlineClient{source: s} }
+
+// resolveNamespaces works like resolveExportNamespaces against the Namespace objects in
+// the dump. Without any namespace flag it exports the kubeconfig context namespace, as a
+// live export does, so the same command line selects the same namespace either way.
+func (s *offlineSource) resolveNamespaces(o *ExportOptions, log logrus.FieldLogger) ([]string, error) {
+	switch {
+	case len(o.namespaces) > 0:
+		return o.namespaces, nil
+	case o.namespaceSelector != "":
+		selector, err := labels.Parse(o.namespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --namespace-selector: %w", err)
+		}
+		var names []string
+		for _, ns := range s.objects[schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}] {
+			if selector.Matches(labels.Set(ns.GetLabels())) {
+				names = append(names, ns.GetName())
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no namespaces in %s match --namespace-selector %q", s.name, o.namespaceSelector)
+		}
+		sort.Strings(names)
+		log.Debugf("Namespace selector %q matched %d namespace(s): %s", o.namespaceSelector, len(names), strings.Join(names, ", "))
+		return names, nil
+	}
+	return []string{o.userSpecifiedNamespace}, nil
+}
+
+// checkNamespace accepts a namespace that has a Namespace object or any object in the dump.
+func (s *offlineSource) checkNamespace(namespace string, log *logrus.Logger) error {
+	if namespace == "" {
+		return fmt.Errorf("namespace must be set (use -n/--namespace or --namespaces)")
+	}
+	for gvr, objects := range s.objects {
+		for _, obj := range objects {
+			if obj.GetNamespace() == namespace || (gvr.Group == "" && gvr.Resource == "namespaces" && obj.GetName() == namespace) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("namespace %q not found in %s", namespace, s.name)
+}
+
+// discover returns one APIResourceList per group version in the dump, sorted so that
+// offline exports are reproducible.
+func (s *offlineSource) discover(log logrus.FieldLogger) ([]*metav1.APIResourceList, error) {
+	byGroupVersion := map[string]*metav1.APIResourceList{}
+	for gvr, r := range s.resources {
+		gv := gvr.GroupVersion().String()
+		list, ok := byGroupVersion[gv]
+		if !ok {
+			list = &metav1.APIResourceList{GroupVersion: gv}
+			byGroupVersion[gv] = list
+		}
+		list.APIResources = append(list.APIResources, r)
+	}
+	lists := make([]*metav1.APIResourceList, 0, len(byGroupVersion))
+	for _, list := range byGroupVersion {
+		sort.Slice(list.APIResources, func(i, j int) bool { return list.APIResources[i].Name < list.APIResources[j].Name })
+		lists = append(lists, list)
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].GroupVersion < lists[j].GroupVersion })
+	log.Debugf("Found %d resource types in %s", len(s.resources), s.name)
+	return lists, nil
+}
+
+// servedResources is discover: a dump holds each object at the one version it was
+// written with.
+func (s *offlineSource) servedResources(log logrus.FieldLogger) ([]*metav1.APIResourceList, error) {
+	return s.discover(log)
+}
+
+var errOfflineReadOnly = errors.New("offline export source is read-only")
+
+// offlineClient is a read-only dynamic.Interface over an offlineSource.
+type offlineClient struct {
+	source *offlineSource
+}
+
+func (c *offlineClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &offlineResourceClient{source: c.source, gvr: gvr}
+}
+
+type offlineResourceClient struct {
+	source    *offlineSource
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (c *offlineResourceClient) Namespace(namespace string) dynamic.ResourceInterface {
+	return &offlineResourceClient{source: c.source, gvr: c.gvr, namespace: namespace}
+}
+
+// List returns copies of the objects in the namespace (all namespaces when unset) that
+// match opts.LabelSelector.
+func (c *offlineResourceClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	selector := labels.Everything()
+	if opts.LabelSelector != "" {
+		var err error
+		if selector, err = labels.Parse(opts.LabelSelector); err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	}
+	list := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{}}
+	for _, obj := range c.source.objects[c.gvr] {
+		if c.namespace != "" && obj.GetNamespace() != c.namespace {
+			continue
+		}
+		if !selector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		list.Items = append(list.Items, *obj.DeepCopy())
+	}
+	return list, nil
+}
+
+func (c *offlineResourceClient) Get(ctx context.Context, name string, opts metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if len(subresources) == 0 {
+		for _, obj := range c.source.objects[c.gvr] {
+			if obj.GetName() == name && obj.GetNamespace() == c.namespace {
+				return obj.DeepCopy(), nil
+			}
+		}
+	}
+	return nil, apierrors.NewNotFound(c.gvr.GroupResource(), name)
+}
+
+func (c *offlineResourceClient) Create(context.Context, *unstructured.Unstructured, metav1.CreateOptions, ...string) (*unstructured.Unstructured, error) {
+	return nil, errOfflineReadOnly
+}
+
+func (c *offlineResourceClient) Update(context.Context, *unstructured.Unstructured, metav1.UpdateOptions, ...string) (*unstructured.Unstructured, error) {
+	return nil, errOfflineReadOnly
+}
+
+func (c *offlineResourceClient) UpdateStatus(context.Context, *unstructured.Unstructured, metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return nil, errOfflineReadOnly
+}
+
+func (c *offlineResourceClient) Delete(context.Context, string, metav1.DeleteOptions, ...string) error {
+	return errOfflineReadOnly
+}
+
+func (c *offlineResourceClient) DeleteCollection(context.Context, metav1.DeleteOptions, metav1.ListOptions) error {
+	return errOfflineReadOnly
+}
+
+func (c *offlineResourceClient) Watch(context.Context, metav1.ListOptions) (watch.Interface, error) {
+	return nil, errOfflineReadOnly
+}
+
+func (c *offlineResourceClient) Patch(context.Context, string, types.PatchType, []byte, metav1.PatchOptions, ...string) (*unstructured.Unstructured, error) {
+	return nil, errOfflineReadOnly
+}
+
+func (c *offlineResourceClient) Apply(context.Context, string, *unstructured.Unstructured, metav1.ApplyOptions, ...string) (*unstructured.Unstructured, error) {
+	return nil, errOfflineReadOnly
+}
+
+func (c *offlineResourceClient) ApplyStatus(context.Context, string, *unstructured.Unstructured, metav1.ApplyOptions) (*unstructured.Unstructured, error) {
+	return nil, errOfflineReadOnly
+}
@@ -0,0 +1,297 @@
+package export
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/crane/internal/file"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const offlineDump = `apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+    namespace: app
+    labels:
+      tier: frontend
+  spec:
+    template:
+      spec:
+        priorityClassName: high
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: deployer
+    namespace: app
+---
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: web
+  namespace: app
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+  namespace: other
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: app
+  labels:
+    team: a
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: deployer
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: deployer
+subjects:
+- kind: ServiceAccount
+  name: deployer
+  namespace: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: deployer
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: unrelated
+---
+apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: high
+value: 1000
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+  namespace: app
+---
+{"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition", "metadata": {"name": "gadgets.example.com"},
+ "spec": {"group": "example.com", "scope": "Namespaced", "names": {"kind": "Widget", "plural": "gadgets"}}}
+`
+
+func writeOfflineDump(t *testing.T) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "dump.yaml")
+	if err := os.WriteFile(p, []byte(offlineDump), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestOfflineSource_FromFile(t *testing.T) {
+	objects, err := readObjectDump(writeOfflineDump(t))
+	if err != nil {
+		t.Fatalf("readObjectDump: %v", err)
+	}
+	if len(objects) != 11 {
+		t.Fatalf("got %d objects, want 11 (List flattened)", len(objects))
+	}
+	s := newOfflineSource("file:dump.yaml", objects, nil, testLogger())
+
+	for _, tt := range []struct {
+		gvr        schema.GroupVersionResource
+		namespaced bool
+	}{
+		{schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, true},
+		{schema.GroupVersionResource{Version: "v1", Resource: "endpoints"}, true},
+		{schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, false},
+		// The plural comes from the CRD in the dump, not from the kind.
+		{schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"}, true},
+	} {
+		r, ok := s.resources[tt.gvr]
+		if !ok {
+			t.Errorf("missing resource %s", tt.gvr)
+			continue
+		}
+		if r.Namespaced != tt.namespaced {
+			t.Errorf("%s namespaced = %v, want %v", tt.gvr, r.Namespaced, tt.namespaced)
+		}
+	}
+
+	namespaces, err := s.resolveNamespaces(&ExportOptions{userSpecifiedNamespace: "other"}, testLogger())
+	if err != nil || strings.Join(namespaces, ",") != "other" {
+		t.Errorf("resolveNamespaces = %v, %v; want the context namespace, as a live export", namespaces, err)
+	}
+	namespaces, err = s.resolveNamespaces(&ExportOptions{namespaceSelector: "team=a"}, testLogger())
+	if err != nil || strings.Join(namespaces, ",") != "app" {
+		t.Errorf("resolveNamespaces(selector) = %v, %v", namespaces, err)
+	}
+	if err := s.checkNamespace("missing", nil); err == nil {
+		t.Error("expected error for namespace not in the dump")
+	}
+
+	client := s.client()
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	list, err := client.Resource(deployments).Namespace("app").List(context.Background(), metav1.ListOptions{LabelSelector: "tier=backend"})
+	if err != nil || len(list.Items) != 0 {
+		t.Errorf("List with non-matching selector = %v, %v", list, err)
+	}
+	_, err = client.Resource(crdGVR).Get(context.Background(), "missing.example.com", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+func writeVeleroBackup(t *testing.T, files map[string]string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "backup.tar.gz")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0600, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReadVeleroBackup(t *testing.T) {
+	backup := writeVeleroBackup(t, map[string]string{
+		"metadata/version": "1",
+		"resources/deployments.apps/v1-preferredversion/namespaces/app/web.json": `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"app"}}`,
+		"resources/deployments.apps/v1beta2/namespaces/app/web.json":             `{"apiVersion":"apps/v1beta2","kind":"Deployment","metadata":{"name":"web","namespace":"app"}}`,
+		"resources/widgets.example.com/namespaces/app/w.json":                    `{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"w","namespace":"app"}}`,
+		"resources/clusterroles.rbac.authorization.k8s.io/cluster/r.json":        `{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"r"}}`,
+	})
+	objects, hints, err := readVeleroBackup(backup, testLogger())
+	if err != nil {
+		t.Fatalf("readVeleroBackup: %v", err)
+	}
+	if len(objects) != 3 {
+		t.Fatalf("got %d objects, want 3 (only the preferred Deployment version)", len(objects))
+	}
+	for _, o := range objects {
+		if o.GetKind() == "Deployment" && o.GetAPIVersion() != "apps/v1" {
+			t.Errorf("read non-preferred Deployment version %s", o.GetAPIVersion())
+		}
+	}
+	if h := hints[schema.GroupKind{Group: "example.com", Kind: "Widget"}]; h.resource != "widgets" || !h.namespaced {
+		t.Errorf("Widget hint = %+v", h)
+	}
+	if h := hints[schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}]; h.namespaced {
+		t.Errorf("ClusterRole hint = %+v", h)
+	}
+
+	notBackup := writeVeleroBackup(t, map[string]string{"other/file.json": "{}"})
+	if _, _, err := readVeleroBackup(notBackup, testLogger()); err == nil || !strings.Contains(err.Error(), "not a Velero backup") {
+		t.Errorf("expected not a Velero backup error, got %v", err)
+	}
+}
+
+func TestRun_FromFile(t *testing.T) {
+	filter, err := newResourceFilter(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportDir := filepath.Join(t.TempDir(), "export")
+	dump := writeOfflineDump(t)
+	o := &ExportOptions{
+		exportDir:               exportDir,
+		fromFile:                dump,
+		namespaces:              []string{"app"},
+		resourceFilter:          filter,
+		followClusterReferences: true,
+	}
+	if err := o.run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	for _, want := range []string{
+		"resources/app/Deployment_apps_v1_app_web.yaml",
+		"resources/app/Endpoints__v1_app_web.yaml",
+		"resources/app/Widget_example.com_v1_app_w.yaml",
+		"resources/app/_cluster/ClusterRoleBinding_rbac.authorization.k8s.io_v1_clusterscoped_deployer.yaml",
+		"resources/app/_cluster/ClusterRole_rbac.authorization.k8s.io_v1_clusterscoped_deployer.yaml",
+		"resources/app/_cluster/CustomResourceDefinition_apiextensions.k8s.io_v1_clusterscoped_gadgets.example.com.yaml",
+		"resources/app/_cluster/PriorityClass_scheduling.k8s.io_v1_clusterscoped_high.yaml",
+	} {
+		if _, err := os.Stat(filepath.Join(exportDir, want)); err != nil {
+			t.Errorf("missing %s: %v", want, err)
+		}
+	}
+	for _, unwanted := range []string{
+		"resources/app/_cluster/ClusterRole_rbac.authorization.k8s.io_v1_clusterscoped_unrelated.yaml",
+		"resources/other",
+	} {
+		if _, err := os.Stat(filepath.Join(exportDir, unwanted)); err == nil {
+			t.Errorf("unexpected %s", unwanted)
+		}
+	}
+
+	manifest, err := file.ReadExportManifest(exportDir)
+	if err != nil || manifest == nil {
+		t.Fatalf("ReadExportManifest: %v, %v", manifest, err)
+	}
+	if manifest.Source != "file:"+dump || manifest.Server != "" || manifest.Context != "" {
+		t.Errorf("manifest provenance = source %q, server %q, context %q", manifest.Source, manifest.Server, manifest.Context)
+	}
+}
+
+// TestRun_FromFile_DefaultDoesNotFollowReferences checks that, without
+// --follow-cluster-references, referenced cluster-scoped objects are not exported.
+func TestRun_FromFile_DefaultDoesNotFollowReferences(t *testing.T) {
+	filter, err := newResourceFilter(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportDir := filepath.Join(t.TempDir(), "export")
+	o := &ExportOptions{
+		exportDir:      exportDir,
+		fromFile:       writeOfflineDump(t),
+		namespaces:     []string{"app"},
+		resourceFilter: filter,
+	}
+	if err := o.run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(exportDir, "resources/app/Deployment_apps_v1_app_web.yaml")); err != nil {
+		t.Errorf("missing Deployment: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(exportDir, "resources/app/_cluster/PriorityClass_scheduling.k8s.io_v1_clusterscoped_high.yaml")); err == nil {
+		t.Error("exported the referenced PriorityClass without --follow-cluster-references")
+	}
+	filepath.WalkDir(filepath.Join(exportDir, file.FailuresDirName), func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			t.Errorf("unexpected failure file %s", path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,166 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultKeepOwnedKinds are owned kinds that --skip-owned keeps unless --keep-owned-kinds
+// is set. PersistentVolumeClaims carry data and are needed for PVC transfer even when a
+// StatefulSet owns them.
+var defaultKeepOwnedKinds = []string{"PersistentVolumeClaim"}
+
+// prunedOwner identifies the exported owner that caused an object to be pruned.
+type prunedOwner struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid"`
+}
+
+// prunedObject is one entry in the pruned/ report.
+type prunedObject struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Namespace  string      `json:"namespace,omitempty"`
+	Name       string      `json:"name"`
+	Owner      prunedOwner `json:"owner"`
+}
+
+// ownedKindAllowlist matches objects by "Kind" or "Kind.group", case-insensitively.
+type ownedKindAllowlist map[string]struct{}
+
+func newOwnedKindAllowlist(kinds []string) ownedKindAllowlist {
+	allow := ownedKindAllowlist{}
+	for _, k := range kinds {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			allow[k] = struct{}{}
+		}
+	}
+	return allow
+}
+
+func (a ownedKindAllowlist) keeps(obj unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	kind := strings.ToLower(gvk.Kind)
+	if _, ok := a[kind]; ok {
+		return true
+	}
+	if gvk.Group == "" {
+		return false
+	}
+	_, ok := a[kind+"."+strings.ToLower(gvk.Group)]
+	return ok
+}
+
+// pruneOwnedObjects drops every object with an ownerReference to another object being
+// exported, unless its kind is allowlisted. Owners are resolved by UID against the objects
+// collected before pruning, so whole ownership chains (Deployment → ReplicaSet → Pod) are
+// reduced to their root. The groupResources in resources are updated in place; pruned
+// objects are returned sorted by namespace, kind, and name.
+func pruneOwnedObjects(resources []*groupResource, keep ownedKindAllowlist, log logrus.FieldLogger) []prunedObject {
+	exported := map[types.UID]struct{}{}
+	for _, g := range resources {
+		if g == nil || g.objects == nil {
+			continue
+		}
+		for _, obj := range g.objects.Items {
+			if uid := obj.GetUID(); uid != "" {
+				exported[uid] = struct{}{}
+			}
+		}
+	}
+
+	pruned := []prunedObject{}
+	for _, g := range resources {
+		if g == nil || g.objects == nil {
+			continue
+		}
+		kept := make([]unstructured.Unstructured, 0, len(g.objects.Items))
+		for _, obj := range g.objects.Items {
+			owner, ok := exportedOwner(obj, exported)
+			if !ok || keep.keeps(obj) {
+				kept = append(kept, obj)
+				continue
+			}
+			log.Debugf("Pruning %s %s/%s owned by exported %s %s", obj.GetKind(), obj.GetNamespace(), obj.GetName(), owner.Kind, owner.Name)
+			pruned = append(pruned, prunedObject{
+				APIVersion: obj.GetAPIVersion(),
+				Kind:       obj.GetKind(),
+				Namespace:  obj.GetNamespace(),
+				Name:       obj.GetName(),
+				Owner:      owner,
+			})
+		}
+		g.objects.Items = kept
+	}
+
+	sort.Slice(pruned, func(i, j int) bool {
+		a, b := pruned[i], pruned[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return pruned
+}
+
+// exportedOwner returns the first ownerReference of obj that points at an exported object,
+// preferring the controller reference.
+func exportedOwner(obj unstructured.Unstructured, exported map[types.UID]struct{}) (prunedOwner, bool) {
+	refs := obj.GetOwnerReferences()
+	sort.SliceStable(refs, func(i, j int) bool {
+		return refs[i].Controller != nil && *refs[i].Controller && (refs[j].Controller == nil || !*refs[j].Controller)
+	})
+	for _, ref := range refs {
+		if _, ok := exported[ref.UID]; ok {
+			return prunedOwner{APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, UID: ref.UID}, true
+		}
+	}
+	return prunedOwner{}, false
+}
+
+// writePrunedReport writes pruned objects to prunedDir, one <namespace>.yaml per namespace
+// and _cluster.yaml for cluster-scoped objects. Any previous report is replaced.
+func writePrunedReport(prunedDir string, pruned []prunedObject) error {
+	if err := os.RemoveAll(prunedDir); err != nil {
+		return fmt.Errorf("clear pruned report directory %q: %w", prunedDir, err)
+	}
+	if len(pruned) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(prunedDir, 0700); err != nil {
+		return fmt.Errorf("create pruned report directory %q: %w", prunedDir, err)
+	}
+
+	byNamespace := map[string][]prunedObject{}
+	for _, p := range pruned {
+		ns := p.Namespace
+		if ns == "" {
+			ns = clusterScopeDirName
+		}
+		byNamespace[ns] = append(byNamespace[ns], p)
+	}
+	for ns, objs := range byNamespace {
+		data, err := yaml.Marshal(objs)
+		if err != nil {
+			return fmt.Errorf("marshal pruned report for %q: %w", ns, err)
+		}
+		path := filepath.Join(prunedDir, ns+".yaml")
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return fmt.Errorf("write pruned report %q: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func ownerRef(kind, name, uid string, controller bool) metav1.OwnerReference {
+	return metav1.OwnerReference{APIVersion: "apps/v1", Kind: kind, Name: name, UID: types.UID(uid), Controller: &controller}
+}
+
+func TestPruneOwnedObjects(t *testing.T) {
+	deployments := testGroupResource("deployments", "Deployment", true,
+		namespacedObj("app", "web", withKind("apps/v1", "Deployment"), withUID("d1")),
+	)
+	replicaSets := testGroupResource("replicasets", "ReplicaSet", true,
+		namespacedObj("app", "web-abc", withKind("apps/v1", "ReplicaSet"), withUID("rs1"), withOwners(ownerRef("Deployment", "web", "d1", true))),
+	)
+	pods := testGroupResource("pods", "Pod", true,
+		namespacedObj("app", "web-abc-1", withKind("v1", "Pod"), withUID("p1"), withOwners(ownerRef("ReplicaSet", "web-abc", "rs1", true))),
+		// Owner is not part of the export, so the pod is kept.
+		namespacedObj("app", "orphan", withKind("v1", "Pod"), withUID("p2"), withOwners(ownerRef("ReplicaSet", "gone", "rs-missing", true))),
+		namespacedObj("app", "standalone", withKind("v1", "Pod"), withUID("p3")),
+	)
+	pvcs := testGroupResource("persistentvolumeclaims", "PersistentVolumeClaim", true,
+		namespacedObj("app", "data-db-0", withKind("v1", "PersistentVolumeClaim"), withUID("pvc1"), withOwners(ownerRef("Deployment", "web", "d1", true))),
+	)
+	resources := []*groupResource{deployments, replicaSets, pods, pvcs}
+
+	pruned := pruneOwnedObjects(resources, newOwnedKindAllowlist(defaultKeepOwnedKinds), testLogger())
+
+	if len(pruned) != 2 {
+		t.Fatalf("expected ReplicaSet and Pod to be pruned, got %+v", pruned)
+	}
+	if pruned[0].Kind != "Pod" || pruned[0].Name != "web-abc-1" || pruned[0].Owner.Kind != "ReplicaSet" {
+		t.Errorf("unexpected first pruned entry: %+v", pruned[0])
+	}
+	if pruned[1].Kind != "ReplicaSet" || pruned[1].Owner.UID != "d1" {
+		t.Errorf("unexpected second pruned entry: %+v", pruned[1])
+	}
+	if len(deployments.objects.Items) != 1 || len(replicaSets.objects.Items) != 0 {
+		t.Errorf("deployments=%d replicasets=%d, want 1 and 0", len(deployments.objects.Items), len(replicaSets.objects.Items))
+	}
+	if len(pods.objects.Items) != 2 {
+		t.Errorf("expected orphan and standalone pods to be kept, got %d", len(pods.objects.Items))
+	}
+	if len(pvcs.objects.Items) != 1 {
+		t.Error("allowlisted PersistentVolumeClaim should be kept")
+	}
+}
+
+func TestOwnedKindAllowlist(t *testing.T) {
+	allow := newOwnedKindAllowlist([]string{" secret ", "Job.batch"})
+	tests := []struct {
+		apiVersion, kind string
+		want             bool
+	}{
+		{"v1", "Secret", true},
+		{"batch/v1", "Job", true},
+		{"example.com/v1", "Job", false},
+		{"v1", "Pod", false},
+	}
+	for _, tt := range tests {
+		obj := namespacedObj("app", "x", withKind(tt.apiVersion, tt.kind), withUID("u"))
+		if got := allow.keeps(obj); got != tt.want {
+			t.Errorf("keeps(%s %s) = %v, want %v", tt.apiVersion, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestWritePrunedReport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pruned")
+	pruned := []prunedObject{
+		{APIVersion: "v1", Kind: "Pod", Namespace: "app", Name: "web-1", Owner: prunedOwner{Kind: "ReplicaSet", Name: "web", UID: "rs1"}},
+		{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "agg", Owner: prunedOwner{Kind: "ClusterRole", Name: "parent", UID: "c1"}},
+	}
+	if err := writePrunedReport(dir, pruned); err != nil {
+		t.Fatalf("writePrunedReport: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "app.yaml"))
+	if err != nil {
+		t.Fatalf("read namespace report: %v", err)
+	}
+	if !strings.Contains(string(data), "name: web-1") || !strings.Contains(string(data), "kind: ReplicaSet") {
+		t.Errorf("unexpected report content:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, clusterScopeDirName+".yaml")); err != nil {
+		t.Errorf("expected cluster-scoped report: %v", err)
+	}
+
+	if err := writePrunedReport(dir, nil); err != nil {
+		t.Fatalf("writePrunedReport(nil): %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected stale report directory to be removed, stat err = %v", err)
+	}
+}
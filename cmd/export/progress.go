@@ -0,0 +1,211 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/konveyor/crane/internal/cli"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Lifecycle events written with --events-json.
+const (
+	eventDiscoveryDone = "discovery-done"
+	eventGVRListed     = "gvr-listed"
+	eventGVRFailed     = "gvr-failed"
+	eventFileWritten   = "file-written"
+	eventCRDCollected  = "crd-collected"
+)
+
+// exportPhases is the number of phases shown with --progress.
+const exportPhases = 4
+
+// exportProgress reports how far an export run has got: phase and progress lines on
+// standard error with --progress, and lifecycle events with --events-json. Either may be
+// off; a nil exportProgress reports nothing. Listing runs concurrently, so the counters
+// are guarded by mu.
+type exportProgress struct {
+	exportDir string
+	phases    *cli.PhaseTracker
+	events    *cli.EventWriter
+
+	mu           sync.Mutex
+	gvrsTotal    int
+	gvrsDone     int
+	objects      int
+	filesTotal   int
+	filesWritten int
+}
+
+// newExportProgress returns the progress reporter selected by --progress and
+// --events-json, and a function that closes the event stream. --events-json - writes the
+// events to standard output.
+func (o *ExportOptions) newExportProgress() (*exportProgress, func() error, error) {
+	closeEvents := func() error { return nil }
+	if !o.progress && o.eventsJSON == "" {
+		return nil, closeEvents, nil
+	}
+	p := &exportProgress{exportDir: o.exportDir}
+	if o.progress && o.ErrOut != nil {
+		p.phases = cli.NewPhaseTracker(o.ErrOut, exportPhases)
+	}
+	switch o.eventsJSON {
+	case "":
+	case "-":
+		p.events = cli.NewEventWriter(o.Out)
+	default:
+		f, err := os.Create(o.eventsJSON)
+		if err != nil {
+			return nil, closeEvents, fmt.Errorf("create --events-json file: %w", err)
+		}
+		p.events = cli.NewEventWriter(f)
+		closeEvents = f.Close
+	}
+	return p, closeEvents, nil
+}
+
+func (p *exportProgress) start(name string) {
+	if p != nil && p.phases != nil {
+		p.phases.Start(name)
+	}
+}
+
+func (p *exportProgress) end(detail string) {
+	if p != nil && p.phases != nil {
+		p.phases.End("ok", detail)
+	}
+}
+
+// discoveryDone ends the discovery phase; total is the number of resource types that
+// will be listed, counting namespaced types once per namespace.
+func (p *exportProgress) discoveryDone(lists []*metav1.APIResourceList, total int) {
+	if p == nil {
+		return
+	}
+	p.gvrsTotal = total
+	p.events.Emit(eventDiscoveryDone, map[string]interface{}{"groupVersions": len(lists), "resourceTypes": total})
+	p.end(fmt.Sprintf("%d resource types to list", total))
+}
+
+// observeList wraps list to report each resource type listed in namespace.
+func (p *exportProgress) observeList(namespace string, list listFunc) listFunc {
+	if p == nil {
+		return list
+	}
+	return func(g *groupResource) (*unstructured.UnstructuredList, error) {
+		objs, err := list(g)
+		fields := map[string]interface{}{
+			"namespace": namespace,
+			"group":     g.APIGroup,
+			"version":   g.APIVersion,
+			"resource":  g.APIResource.Name,
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.gvrsDone++
+		if err != nil {
+			fields["error"] = err.Error()
+			p.events.Emit(eventGVRFailed, fields)
+		} else {
+			fields["objects"] = len(objs.Items)
+			p.objects += len(objs.Items)
+			p.events.Emit(eventGVRListed, fields)
+		}
+		if p.phases != nil {
+			current := groupResourceName(g.APIGroup, g.APIResource.Name)
+			if namespace != "" {
+				current += " in " + namespace
+			}
+			p.phases.Progress(p.gvrsDone, p.gvrsTotal, fmt.Sprintf("%d objects  %s", p.objects, current))
+		}
+		return objs, err
+	}
+}
+
+// listingDone ends the listing phase.
+func (p *exportProgress) listingDone() {
+	if p != nil {
+		p.end(fmt.Sprintf("%d objects", p.objects))
+	}
+}
+
+// crdsCollected reports the CustomResourceDefinitions collected for exported custom
+// resources and ends the phase that collects them.
+func (p *exportProgress) crdsCollected(crds []*groupResource, referenced []*groupResource) {
+	if p == nil {
+		return
+	}
+	count := 0
+	for _, g := range crds {
+		for _, obj := range g.objects.Items {
+			p.events.Emit(eventCRDCollected, map[string]interface{}{"name": obj.GetName()})
+			count++
+		}
+	}
+	for _, g := range referenced {
+		count += len(g.objects.Items)
+	}
+	p.end(fmt.Sprintf("%d objects", count))
+}
+
+// startWriting starts the phase that writes the manifests of resources.
+func (p *exportProgress) startWriting(resources ...[]*groupResource) {
+	if p == nil {
+		return
+	}
+	p.filesTotal, p.filesWritten = 0, 0
+	for _, rs := range resources {
+		for _, g := range rs {
+			if !g.streamed {
+				p.filesTotal += len(g.objects.Items)
+			}
+		}
+	}
+	p.start("Writing manifests")
+}
+
+// fileWritten reports a manifest written to path, while listing with --stream or in the
+// writing phase.
+func (p *exportProgress) fileWritten(path string, obj unstructured.Unstructured) {
+	if p == nil {
+		return
+	}
+	if rel, err := filepath.Rel(p.exportDir, path); err == nil {
+		path = rel
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events.Emit(eventFileWritten, map[string]interface{}{
+		"path":      filepath.ToSlash(path),
+		"kind":      obj.GetKind(),
+		"namespace": obj.GetNamespace(),
+		"name":      obj.GetName(),
+	})
+	p.filesWritten++
+	if p.phases != nil && p.filesTotal > 0 {
+		p.phases.Progress(p.filesWritten, p.filesTotal, "files")
+	}
+}
+
+// writingDone ends the writing phase and reports a failure to write the event stream.
+func (p *exportProgress) writingDone() error {
+	if p == nil {
+		return nil
+	}
+	p.end(fmt.Sprintf("%d files", p.filesWritten))
+	return p.events.Err()
+}
+
+// listableResourceCount returns the number of resource types in lists that an export
+// lists, the candidates of admittedGroupResources.
+func listableResourceCount(lists []*metav1.APIResourceList, filter resourceFilter) int {
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	candidates, _ := admittedGroupResources(lists, filter, quiet)
+	return len(candidates)
+}
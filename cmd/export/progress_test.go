@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestRun_ProgressAndEvents(t *testing.T) {
+	filter, err := newResourceFilter(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventsFile := filepath.Join(t.TempDir(), "events.json")
+	var errOut bytes.Buffer
+	o := &ExportOptions{
+		exportDir:      filepath.Join(t.TempDir(), "export"),
+		fromFile:       writeOfflineDump(t),
+		namespaces:     []string{"app"},
+		resourceFilter: filter,
+		progress:       true,
+		eventsJSON:     eventsFile,
+		IOStreams:      genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &errOut},
+	}
+	if err := o.run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	for _, want := range []string{
+		"[1/4] Discovering API resources ...",
+		"[2/4] Listing resources ... ok",
+		"[3/4] Collecting CRDs and referenced cluster-scoped objects ... ok",
+		"[4/4] Writing manifests ... ok",
+	} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("progress output is missing %q:\n%s", want, errOut.String())
+		}
+	}
+
+	f, err := os.Open(eventsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	counts := map[string]int{}
+	written := map[string]bool{}
+	crds := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("event %q: %v", scanner.Text(), err)
+		}
+		name, _ := event["event"].(string)
+		counts[name]++
+		switch name {
+		case eventFileWritten:
+			path, _ := event["path"].(string)
+			written[path] = true
+		case eventCRDCollected:
+			crd, _ := event["name"].(string)
+			crds[crd] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if counts[eventDiscoveryDone] != 1 || counts[eventGVRListed] == 0 {
+		t.Errorf("event counts = %v, want one %s and some %s", counts, eventDiscoveryDone, eventGVRListed)
+	}
+	if !written["resources/app/Deployment_apps_v1_app_web.yaml"] {
+		t.Errorf("no %s event for the Deployment, got %v", eventFileWritten, written)
+	}
+	if !crds["gadgets.example.com"] {
+		t.Errorf("no %s event for gadgets.example.com, got %v", eventCRDCollected, crds)
+	}
+}
@@ -0,0 +1,207 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// referencedKind is a cluster-scoped kind that namespaced objects refer to by name.
+type referencedKind struct {
+	gvr      schema.GroupVersionResource
+	kind     string
+	singular string
+}
+
+var (
+	priorityClassKind    = referencedKind{gvr: schema.GroupVersionResource{Group: "scheduling.k8s.io", Version: "v1", Resource: "priorityclasses"}, kind: "PriorityClass", singular: "priorityclass"}
+	ingressClassKind     = referencedKind{gvr: schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingressclasses"}, kind: "IngressClass", singular: "ingressclass"}
+	runtimeClassKind     = referencedKind{gvr: schema.GroupVersionResource{Group: "node.k8s.io", Version: "v1", Resource: "runtimeclasses"}, kind: "RuntimeClass", singular: "runtimeclass"}
+	storageClassKind     = referencedKind{gvr: schema.GroupVersionResource{Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"}, kind: "StorageClass", singular: "storageclass"}
+	persistentVolumeKind = referencedKind{gvr: schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumes"}, kind: "PersistentVolume", singular: "persistentvolume"}
+)
+
+// referencedKinds lists the kinds followed by collectReferencedResources, in the order
+// they are written.
+var referencedKinds = []referencedKind{priorityClassKind, ingressClassKind, runtimeClassKind, storageClassKind, persistentVolumeKind}
+
+// podSpecPaths maps workload kinds to the location of their pod spec.
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"DeploymentConfig":      {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// clusterReference is a reference from an exported object to a cluster-scoped object.
+type clusterReference struct {
+	kind referencedKind
+	name string
+	// from names the referencing object and field, e.g.
+	// "Deployment app/web field spec.template.spec.priorityClassName".
+	from string
+}
+
+// referenceFailureAPIResourceName returns a unique writeErrors filename stem for a failed
+// GET of a referenced object.
+func referenceFailureAPIResourceName(kind referencedKind, name string) string {
+	return kind.singular + "-" + strings.ReplaceAll(name, "/", "-")
+}
+
+// clusterReferencesOf returns the cluster-scoped objects obj refers to by name.
+func clusterReferencesOf(obj unstructured.Unstructured) []clusterReference {
+	var refs []clusterReference
+	source := obj.GetKind() + " " + obj.GetName()
+	if obj.GetNamespace() != "" {
+		source = obj.GetKind() + " " + obj.GetNamespace() + "/" + obj.GetName()
+	}
+	add := func(kind referencedKind, fields ...string) {
+		name, _, _ := unstructured.NestedString(obj.Object, fields...)
+		if name == "" {
+			return
+		}
+		refs = append(refs, clusterReference{kind: kind, name: name, from: source + " field " + strings.Join(fields, ".")})
+	}
+
+	if path, ok := podSpecPaths[obj.GetKind()]; ok {
+		add(priorityClassKind, append(append([]string{}, path...), "priorityClassName")...)
+		add(runtimeClassKind, append(append([]string{}, path...), "runtimeClassName")...)
+	}
+
+	switch obj.GetKind() {
+	case "StatefulSet":
+		templates, _, _ := unstructured.NestedSlice(obj.Object, "spec", "volumeClaimTemplates")
+		for i, t := range templates {
+			m, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, _, _ := unstructured.NestedString(m, "spec", "storageClassName"); name != "" {
+				refs = append(refs, clusterReference{kind: storageClassKind, name: name, from: fmt.Sprintf("%s field spec.volumeClaimTemplates[%d].spec.storageClassName", source, i)})
+			}
+		}
+	case "PersistentVolumeClaim":
+		add(storageClassKind, "spec", "storageClassName")
+		add(persistentVolumeKind, "spec", "volumeName")
+	case "PersistentVolume":
+		add(storageClassKind, "spec", "storageClassName")
+	case "Ingress":
+		add(ingressClassKind, "spec", "ingressClassName")
+	}
+	return refs
+}
+
+// collectReferencedResources follows references from the exported objects in resources to
+// PriorityClasses, IngressClasses, RuntimeClasses, StorageClasses, and the PersistentVolumes
+// bound to PersistentVolumeClaims, and returns the referenced objects as cluster-scoped
+// groupResource rows. PersistentVolumes are followed in turn to their StorageClass. Kinds
+// rejected by filter are not followed. Dangling references are logged; other failed GETs are
+// returned as groupResourceError entries.
+func (c *ClusterScopeHandler) collectReferencedResources(requestTimeout time.Duration, resources []*groupResource, dynamicClient dynamic.Interface, filter resourceFilter, log logrus.FieldLogger) ([]*groupResource, []*groupResourceError) {
+	var queue []clusterReference
+	for _, g := range resources {
+		if g == nil || g.objects == nil {
+			continue
+		}
+		if g.streamed {
+			queue = append(queue, g.refs...)
+			continue
+		}
+		for _, obj := range g.objects.Items {
+			queue = append(queue, clusterReferencesOf(obj)...)
+		}
+	}
+
+	collected := map[schema.GroupVersionResource][]unstructured.Unstructured{}
+	seen := map[string]struct{}{}
+	var outErrs []*groupResourceError
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+
+		key := ref.kind.gvr.String() + "/" + ref.name
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		gv := ref.kind.gvr.GroupVersion()
+		if reason, _ := filter.skipReason(gv, metav1.APIResource{Name: ref.kind.gvr.Resource}); reason != "" {
+			log.Debugf("Not following %s %q referenced by %s: %s", ref.kind.kind, ref.name, ref.from, reason)
+			continue
+		}
+
+		ctx := context.Background()
+		var cancel context.CancelFunc
+		if requestTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		}
+		obj, err := dynamicClient.Resource(ref.kind.gvr).Get(ctx, ref.name, metav1.GetOptions{})
+		if cancel != nil {
+			cancel()
+		}
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				log.Warnf("%s %q referenced by %s does not exist, not exporting it", ref.kind.kind, ref.name, ref.from)
+				continue
+			}
+			if apierrors.IsForbidden(err) {
+				log.Warnf("Cannot get %s %q referenced by %s (forbidden); ensure get on %s", ref.kind.kind, ref.name, ref.from, groupResourceName(gv.Group, ref.kind.gvr.Resource))
+			} else {
+				log.Warnf("Error getting %s %q referenced by %s: %v", ref.kind.kind, ref.name, ref.from, err)
+			}
+			outErrs = append(outErrs, &groupResourceError{
+				APIResource: metav1.APIResource{
+					Name:         referenceFailureAPIResourceName(ref.kind, ref.name),
+					SingularName: ref.kind.singular,
+					Namespaced:   false,
+					Kind:         ref.kind.kind,
+					Verbs:        metav1.Verbs{"get"},
+				},
+				Error: err,
+			})
+			continue
+		}
+
+		log.Infof("Adding %s %q to _cluster/ (referenced by %s)", ref.kind.kind, ref.name, ref.from)
+		collected[ref.kind.gvr] = append(collected[ref.kind.gvr], *obj)
+		queue = append(queue, clusterReferencesOf(*obj)...)
+	}
+
+	var out []*groupResource
+	for _, k := range referencedKinds {
+		objs := collected[k.gvr]
+		if len(objs) == 0 {
+			continue
+		}
+		sort.Slice(objs, func(i, j int) bool { return objs[i].GetName() < objs[j].GetName() })
+		out = append(out, &groupResource{
+			APIGroup:        k.gvr.Group,
+			APIVersion:      k.gvr.Version,
+			APIGroupVersion: k.gvr.GroupVersion().String(),
+			APIResource: metav1.APIResource{
+				Name:         k.gvr.Resource,
+				SingularName: k.singular,
+				Namespaced:   false,
+				Kind:         k.kind,
+				Verbs:        metav1.Verbs{"get"},
+			},
+			objects: &unstructured.UnstructuredList{Items: objs},
+		})
+	}
+	return out, outErrs
+}
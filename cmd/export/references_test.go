@@ -0,0 +1,189 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+func TestClusterReferencesOf(t *testing.T) {
+	deployment := namespacedObj("app", "web", withKind("apps/v1", "Deployment"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"priorityClassName": "high",
+			"runtimeClassName":  "gvisor",
+		}}},
+	}))
+	cronJob := namespacedObj("app", "nightly", withKind("batch/v1", "CronJob"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"priorityClassName": "low",
+		}}}}},
+	}))
+	statefulSet := namespacedObj("app", "db", withKind("apps/v1", "StatefulSet"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"volumeClaimTemplates": []interface{}{
+			map[string]interface{}{"spec": map[string]interface{}{}},
+			map[string]interface{}{"spec": map[string]interface{}{"storageClassName": "fast"}},
+		}},
+	}))
+	pvc := namespacedObj("app", "data", withKind("v1", "PersistentVolumeClaim"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"storageClassName": "fast", "volumeName": "pv-1"},
+	}))
+	ingress := namespacedObj("app", "web", withKind("networking.k8s.io/v1", "Ingress"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"ingressClassName": "nginx"},
+	}))
+	pv := clusterScopedObj("pv-1", withKind("v1", "PersistentVolume"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"storageClassName": "fast"},
+	}))
+
+	tests := []struct {
+		name string
+		obj  unstructured.Unstructured
+		want []string
+	}{
+		{
+			name: "deployment pod template",
+			obj:  deployment,
+			want: []string{
+				"PriorityClass high from Deployment app/web field spec.template.spec.priorityClassName",
+				"RuntimeClass gvisor from Deployment app/web field spec.template.spec.runtimeClassName",
+			},
+		},
+		{
+			name: "cronjob job template",
+			obj:  cronJob,
+			want: []string{"PriorityClass low from CronJob app/nightly field spec.jobTemplate.spec.template.spec.priorityClassName"},
+		},
+		{
+			name: "statefulset volume claim templates",
+			obj:  statefulSet,
+			want: []string{"StorageClass fast from StatefulSet app/db field spec.volumeClaimTemplates[1].spec.storageClassName"},
+		},
+		{
+			name: "bound pvc",
+			obj:  pvc,
+			want: []string{
+				"StorageClass fast from PersistentVolumeClaim app/data field spec.storageClassName",
+				"PersistentVolume pv-1 from PersistentVolumeClaim app/data field spec.volumeName",
+			},
+		},
+		{
+			name: "ingress",
+			obj:  ingress,
+			want: []string{"IngressClass nginx from Ingress app/web field spec.ingressClassName"},
+		},
+		{
+			name: "persistent volume",
+			obj:  pv,
+			want: []string{"StorageClass fast from PersistentVolume pv-1 field spec.storageClassName"},
+		},
+		{
+			name: "unrelated kind",
+			obj:  namespacedObj("app", "c", withKind("v1", "ConfigMap")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range clusterReferencesOf(tt.obj) {
+				got = append(got, r.kind.kind+" "+r.name+" from "+r.from)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectReferencedResources(t *testing.T) {
+	pvc := namespacedObj("app", "data", withKind("v1", "PersistentVolumeClaim"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"volumeName": "pv-1"},
+	}))
+	other := namespacedObj("app", "other", withKind("v1", "PersistentVolumeClaim"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"storageClassName": "fast", "volumeName": "pv-1"},
+	}))
+	pod := namespacedObj("app", "p", withKind("v1", "Pod"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"priorityClassName": "missing"},
+	}))
+	pv := clusterScopedObj("pv-1", withKind("v1", "PersistentVolume"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"storageClassName": "fast"},
+	}))
+	sc := clusterScopedObj("fast", withKind("storage.k8s.io/v1", "StorageClass"))
+
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), &pv, &sc)
+	handler := NewClusterScopeHandler()
+	resources := []*groupResource{
+		testGroupResource("persistentvolumeclaims", "PersistentVolumeClaim", true, pvc, other),
+		testGroupResource("pods", "Pod", true, pod),
+	}
+
+	got, errs := handler.collectReferencedResources(0, resources, client, resourceFilter{}, testLogger())
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	var kinds []string
+	for _, g := range got {
+		if g.APIResource.Namespaced {
+			t.Errorf("%s should be cluster-scoped", g.APIResource.Kind)
+		}
+		for _, obj := range g.objects.Items {
+			kinds = append(kinds, g.APIResource.Kind+"/"+obj.GetName())
+		}
+	}
+	want := []string{"StorageClass/fast", "PersistentVolume/pv-1"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("collected %v, want %v", kinds, want)
+	}
+}
+
+func TestCollectReferencedResources_respectsResourceFilter(t *testing.T) {
+	pvc := namespacedObj("app", "data", withKind("v1", "PersistentVolumeClaim"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"storageClassName": "fast", "volumeName": "pv-1"},
+	}))
+	pv := clusterScopedObj("pv-1", withKind("v1", "PersistentVolume"))
+	sc := clusterScopedObj("fast", withKind("storage.k8s.io/v1", "StorageClass"))
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), &pv, &sc)
+	filter, err := newResourceFilter(nil, []string{"persistentvolumes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, errs := NewClusterScopeHandler().collectReferencedResources(0, []*groupResource{testGroupResource("persistentvolumeclaims", "PersistentVolumeClaim", true, pvc)}, client, filter, testLogger())
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(got) != 1 || got[0].APIResource.Kind != "StorageClass" {
+		t.Fatalf("expected only the StorageClass, got %+v", got)
+	}
+}
+
+func TestCollectReferencedResources_forbiddenReturnsGroupResourceError(t *testing.T) {
+	ingress := namespacedObj("app", "web", withKind("networking.k8s.io/v1", "Ingress"), withFields(map[string]interface{}{
+		"spec": map[string]interface{}{"ingressClassName": "nginx"},
+	}))
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependReactor("get", "ingressclasses", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "networking.k8s.io", Resource: "ingressclasses"}, "nginx", nil)
+	})
+
+	got, errs := NewClusterScopeHandler().collectReferencedResources(0, []*groupResource{testGroupResource("ingresses", "Ingress", true, ingress)}, client, resourceFilter{}, testLogger())
+	if len(got) != 0 {
+		t.Fatalf("expected no rows, got %d", len(got))
+	}
+	if len(errs) != 1 {
+		t.Fatalf("len(errs) = %d, want 1", len(errs))
+	}
+	if errs[0].APIResource.Name != "ingressclass-nginx" || errs[0].APIResource.Kind != "IngressClass" {
+		t.Errorf("unexpected APIResource %+v", errs[0].APIResource)
+	}
+	if !apierrors.IsForbidden(errs[0].Error) {
+		t.Errorf("expected Forbidden, got %v", errs[0].Error)
+	}
+	if name := failedResourceName(errs[0]); name != "ingressclasses" {
+		t.Errorf("failedResourceName = %q, want ingressclasses", name)
+	}
+}
@@ -0,0 +1,272 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/dynamic"
+)
+
+// maxRetryBackoff caps the exponential backoff between two attempts.
+const maxRetryBackoff = 30 * time.Second
+
+// retryPolicy controls how list and get requests are retried on transient errors.
+type retryPolicy struct {
+	// maxRetries is the number of retries after the first attempt; 0 disables retries.
+	maxRetries int
+	// backoff is the delay before the first retry. It doubles for every further retry,
+	// up to maxRetryBackoff, and is jittered so parallel lists do not retry in lockstep.
+	backoff time.Duration
+	// sleep waits for d or until ctx is done; tests replace it to avoid real waits.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// delay returns the jittered wait before retry number n (starting at 1). A Retry-After
+// suggested by the server is honoured when it is longer.
+func (p retryPolicy) delay(n int, err error) time.Duration {
+	d := p.backoff
+	for i := 1; i < n && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	if d > 0 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	if seconds, ok := apierrors.SuggestsClientDelay(err); ok {
+		if suggested := time.Duration(seconds) * time.Second; suggested > d {
+			d = suggested
+		}
+	}
+	return d
+}
+
+// isRetriableError reports whether err is likely transient: throttling, an unavailable
+// or timed-out API server (including aggregated APIs), or a dropped connection.
+func isRetriableError(err error) bool {
+	switch {
+	case apierrors.IsTooManyRequests(err),
+		apierrors.IsServiceUnavailable(err),
+		apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err):
+		return true
+	}
+	return utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryingClient wraps a dynamic client so List and Get are retried according to policy.
+// It records, per resource and namespace, the most attempts any single request needed.
+type retryingClient struct {
+	dynamic.Interface
+	policy retryPolicy
+	log    logrus.FieldLogger
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func newRetryingClient(client dynamic.Interface, policy retryPolicy, log logrus.FieldLogger) *retryingClient {
+	if policy.sleep == nil {
+		policy.sleep = sleepContext
+	}
+	return &retryingClient{Interface: client, policy: policy, log: log, attempts: map[string]int{}}
+}
+
+func attemptsKey(gvr schema.GroupVersionResource, namespace string) string {
+	return gvr.String() + "|" + namespace
+}
+
+// maxAttempts returns the most attempts a request for gvr in namespace needed, or 0
+// when no request was made.
+func (c *retryingClient) maxAttempts(gvr schema.GroupVersionResource, namespace string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts[attemptsKey(gvr, namespace)]
+}
+
+func (c *retryingClient) record(gvr schema.GroupVersionResource, namespace string, attempts int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := attemptsKey(gvr, namespace)
+	if attempts > c.attempts[key] {
+		c.attempts[key] = attempts
+	}
+}
+
+// do calls fn until it succeeds, fails with a non-retriable error, ctx is done, or the
+// retries are used up, and returns the last error.
+func (c *retryingClient) do(ctx context.Context, gvr schema.GroupVersionResource, namespace, verb string, fn func() error) error {
+	attempts := 0
+	defer func() { c.record(gvr, namespace, attempts) }()
+	for {
+		attempts++
+		err := fn()
+		if err == nil || attempts > c.policy.maxRetries || !isRetriableError(err) {
+			if err != nil && attempts > 1 {
+				err = &retriesExhaustedError{attempts: attempts, err: err}
+			}
+			return err
+		}
+		d := c.policy.delay(attempts, err)
+		c.log.Warnf("Retrying %s %s in namespace %q in %s (attempt %d of %d): %v", verb, gvr.GroupResource(), namespace, d.Round(time.Millisecond), attempts+1, c.policy.maxRetries+1, err)
+		if sleepErr := c.policy.sleep(ctx, d); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// retriesExhaustedError is returned when a retriable error persisted after every retry.
+// It unwraps to the last error, so apierrors checks still see the original status.
+type retriesExhaustedError struct {
+	attempts int
+	err      error
+}
+
+func (e *retriesExhaustedError) Error() string {
+	return fmt.Sprintf("%v (after %d attempts)", e.err, e.attempts)
+}
+
+func (e *retriesExhaustedError) Unwrap() error { return e.err }
+
+func (c *retryingClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &retryingResourceClient{NamespaceableResourceInterface: c.Interface.Resource(gvr), client: c, gvr: gvr}
+}
+
+type retryingResourceClient struct {
+	dynamic.NamespaceableResourceInterface
+	client *retryingClient
+	gvr    schema.GroupVersionResource
+}
+
+func (r *retryingResourceClient) Namespace(namespace string) dynamic.ResourceInterface {
+	return &retryingNamespacedClient{ResourceInterface: r.NamespaceableResourceInterface.Namespace(namespace), client: r.client, gvr: r.gvr, namespace: namespace}
+}
+
+func (r *retryingResourceClient) List(ctx context.Context, opts metav1.ListOptions) (list *unstructured.UnstructuredList, err error) {
+	err = r.client.do(ctx, r.gvr, "", "list", func() error {
+		list, err = r.NamespaceableResourceInterface.List(ctx, opts)
+		return err
+	})
+	return list, err
+}
+
+func (r *retryingResourceClient) Get(ctx context.Context, name string, opts metav1.GetOptions, subresources ...string) (obj *unstructured.Unstructured, err error) {
+	err = r.client.do(ctx, r.gvr, "", "get", func() error {
+		obj, err = r.NamespaceableResourceInterface.Get(ctx, name, opts, subresources...)
+		return err
+	})
+	return obj, err
+}
+
+type retryingNamespacedClient struct {
+	dynamic.ResourceInterface
+	client    *retryingClient
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (r *retryingNamespacedClient) List(ctx context.Context, opts metav1.ListOptions) (list *unstructured.UnstructuredList, err error) {
+	err = r.client.do(ctx, r.gvr, r.namespace, "list", func() error {
+		list, err = r.ResourceInterface.List(ctx, opts)
+		return err
+	})
+	return list, err
+}
+
+func (r *retryingNamespacedClient) Get(ctx context.Context, name string, opts metav1.GetOptions, subresources ...string) (obj *unstructured.Unstructured, err error) {
+	err = r.client.do(ctx, r.gvr, r.namespace, "get", func() error {
+		obj, err = r.ResourceInterface.Get(ctx, name, opts, subresources...)
+		return err
+	})
+	return obj, err
+}
+
+// recordAttempts copies the attempt counts of resources that needed retries into the
+// export manifest.
+func recordAttempts(manifest *file.ExportManifest, client *retryingClient) {
+	for i := range manifest.Resources {
+		r := &manifest.Resources[i]
+		if n := client.maxAttempts(schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Resource}, r.Namespace); n > 1 {
+			r.Attempts = n
+		}
+	}
+	for i := range manifest.Skipped {
+		s := &manifest.Skipped[i]
+		if n := client.maxAttempts(schema.GroupVersionResource{Group: s.Group, Version: s.Version, Resource: s.Resource}, s.Namespace); n > 1 {
+			s.Attempts = n
+		}
+	}
+}
+
+// Values accepted by --fail-on.
+const (
+	failOnAny           = "any"
+	failOnForbiddenOnly = "forbidden-only"
+	failOnNone          = "none"
+)
+
+var failOnPolicies = []string{failOnAny, failOnForbiddenOnly, failOnNone}
+
+// listFailures returns the errors that make the export exit non-zero under the --fail-on
+// policy, given the resources and list/get errors of every namespace (cluster-scoped ones
+// under ""):
+//   - any: every resource type that could not be listed or fetched after retries.
+//   - forbidden-only: a namespace where nothing was exported because every list returned
+//     Forbidden, which usually means the namespace or the user's permissions are wrong.
+//   - none: never.
+//
+// An empty policy is treated as forbidden-only, the default.
+func listFailures(policy string, namespaces []string, resources map[string][]*groupResource, resourceErrs map[string][]*groupResourceError) []error {
+	var errs []error
+	switch policy {
+	case failOnAny:
+		for _, namespace := range append([]string{""}, namespaces...) {
+			if n := len(resourceErrs[namespace]); n > 0 {
+				scope := fmt.Sprintf("namespace %q", namespace)
+				if namespace == "" {
+					scope = "cluster-scoped resources"
+				}
+				names := make([]string, 0, n)
+				for _, e := range resourceErrs[namespace] {
+					names = append(names, e.APIResource.Name)
+				}
+				errs = append(errs, fmt.Errorf("%d resource type(s) failed for %s: %s (--fail-on=%s)", n, scope, strings.Join(names, ", "), failOnAny))
+			}
+		}
+	case failOnForbiddenOnly, "":
+		for _, namespace := range namespaces {
+			all := append(append([]*groupResource{}, resources[namespace]...), resources[""]...)
+			allErrs := append(append([]*groupResourceError{}, resourceErrs[namespace]...), resourceErrs[""]...)
+			if allResourceListsForbidden(all, allErrs) {
+				errs = append(errs, fmt.Errorf(
+					"all resource types returned Forbidden for namespace %q -- verify the namespace exists and your user has list permissions",
+					namespace,
+				))
+			}
+		}
+	}
+	return errs
+}
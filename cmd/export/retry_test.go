@@ -0,0 +1,243 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/konveyor/crane/internal/file"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func TestIsRetriableError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"too many requests", apierrors.NewTooManyRequests("slow down", 0), true},
+		{"service unavailable", apierrors.NewServiceUnavailable("metrics API down"), true},
+		{"server timeout", apierrors.NewServerTimeout(gr, "list", 1), true},
+		{"gateway timeout", apierrors.NewTimeoutError("upstream", 1), true},
+		{"connection reset", &wrappedErr{syscall.ECONNRESET}, true},
+		{"forbidden", apierrors.NewForbidden(gr, "", errors.New("no")), false},
+		{"not found", apierrors.NewNotFound(gr, "x"), false},
+		{"client deadline", context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetriableError(tt.err); got != tt.want {
+				t.Errorf("isRetriableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+type wrappedErr struct{ err error }
+
+func (e *wrappedErr) Error() string { return "read tcp: " + e.err.Error() }
+func (e *wrappedErr) Unwrap() error { return e.err }
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := retryPolicy{backoff: time.Second}
+	for n, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: maxRetryBackoff} {
+		if d := p.delay(n, errors.New("x")); d < max/2 || d > max {
+			t.Errorf("delay(%d) = %s, want between %s and %s", n, d, max/2, max)
+		}
+	}
+	if d := p.delay(1, apierrors.NewTooManyRequests("slow down", 5)); d != 5*time.Second {
+		t.Errorf("delay with Retry-After 5 = %s, want 5s", d)
+	}
+}
+
+func failingConfigMapsClient(t *testing.T, failures int, err error) (*retryingClient, *int, *[]time.Duration) {
+	t.Helper()
+	fake := dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme)
+	calls := 0
+	fake.PrependReactor("list", "configmaps", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		calls++
+		if calls <= failures {
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+	var waits []time.Duration
+	policy := retryPolicy{maxRetries: 3, backoff: time.Second, sleep: func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}}
+	return newRetryingClient(fake, policy, testLogger()), &calls, &waits
+}
+
+func TestRetryingClient_List(t *testing.T) {
+	unavailable := apierrors.NewServiceUnavailable("try again")
+	forbidden := apierrors.NewForbidden(configMapsGVR.GroupResource(), "", errors.New("no"))
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantErr      bool
+		wantCalls    int
+		wantAttempts int
+	}{
+		{name: "no failures", wantCalls: 1, wantAttempts: 1},
+		{name: "recovers after retries", failures: 2, err: unavailable, wantCalls: 3, wantAttempts: 3},
+		{name: "retries exhausted", failures: 10, err: unavailable, wantErr: true, wantCalls: 4, wantAttempts: 4},
+		{name: "permanent error not retried", failures: 10, err: forbidden, wantErr: true, wantCalls: 1, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, calls, waits := failingConfigMapsClient(t, tt.failures, tt.err)
+			_, err := client.Resource(configMapsGVR).Namespace("app").List(context.Background(), metav1.ListOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("List error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, tt.err) && apierrors.ReasonForError(err) != apierrors.ReasonForError(tt.err) {
+				t.Errorf("List error %v does not preserve %v", err, tt.err)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", *calls, tt.wantCalls)
+			}
+			if len(*waits) != tt.wantCalls-1 {
+				t.Errorf("waited %d times, want %d", len(*waits), tt.wantCalls-1)
+			}
+			if got := client.maxAttempts(configMapsGVR, "app"); got != tt.wantAttempts {
+				t.Errorf("maxAttempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if got := client.maxAttempts(configMapsGVR, "other"); got != 0 {
+				t.Errorf("maxAttempts for another namespace = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestRetryingClient_stopsWhenContextDone(t *testing.T) {
+	client, calls, _ := failingConfigMapsClient(t, 10, apierrors.NewServiceUnavailable("try again"))
+	client.policy.sleep = sleepContext
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Resource(configMapsGVR).Namespace("app").List(ctx, metav1.ListOptions{}); !apierrors.IsServiceUnavailable(err) {
+		t.Fatalf("List error = %v, want the last ServiceUnavailable", err)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestRecordAttempts(t *testing.T) {
+	client, _, _ := failingConfigMapsClient(t, 1, apierrors.NewTooManyRequests("slow down", 0))
+	if _, err := client.Resource(configMapsGVR).Namespace("app").List(context.Background(), metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	manifest := &file.ExportManifest{
+		Resources: []file.ExportedResource{
+			{Namespace: "app", Version: "v1", Resource: "configmaps"},
+			{Namespace: "app", Version: "v1", Resource: "secrets"},
+		},
+	}
+	recordAttempts(manifest, client)
+	if manifest.Resources[0].Attempts != 2 || manifest.Resources[1].Attempts != 0 {
+		t.Errorf("attempts = %d, %d; want 2, 0", manifest.Resources[0].Attempts, manifest.Resources[1].Attempts)
+	}
+}
+
+func TestListFailures(t *testing.T) {
+	apiResource := metav1.APIResource{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}
+	forbidden := &groupResourceError{APIResource: apiResource, Error: apierrors.NewForbidden(configMapsGVR.GroupResource(), "", errors.New("no"))}
+	unavailable := &groupResourceError{APIResource: apiResource, Error: apierrors.NewServiceUnavailable("down")}
+	exported := []*groupResource{{APIResource: apiResource}}
+
+	tests := []struct {
+		name         string
+		policy       string
+		resources    map[string][]*groupResource
+		resourceErrs map[string][]*groupResourceError
+		want         []string
+	}{
+		{
+			name:         "forbidden-only fails a namespace where everything is Forbidden",
+			policy:       failOnForbiddenOnly,
+			resources:    map[string][]*groupResource{"b": exported},
+			resourceErrs: map[string][]*groupResourceError{"a": {forbidden}},
+			want:         []string{`all resource types returned Forbidden for namespace "a"`},
+		},
+		{
+			name:         "default policy is forbidden-only",
+			resourceErrs: map[string][]*groupResourceError{"a": {forbidden}, "b": {forbidden}},
+			want:         []string{`namespace "a"`, `namespace "b"`},
+		},
+		{
+			name:         "forbidden-only ignores other errors",
+			policy:       failOnForbiddenOnly,
+			resourceErrs: map[string][]*groupResourceError{"a": {unavailable}},
+		},
+		{
+			name:         "any fails on every remaining error",
+			policy:       failOnAny,
+			resources:    map[string][]*groupResource{"a": exported},
+			resourceErrs: map[string][]*groupResourceError{"": {unavailable}, "a": {forbidden}},
+			want:         []string{"cluster-scoped resources: configmaps", `namespace "a": configmaps`},
+		},
+		{
+			name:         "none never fails",
+			policy:       failOnNone,
+			resourceErrs: map[string][]*groupResourceError{"a": {forbidden}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := listFailures(tt.policy, []string{"a", "b"}, tt.resources, tt.resourceErrs)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("listFailures() = %v, want %d error(s)", errs, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("error %d = %q, want it to contain %q", i, errs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_retryFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		o       ExportOptions
+		wantErr string
+	}{
+		{name: "defaults", o: ExportOptions{maxRetries: 3, retryBackoff: time.Second, failOn: failOnForbiddenOnly}},
+		{name: "retries disabled", o: ExportOptions{failOn: failOnNone}},
+		{name: "negative retries", o: ExportOptions{maxRetries: -1}, wantErr: "--max-retries"},
+		{name: "missing backoff", o: ExportOptions{maxRetries: 2}, wantErr: "--retry-backoff"},
+		{name: "unknown policy", o: ExportOptions{failOn: "sometimes"}, wantErr: "--fail-on"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := tt.o
+			o.configFlags = genericclioptions.NewConfigFlags(true)
+			err := o.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want error mentioning %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"time"
+
+	"github.com/konveyor/crane/internal/secrets"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// secretsMode returns how Secret values are protected in this export, or "" when they
+// are written as-is.
+func (o *ExportOptions) secretsMode() string {
+	switch {
+	case o.secretsKeyFile != "":
+		return secrets.ModeEncrypted
+	case o.redactSecrets:
+		return secrets.ModeRedacted
+	}
+	return ""
+}
+
+// protectSecrets encrypts or redacts the data and stringData values of every exported
+// Secret in place, according to secretsMode. It stops at the first failure so that no
+// Secret is written with its values in clear text. Streamed resources were protected
+// while listing and are skipped.
+func (o *ExportOptions) protectSecrets(resources []*groupResource, log logrus.FieldLogger) error {
+	if o.secretsMode() == "" {
+		return nil
+	}
+	now := time.Now()
+	count := 0
+	for _, r := range resources {
+		if r.streamed {
+			continue
+		}
+		for i := range r.objects.Items {
+			protected, err := o.protectSecret(&r.objects.Items[i], now, log)
+			if err != nil {
+				return err
+			}
+			if protected {
+				count++
+			}
+		}
+	}
+	if count > 0 {
+		log.Infof("Stored %d Secret(s) %s", count, o.secretsMode())
+	}
+	return nil
+}
+
+// protectSecret encrypts or redacts obj in place if it is a Secret and secretsMode is
+// set, and reports whether it did.
+func (o *ExportOptions) protectSecret(obj *unstructured.Unstructured, now time.Time, log logrus.FieldLogger) (bool, error) {
+	if !secrets.IsSecret(*obj) {
+		return false, nil
+	}
+	switch o.secretsMode() {
+	case secrets.ModeEncrypted:
+		if err := secrets.Encrypt(obj, o.secretsKey, now); err != nil {
+			return false, err
+		}
+	case secrets.ModeRedacted:
+		fields, err := secrets.Redact(obj)
+		if err != nil {
+			return false, err
+		}
+		log.Debugf("Redacted %v of Secret %s/%s", fields, obj.GetNamespace(), obj.GetName())
+	default:
+		return false, nil
+	}
+	return true, nil
+}
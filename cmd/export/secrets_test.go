@@ -0,0 +1,78 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/crane/internal/secrets"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func secretTestResources() []*groupResource {
+	secret := namespacedObj("app", "db", withKind("v1", "Secret"), withUID("s1"))
+	secret.Object["data"] = map[string]interface{}{"password": "czNjcjN0"}
+	configMap := namespacedObj("app", "settings", withKind("v1", "ConfigMap"), withUID("c1"))
+	configMap.Object["data"] = map[string]interface{}{"password": "not-a-secret"}
+	return []*groupResource{
+		testGroupResource("secrets", "Secret", true, secret),
+		testGroupResource("configmaps", "ConfigMap", true, configMap),
+	}
+}
+
+func TestProtectSecrets(t *testing.T) {
+	key := bytes.Repeat([]byte{0x07}, 32)
+	tests := []struct {
+		name         string
+		opts         ExportOptions
+		wantPassword func(string) bool
+	}{
+		{
+			name:         "as-is",
+			opts:         ExportOptions{},
+			wantPassword: func(v string) bool { return v == "czNjcjN0" },
+		},
+		{
+			name:         "encrypted",
+			opts:         ExportOptions{secretsKeyFile: "key", secretsKey: key},
+			wantPassword: func(v string) bool { return strings.HasPrefix(v, "ENC[AES256_GCM,") },
+		},
+		{
+			name:         "redacted",
+			opts:         ExportOptions{redactSecrets: true},
+			wantPassword: func(v string) bool { return v == "" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := secretTestResources()
+			if err := tt.opts.protectSecrets(resources, testLogger()); err != nil {
+				t.Fatalf("protectSecrets: %v", err)
+			}
+			secret := resources[0].objects.Items[0]
+			password, _, _ := unstructured.NestedString(secret.Object, "data", "password")
+			if !tt.wantPassword(password) {
+				t.Errorf("unexpected Secret data.password %q", password)
+			}
+			if tt.opts.redactSecrets && len(secrets.RedactedFields(secret.GetAnnotations())) != 1 {
+				t.Errorf("expected redacted fields annotation, got %v", secret.GetAnnotations())
+			}
+			configMap := resources[1].objects.Items[0]
+			if v, _, _ := unstructured.NestedString(configMap.Object, "data", "password"); v != "not-a-secret" {
+				t.Errorf("ConfigMap data must be left alone, got %q", v)
+			}
+		})
+	}
+}
+
+func TestValidate_SecretsFlags(t *testing.T) {
+	o := &ExportOptions{
+		configFlags:    genericclioptions.NewConfigFlags(true),
+		secretsKeyFile: "key",
+		redactSecrets:  true,
+	}
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "--redact-secrets") {
+		t.Fatalf("expected mutually exclusive error, got %v", err)
+	}
+}
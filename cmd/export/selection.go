@@ -0,0 +1,358 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const rbacGroup = "rbac.authorization.k8s.io"
+
+// objectRef names a namespaced object as "Kind.group/name", or "Kind/name" for any group.
+// Kind also matches the resource name (deployments) and is case-insensitive.
+type objectRef struct {
+	kind string
+	// group is the API group; anyGroup is set when the reference did not name one.
+	group    string
+	anyGroup bool
+	name     string
+}
+
+func (r objectRef) String() string {
+	if r.anyGroup || r.group == "" {
+		return r.kind + "/" + r.name
+	}
+	return r.kind + "." + r.group + "/" + r.name
+}
+
+// parseObjectRef parses a --objects-file line or --app-root value.
+func parseObjectRef(s string) (objectRef, error) {
+	kindGroup, name, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok || kindGroup == "" || name == "" || strings.Contains(name, "/") {
+		return objectRef{}, fmt.Errorf("%q is not kind.group/name", s)
+	}
+	kind, group, hasGroup := strings.Cut(kindGroup, ".")
+	return objectRef{kind: kind, group: group, anyGroup: !hasGroup, name: name}, nil
+}
+
+// coreRef references a core object by kind and name.
+func coreRef(kind, name string) objectRef {
+	return objectRef{kind: kind, name: name}
+}
+
+func (r objectRef) matches(g *groupResource, obj unstructured.Unstructured) bool {
+	if obj.GetName() != r.name {
+		return false
+	}
+	if !r.anyGroup && r.group != g.APIGroup {
+		return false
+	}
+	return strings.EqualFold(r.kind, g.APIResource.Kind) || strings.EqualFold(r.kind, g.APIResource.Name) || strings.EqualFold(r.kind, g.APIResource.SingularName)
+}
+
+// readObjectsFile reads --objects-file: one kind.group/name per line. Blank lines and lines
+// starting with # are ignored.
+func readObjectsFile(path string) ([]objectRef, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("--objects-file: %w", err)
+	}
+	defer f.Close()
+
+	var refs []objectRef
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		ref, err := parseObjectRef(text)
+		if err != nil {
+			return nil, fmt.Errorf("--objects-file %s line %d: %w", path, line, err)
+		}
+		refs = append(refs, ref)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("--objects-file: %w", err)
+	}
+	return refs, nil
+}
+
+// loadObjectSelection parses --objects-file and --app-root.
+func (o *ExportOptions) loadObjectSelection() error {
+	if o.objectsFile != "" {
+		refs, err := readObjectsFile(o.objectsFile)
+		if err != nil {
+			return err
+		}
+		if len(refs) == 0 {
+			return fmt.Errorf("--objects-file %s names no objects", o.objectsFile)
+		}
+		o.objectRefs = refs
+	}
+	o.rootRefs = nil
+	for _, root := range o.appRoots {
+		ref, err := parseObjectRef(root)
+		if err != nil {
+			return fmt.Errorf("--app-root: %w", err)
+		}
+		o.rootRefs = append(o.rootRefs, ref)
+	}
+	return nil
+}
+
+// selectsObjects reports whether --objects-file or --app-root limits the export.
+func (o *ExportOptions) selectsObjects() bool {
+	return len(o.objectRefs) > 0 || len(o.rootRefs) > 0
+}
+
+// selectable is one listed object and the resource type it was listed as.
+type selectable struct {
+	g   *groupResource
+	obj *unstructured.Unstructured
+}
+
+func (s selectable) key() string {
+	return s.g.APIGroup + "/" + s.g.APIResource.Kind + "/" + s.obj.GetName()
+}
+
+func (s selectable) String() string {
+	return s.obj.GetKind() + " " + s.obj.GetNamespace() + "/" + s.obj.GetName()
+}
+
+// selectObjects keeps only the objects of one namespace named by refs, plus the dependency
+// closure of every object named by roots (see dependenciesOf and dependentsOf). The
+// groupResources in resources are updated in place; references to objects that were not
+// listed are logged. It returns the number of objects kept.
+func selectObjects(namespace string, resources []*groupResource, refs, roots []objectRef, log logrus.FieldLogger) int {
+	var all []selectable
+	for _, g := range resources {
+		if g == nil || g.objects == nil {
+			continue
+		}
+		for i := range g.objects.Items {
+			all = append(all, selectable{g: g, obj: &g.objects.Items[i]})
+		}
+	}
+	find := func(ref objectRef) []selectable {
+		var found []selectable
+		for _, s := range all {
+			if ref.matches(s.g, *s.obj) {
+				found = append(found, s)
+			}
+		}
+		return found
+	}
+
+	selected := map[string]bool{}
+	for _, ref := range refs {
+		found := find(ref)
+		if len(found) == 0 {
+			log.Warnf("%s was not found in namespace %q", ref, namespace)
+		}
+		for _, s := range found {
+			selected[s.key()] = true
+		}
+	}
+	var queue []selectable
+	for _, root := range roots {
+		found := find(root)
+		if len(found) == 0 {
+			log.Warnf("App root %s was not found in namespace %q", root, namespace)
+		}
+		queue = append(queue, found...)
+	}
+	inClosure := map[string]bool{}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if inClosure[s.key()] {
+			continue
+		}
+		inClosure[s.key()] = true
+		selected[s.key()] = true
+		for _, dep := range dependenciesOf(*s.obj) {
+			found := find(dep)
+			if len(found) == 0 {
+				log.Warnf("%s referenced by %s was not found in namespace %q, not exporting it", dep, s, namespace)
+			}
+			queue = append(queue, found...)
+		}
+		queue = append(queue, dependentsOf(s, all)...)
+	}
+
+	kept := 0
+	for _, g := range resources {
+		if g == nil || g.objects == nil {
+			continue
+		}
+		items := make([]unstructured.Unstructured, 0, len(g.objects.Items))
+		for i := range g.objects.Items {
+			if selected[(selectable{g: g, obj: &g.objects.Items[i]}).key()] {
+				items = append(items, g.objects.Items[i])
+			}
+		}
+		g.objects.Items = items
+		kept += len(items)
+	}
+	return kept
+}
+
+// dependenciesOf returns the namespaced objects obj refers to by name: for workloads, the
+// ConfigMaps, Secrets, and PersistentVolumeClaims of volumes, env, and envFrom, image pull
+// Secrets, and the ServiceAccount; for ServiceAccounts, their Secrets; for RoleBindings,
+// the Role.
+func dependenciesOf(obj unstructured.Unstructured) []objectRef {
+	var refs []objectRef
+	add := func(kind string, m map[string]interface{}, fields ...string) {
+		if name, _, _ := unstructured.NestedString(m, fields...); name != "" {
+			refs = append(refs, coreRef(kind, name))
+		}
+	}
+	each := func(m map[string]interface{}, fields ...string) []map[string]interface{} {
+		items, _, _ := unstructured.NestedSlice(m, fields...)
+		var out []map[string]interface{}
+		for _, item := range items {
+			if im, ok := item.(map[string]interface{}); ok {
+				out = append(out, im)
+			}
+		}
+		return out
+	}
+
+	switch obj.GetKind() {
+	case "ServiceAccount":
+		for _, s := range each(obj.Object, "secrets") {
+			add("Secret", s, "name")
+		}
+		for _, s := range each(obj.Object, "imagePullSecrets") {
+			add("Secret", s, "name")
+		}
+		return refs
+	case "RoleBinding":
+		if kind, _, _ := unstructured.NestedString(obj.Object, "roleRef", "kind"); kind == "Role" {
+			name, _, _ := unstructured.NestedString(obj.Object, "roleRef", "name")
+			refs = append(refs, objectRef{kind: "Role", group: rbacGroup, name: name})
+		}
+		return refs
+	}
+
+	path, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return nil
+	}
+	spec, _, _ := unstructured.NestedMap(obj.Object, path...)
+	if spec == nil {
+		return nil
+	}
+	if name, _, _ := unstructured.NestedString(spec, "serviceAccountName"); name != "" {
+		refs = append(refs, coreRef("ServiceAccount", name))
+	} else {
+		add("ServiceAccount", spec, "serviceAccount")
+	}
+	for _, s := range each(spec, "imagePullSecrets") {
+		add("Secret", s, "name")
+	}
+	for _, v := range each(spec, "volumes") {
+		add("ConfigMap", v, "configMap", "name")
+		add("Secret", v, "secret", "secretName")
+		add("PersistentVolumeClaim", v, "persistentVolumeClaim", "claimName")
+		for _, source := range each(v, "projected", "sources") {
+			add("ConfigMap", source, "configMap", "name")
+			add("Secret", source, "secret", "name")
+		}
+	}
+	for _, containers := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		for _, c := range each(spec, containers) {
+			for _, env := range each(c, "env") {
+				add("ConfigMap", env, "valueFrom", "configMapKeyRef", "name")
+				add("Secret", env, "valueFrom", "secretKeyRef", "name")
+			}
+			for _, from := range each(c, "envFrom") {
+				add("ConfigMap", from, "configMapRef", "name")
+				add("Secret", from, "secretRef", "name")
+			}
+		}
+	}
+	return refs
+}
+
+// dependentsOf returns the objects in all that s needs but that point at s rather than
+// the other way round: Services whose selector matches the pods of a workload, the
+// PersistentVolumeClaims created from a StatefulSet's volumeClaimTemplates, and the
+// RoleBindings of a ServiceAccount.
+func dependentsOf(s selectable, all []selectable) []selectable {
+	var out []selectable
+	kind := s.obj.GetKind()
+	if path, ok := podSpecPaths[kind]; ok {
+		labelsPath := append(append([]string{}, path[:len(path)-1]...), "metadata", "labels")
+		if kind == "Pod" {
+			labelsPath = []string{"metadata", "labels"}
+		}
+		podLabels, _, _ := unstructured.NestedStringMap(s.obj.Object, labelsPath...)
+		for _, other := range all {
+			if other.g.APIGroup != "" || other.obj.GetKind() != "Service" {
+				continue
+			}
+			selector, _, _ := unstructured.NestedStringMap(other.obj.Object, "spec", "selector")
+			if len(selector) > 0 && labels.SelectorFromSet(selector).Matches(labels.Set(podLabels)) {
+				out = append(out, other)
+			}
+		}
+	}
+
+	switch kind {
+	case "StatefulSet":
+		templates, _, _ := unstructured.NestedSlice(s.obj.Object, "spec", "volumeClaimTemplates")
+		for _, t := range templates {
+			m, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(m, "metadata", "name")
+			prefix := name + "-" + s.obj.GetName() + "-"
+			for _, other := range all {
+				if other.g.APIGroup == "" && other.obj.GetKind() == "PersistentVolumeClaim" && isOrdinalName(other.obj.GetName(), prefix) {
+					out = append(out, other)
+				}
+			}
+		}
+	case "ServiceAccount":
+		for _, other := range all {
+			if other.g.APIGroup != rbacGroup || other.obj.GetKind() != "RoleBinding" {
+				continue
+			}
+			subjects, _, _ := unstructured.NestedSlice(other.obj.Object, "subjects")
+			for _, subject := range subjects {
+				m, ok := subject.(map[string]interface{})
+				if !ok || m["kind"] != "ServiceAccount" || m["name"] != s.obj.GetName() {
+					continue
+				}
+				if ns, _ := m["namespace"].(string); ns == "" || ns == s.obj.GetNamespace() {
+					out = append(out, other)
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// isOrdinalName reports whether name is prefix followed by a StatefulSet ordinal.
+func isOrdinalName(name, prefix string) bool {
+	ordinal, ok := strings.CutPrefix(name, prefix)
+	if !ok || ordinal == "" {
+		return false
+	}
+	for _, r := range ordinal {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/yaml"
+)
+
+func TestParseObjectRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    objectRef
+		wantErr bool
+	}{
+		{in: "Deployment.apps/web", want: objectRef{kind: "Deployment", group: "apps", name: "web"}},
+		{in: "Deployment/web", want: objectRef{kind: "Deployment", anyGroup: true, name: "web"}},
+		{in: " routes.route.openshift.io/web ", want: objectRef{kind: "routes", group: "route.openshift.io", name: "web"}},
+		{in: "web", wantErr: true},
+		{in: "Deployment/", wantErr: true},
+		{in: "app/Deployment/web", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseObjectRef(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseObjectRef(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseObjectRef(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadObjectsFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(p, []byte("# web app\nDeployment.apps/web\n\nConfigMap/settings\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	refs, err := readObjectsFile(p)
+	if err != nil || len(refs) != 2 || refs[1].String() != "ConfigMap/settings" {
+		t.Fatalf("readObjectsFile = %v, %v", refs, err)
+	}
+
+	if err := os.WriteFile(p, []byte("Deployment.apps/web\nweb\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readObjectsFile(p); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error naming line 2, got %v", err)
+	}
+}
+
+// selectionResources parses docs into groupResources, one per kind, the way they are
+// listed in one namespace.
+func selectionResources(t *testing.T, docs ...string) []*groupResource {
+	t.Helper()
+	byKind := map[string]*groupResource{}
+	var out []*groupResource
+	for _, doc := range docs {
+		obj := unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			t.Fatal(err)
+		}
+		gvk := obj.GroupVersionKind()
+		g, ok := byKind[gvk.Kind]
+		if !ok {
+			g = &groupResource{
+				APIGroup:    gvk.Group,
+				APIVersion:  gvk.Version,
+				APIResource: metav1.APIResource{Name: strings.ToLower(gvk.Kind) + "s", Kind: gvk.Kind, Namespaced: true},
+				objects:     &unstructured.UnstructuredList{},
+			}
+			byKind[gvk.Kind] = g
+			out = append(out, g)
+		}
+		g.objects.Items = append(g.objects.Items, obj)
+	}
+	return out
+}
+
+func selectedNames(resources []*groupResource) []string {
+	var names []string
+	for _, g := range resources {
+		for _, obj := range g.objects.Items {
+			names = append(names, obj.GetKind()+"/"+obj.GetName())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+const selectionDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: app}
+spec:
+  template:
+    metadata:
+      labels: {app: web, tier: frontend}
+    spec:
+      serviceAccountName: web
+      imagePullSecrets: [{name: pull}]
+      volumes:
+      - {name: config, configMap: {name: web-config}}
+      - {name: data, persistentVolumeClaim: {claimName: web-data}}
+      - name: certs
+        projected:
+          sources: [{secret: {name: web-tls}}]
+      containers:
+      - name: web
+        env:
+        - name: PASSWORD
+          valueFrom: {secretKeyRef: {name: db, key: password}}
+        envFrom: [{configMapRef: {name: web-env}}]
+`
+
+func TestSelectObjects_AppRootClosure(t *testing.T) {
+	resources := selectionResources(t,
+		selectionDeployment,
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: worker, namespace: app}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: web-config, namespace: app}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: web-env, namespace: app}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: unrelated, namespace: app}\n",
+		"apiVersion: v1\nkind: Secret\nmetadata: {name: db, namespace: app}\n",
+		"apiVersion: v1\nkind: Secret\nmetadata: {name: web-tls, namespace: app}\n",
+		"apiVersion: v1\nkind: Secret\nmetadata: {name: web-token, namespace: app}\n",
+		"apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata: {name: web-data, namespace: app}\n",
+		"apiVersion: v1\nkind: ServiceAccount\nmetadata: {name: web, namespace: app}\nsecrets: [{name: web-token}]\n",
+		"apiVersion: v1\nkind: Service\nmetadata: {name: web, namespace: app}\nspec: {selector: {app: web}}\n",
+		"apiVersion: v1\nkind: Service\nmetadata: {name: worker, namespace: app}\nspec: {selector: {app: worker}}\n",
+		"apiVersion: rbac.authorization.k8s.io/v1\nkind: RoleBinding\nmetadata: {name: web, namespace: app}\nroleRef: {kind: Role, name: web}\nsubjects: [{kind: ServiceAccount, name: web}]\n",
+		"apiVersion: rbac.authorization.k8s.io/v1\nkind: Role\nmetadata: {name: web, namespace: app}\n",
+	)
+	root, _ := parseObjectRef("Deployment/web")
+	kept := selectObjects("app", resources, nil, []objectRef{root}, testLogger())
+
+	want := []string{
+		"ConfigMap/web-config", "ConfigMap/web-env", "Deployment/web", "PersistentVolumeClaim/web-data",
+		"Role/web", "RoleBinding/web", "Secret/db", "Secret/web-tls", "Secret/web-token",
+		"Service/web", "ServiceAccount/web",
+	}
+	got := selectedNames(resources)
+	if kept != len(want) || strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("selected %d: %v\nwant %v", kept, got, want)
+	}
+}
+
+func TestSelectObjects_ObjectsFileAndStatefulSetClaims(t *testing.T) {
+	resources := selectionResources(t,
+		"apiVersion: apps/v1\nkind: StatefulSet\nmetadata: {name: db, namespace: app}\nspec:\n  volumeClaimTemplates: [{metadata: {name: data}}]\n",
+		"apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata: {name: data-db-0, namespace: app}\n",
+		"apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata: {name: data-db-1, namespace: app}\n",
+		"apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata: {name: data-db-backup, namespace: app}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: settings, namespace: app}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: other, namespace: app}\n",
+	)
+	listed, _ := parseObjectRef("configmaps/settings")
+	missing, _ := parseObjectRef("Secret/absent")
+	root, _ := parseObjectRef("StatefulSet.apps/db")
+	selectObjects("app", resources, []objectRef{listed, missing}, []objectRef{root}, testLogger())
+
+	want := "ConfigMap/settings,PersistentVolumeClaim/data-db-0,PersistentVolumeClaim/data-db-1,StatefulSet/db"
+	if got := strings.Join(selectedNames(resources), ","); got != want {
+		t.Errorf("selected %s, want %s", got, want)
+	}
+}
+
+func TestValidate_StreamWithObjectSelection(t *testing.T) {
+	o := &ExportOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		stream:      true,
+		rootRefs:    []objectRef{{kind: "Deployment", anyGroup: true, name: "web"}},
+	}
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "--stream") {
+		t.Fatalf("expected --stream error, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// exportSource is what an export reads objects from: the live cluster, or an offline
+// dump loaded with --from-file or --from-velero-backup. Everything after discovery
+// (filtering, RBAC handling, CRD collection, writing) is the same for both.
+type exportSource interface {
+	// server is the API server URL recorded in the export manifest, if any.
+	server() string
+	// description identifies an offline source in the export manifest; empty when live.
+	description() string
+	// requestTimeout bounds each request made through client; zero means no limit.
+	requestTimeout() time.Duration
+	// resolveNamespaces returns the namespaces selected by the export options.
+	resolveNamespaces(o *ExportOptions, log logrus.FieldLogger) ([]string, error)
+	// checkNamespace returns an error if namespace does not exist in the source.
+	checkNamespace(namespace string, log *logrus.Logger) error
+	// discover returns the API resource lists that may be exported.
+	discover(log logrus.FieldLogger) ([]*metav1.APIResourceList, error)
+	// servedResources returns the API resource lists of every served version, not only the
+	// preferred ones; used by --alternate-versions.
+	servedResources(log logrus.FieldLogger) ([]*metav1.APIResourceList, error)
+	client() dynamic.Interface
+}
+
+// liveSource reads from the cluster selected by the kubeconfig flags.
+type liveSource struct {
+	o             *ExportOptions
+	restConfig    *rest.Config
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+}
+
+func newLiveSource(o *ExportOptions, log logrus.FieldLogger) (*liveSource, error) {
+	restConfig, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		log.Errorf("Cannot create rest config: %v", err)
+		return nil, err
+	}
+
+	restConfig.Impersonate.Extra = mergeImpersonationExtras(restConfig.Impersonate.Extra, o.extras)
+	restConfig.Burst = o.Burst
+	restConfig.QPS = o.QPS
+
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		log.Errorf("Cannot create kubernetes client: %v", err)
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		log.Errorf("Cannot create dynamic client: %v", err)
+		return nil, err
+	}
+	return &liveSource{o: o, restConfig: restConfig, kubeClient: kubeClient, dynamicClient: dynamicClient}, nil
+}
+
+func (s *liveSource) server() string      { return s.restConfig.Host }
+func (s *liveSource) description() string { return "" }
+
+// requestTimeout passes restConfig.Timeout to child functions for per-request timeout enforcement.
+func (s *liveSource) requestTimeout() time.Duration { return s.restConfig.Timeout }
+
+func (s *liveSource) client() dynamic.Interface { return s.dynamicClient }
+
+func (s *liveSource) resolveNamespaces(o *ExportOptions, log logrus.FieldLogger) ([]string, error) {
+	return o.resolveExportNamespaces(context.Background(), s.kubeClient, log)
+}
+
+func (s *liveSource) checkNamespace(namespace string, log *logrus.Logger) error {
+	return validateExportNamespace(context.Background(), s.kubeClient, namespace, log)
+}
+
+func (s *liveSource) discover(log logrus.FieldLogger) ([]*metav1.APIResourceList, error) {
+	discoveryClient, err := s.o.configFlags.ToDiscoveryClient()
+	if err != nil {
+		log.Errorf("Cannot create discovery client: %v", err)
+		return nil, err
+	}
+
+	// Always request fresh data from the server
+	discoveryClient.Invalidate()
+
+	return discoverPreferredResources(discoveryClient, log)
+}
+
+func (s *liveSource) servedResources(log logrus.FieldLogger) ([]*metav1.APIResourceList, error) {
+	discoveryClient, err := s.o.configFlags.ToDiscoveryClient()
+	if err != nil {
+		log.Errorf("Cannot create discovery client: %v", err)
+		return nil, err
+	}
+	return discoverServedResources(discoveryClient, log)
+}
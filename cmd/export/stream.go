@@ -0,0 +1,299 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/konveyor/crane/internal/helm"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// streamPageSize is the number of objects requested per page, the same as the pager
+// getObjects uses.
+const streamPageSize = 500
+
+// streamWriter writes namespaced objects to the export directory one list page at a time
+// (--stream), so a namespace with hundreds of thousands of objects never has to fit in
+// memory. For each object only an index entry is kept: its identity, resourceVersion, and
+// ownerReferences, plus the cluster references found in it. That is all the RBAC, CRD,
+// --skip-owned, --follow-cluster-references, and manifest passes read.
+type streamWriter struct {
+	layout exportLayout
+	// protect encrypts or redacts a Secret before it is written, and reports whether it did.
+	protect func(obj *unstructured.Unstructured) (bool, error)
+	// previous maps the paths of an incremental export's previous manifest to their objects.
+	// Objects whose UID and resourceVersion are unchanged are not rewritten.
+	previous map[string]file.ExportedObject
+	pageSize int64
+	// progress is told about each file written.
+	progress *exportProgress
+	log      logrus.FieldLogger
+
+	mu       sync.Mutex
+	errs     []error
+	fatal    error
+	written  int
+	upToDate int
+	secrets  int
+	// releases holds the latest revision of each Helm release whose Secret was streamed,
+	// decoded before the Secret is encrypted or redacted.
+	releases map[string]*helm.Release
+}
+
+// streamWriter returns a writer for this export; previous is the incremental export's
+// previous manifest, if any.
+func (o *ExportOptions) streamWriter(layout exportLayout, previous *file.ExportManifest, log logrus.FieldLogger) *streamWriter {
+	w := &streamWriter{layout: layout, pageSize: streamPageSize, log: log, releases: map[string]*helm.Release{}}
+	if o.secretsMode() != "" {
+		now := time.Now()
+		w.protect = func(obj *unstructured.Unstructured) (bool, error) { return o.protectSecret(obj, now, log) }
+	}
+	if previous != nil {
+		w.previous = map[string]file.ExportedObject{}
+		for _, r := range previous.Resources {
+			for _, obj := range r.Objects {
+				w.previous[obj.Path] = obj
+			}
+		}
+	}
+	return w
+}
+
+// indexEntry returns the parts of obj kept in memory after it was written. The Helm
+// release recorded on obj is kept too, so the file can be annotated with it later.
+func indexEntry(obj unstructured.Unstructured) unstructured.Unstructured {
+	entry := unstructured.Unstructured{Object: map[string]interface{}{}}
+	entry.SetAPIVersion(obj.GetAPIVersion())
+	entry.SetKind(obj.GetKind())
+	entry.SetNamespace(obj.GetNamespace())
+	entry.SetName(obj.GetName())
+	entry.SetUID(obj.GetUID())
+	entry.SetResourceVersion(obj.GetResourceVersion())
+	if refs := obj.GetOwnerReferences(); len(refs) > 0 {
+		entry.SetOwnerReferences(refs)
+	}
+	if helm.IsReleaseSecret(obj) {
+		entry.Object["type"] = helm.ReleaseSecretType
+		entry.SetLabels(map[string]string{"name": obj.GetLabels()["name"]})
+	} else if helm.RecordedRelease(obj) != "" {
+		helmAnnotations := map[string]string{}
+		for k, v := range obj.GetAnnotations() {
+			if strings.HasPrefix(k, "meta.helm.sh/") {
+				helmAnnotations[k] = v
+			}
+		}
+		entry.SetAnnotations(helmAnnotations)
+	}
+	return entry
+}
+
+// captureRelease decodes the Helm release stored in obj, a release Secret, and keeps it
+// if it is the latest revision seen. Secrets that cannot be decoded are logged and
+// skipped, as in captureHelmReleases.
+func (w *streamWriter) captureRelease(obj unstructured.Unstructured) {
+	r, err := helm.DecodeSecret(obj)
+	if err != nil {
+		w.log.Warnf("Cannot decode Helm release: %v, continuing", err)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if prev, ok := w.releases[r.Key()]; !ok || r.Revision > prev.Revision {
+		w.releases[r.Key()] = r
+	}
+}
+
+// helmReleases returns the Helm releases captured from streamed Secrets.
+func (w *streamWriter) helmReleases() []*helm.Release {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	releases := make([]*helm.Release, 0, len(w.releases))
+	for _, r := range w.releases {
+		releases = append(releases, r)
+	}
+	return releases
+}
+
+// write writes obj unless an incremental export already holds it, and reports whether
+// the file was written. Write errors are collected and do not stop the export, as in
+// writeResources; a Secret that cannot be protected is fatal.
+func (w *streamWriter) write(obj *unstructured.Unstructured) (bool, error) {
+	// Helm releases are decoded before the Secrets holding them are protected.
+	if helm.IsReleaseSecret(*obj) {
+		w.captureRelease(*obj)
+	}
+	path := w.layout.objectPath(*obj)
+	fullPath := filepath.Join(w.layout.exportDir, filepath.FromSlash(path))
+	if prev, ok := w.previous[path]; ok && prev.UID == string(obj.GetUID()) && prev.ResourceVersion == obj.GetResourceVersion() && fileExists(fullPath) {
+		w.mu.Lock()
+		w.upToDate++
+		w.mu.Unlock()
+		return false, nil
+	}
+	if w.protect != nil {
+		protected, err := w.protect(obj)
+		if err != nil {
+			w.mu.Lock()
+			if w.fatal == nil {
+				w.fatal = err
+			}
+			w.mu.Unlock()
+			return false, err
+		}
+		if protected {
+			w.mu.Lock()
+			w.secrets++
+			w.mu.Unlock()
+		}
+	}
+
+	data, err := yaml.Marshal(obj.Object)
+	if err == nil {
+		err = os.WriteFile(fullPath, data, 0666)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.errs = append(w.errs, err)
+		return false, nil
+	}
+	w.written++
+	w.progress.fileWritten(fullPath, *obj)
+	return true, nil
+}
+
+// removeWritten deletes files written for a list that has to be restarted, so objects
+// deleted in the meantime are not left behind.
+func (w *streamWriter) removeWritten(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(filepath.Join(w.layout.exportDir, filepath.FromSlash(path))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			w.mu.Lock()
+			w.errs = append(w.errs, err)
+			w.mu.Unlock()
+		}
+	}
+}
+
+// listFunc returns the list call extractResources uses to stream g in namespace.
+func (w *streamWriter) listFunc(requestTimeout time.Duration, namespace, labelSelector string, d dynamic.Interface, log logrus.FieldLogger) listFunc {
+	return func(g *groupResource) (*unstructured.UnstructuredList, error) {
+		return w.listObjects(requestTimeout, g, namespace, labelSelector, d, log)
+	}
+}
+
+// listObjects lists g page by page like getObjects, writing each object as soon as its page
+// arrives, and returns the index entries. g is marked streamed and its cluster references
+// are recorded. If the continue token expires while paging, the list is restarted once from
+// the beginning.
+func (w *streamWriter) listObjects(requestTimeout time.Duration, g *groupResource, namespace, labelSelector string, d dynamic.Interface, log logrus.FieldLogger) (*unstructured.UnstructuredList, error) {
+	c := d.Resource(schema.GroupVersionResource{
+		Group:    g.APIGroup,
+		Version:  g.APIVersion,
+		Resource: g.APIResource.Name,
+	})
+	var client dynamic.ResourceInterface = c
+	opts := metav1.ListOptions{Limit: w.pageSize}
+	if g.APIResource.Namespaced {
+		client = c.Namespace(namespace)
+		opts.LabelSelector = labelSelector
+	}
+
+	index := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{}}
+	var refs []clusterReference
+	var written []string
+	restarted := false
+	for {
+		ctx := context.Background()
+		cancel := func() {}
+		if requestTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		}
+		page, err := client.List(ctx, opts)
+		cancel()
+		if err != nil {
+			if apierrors.IsResourceExpired(err) && opts.Continue != "" && !restarted {
+				log.Warnf("List of %s in namespace %q expired after %d objects; restarting it", g.APIResource.Name, namespace, len(index.Items))
+				w.removeWritten(written)
+				restarted = true
+				opts.Continue = ""
+				index = &unstructured.UnstructuredList{Items: []unstructured.Unstructured{}}
+				refs, written = nil, nil
+				continue
+			}
+			return nil, err
+		}
+		if index.GetResourceVersion() == "" {
+			index.SetResourceVersion(page.GetResourceVersion())
+		}
+
+		items := page
+		if g.APIResource.Name == "imagestreamtags" || g.APIResource.Name == "imagetags" {
+			if items, err = iterateItemsByGet(requestTimeout, c, g, page, namespace, log); err != nil {
+				return nil, err
+			}
+		}
+		for i := range items.Items {
+			obj := &items.Items[i]
+			refs = append(refs, clusterReferencesOf(*obj)...)
+			wrote, err := w.write(obj)
+			if err != nil {
+				return nil, fmt.Errorf("protect Secret %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			}
+			if wrote {
+				written = append(written, w.layout.objectPath(*obj))
+			}
+			index.Items = append(index.Items, indexEntry(*obj))
+		}
+		log.Debugf("Streamed %d %s in namespace %q", len(index.Items), g.APIResource.Name, namespace)
+
+		if page.GetContinue() == "" {
+			break
+		}
+		opts.Continue = page.GetContinue()
+	}
+	g.streamed = true
+	g.refs = refs
+	return index, nil
+}
+
+// errors returns the write errors collected so far.
+func (w *streamWriter) errors() []error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]error{}, w.errs...)
+}
+
+// err returns the first error that must abort the export.
+func (w *streamWriter) err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fatal
+}
+
+// removePrunedFiles deletes the manifests of objects pruned by --skip-owned after they
+// were streamed to disk.
+func removePrunedFiles(layout exportLayout, pruned []prunedObject) []error {
+	paths := make([]string, 0, len(pruned))
+	for _, p := range pruned {
+		obj := unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetAPIVersion(p.APIVersion)
+		obj.SetKind(p.Kind)
+		obj.SetNamespace(p.Namespace)
+		obj.SetName(p.Name)
+		paths = append(paths, layout.objectPath(obj))
+	}
+	return removeStaleManifests(layout.exportDir, paths)
+}
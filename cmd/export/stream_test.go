@@ -0,0 +1,216 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/crane/internal/file"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+func streamedConfigMap(name string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            name,
+			"namespace":       "app",
+			"uid":             "uid-" + name,
+			"resourceVersion": "1",
+		},
+		"data": map[string]interface{}{"key": strings.Repeat("x", 64)},
+	}}
+	return obj
+}
+
+// pagingClient serves the ConfigMaps in app two per page. When expireOnce is set, the
+// first request with a continue token fails with 410 Expired.
+func pagingClient(t *testing.T, names []string, expireOnce bool) (*dynamicfake.FakeDynamicClient, *int) {
+	t.Helper()
+	client := dynamicfake.NewSimpleDynamicClient(clientgoscheme.Scheme)
+	calls := 0
+	client.PrependReactor("list", "configmaps", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		calls++
+		opts := action.(kubetesting.ListActionImpl).GetListOptions()
+		if opts.Limit != 2 {
+			t.Errorf("page limit = %d, want 2", opts.Limit)
+		}
+		if opts.Continue != "" && expireOnce {
+			expireOnce = false
+			return true, nil, apierrors.NewResourceExpired("continue token expired")
+		}
+		start := 0
+		if opts.Continue != "" {
+			start = len(opts.Continue)
+		}
+		end := start + 2
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMapList"}}
+		list.SetResourceVersion("42")
+		if end < len(names) {
+			list.SetContinue(strings.Repeat("c", end))
+		} else {
+			end = len(names)
+		}
+		for _, name := range names[start:end] {
+			list.Items = append(list.Items, streamedConfigMap(name))
+		}
+		return true, list, nil
+	})
+	return client, &calls
+}
+
+func configMapsGroupResource() *groupResource {
+	return &groupResource{
+		APIVersion:      "v1",
+		APIGroupVersion: "v1",
+		APIResource:     metav1.APIResource{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+	}
+}
+
+func TestStreamWriter_listObjects(t *testing.T) {
+	tests := []struct {
+		name       string
+		expireOnce bool
+		wantCalls  int
+	}{
+		{name: "pages", wantCalls: 3},
+		{name: "restarts after expired continue token", expireOnce: true, wantCalls: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportDir := t.TempDir()
+			layout := exportLayout{exportDir: exportDir, namespaces: []string{"app"}}
+			if err := os.MkdirAll(layout.resourceDir("app"), 0700); err != nil {
+				t.Fatal(err)
+			}
+			client, calls := pagingClient(t, []string{"a", "b", "c", "d", "e"}, tt.expireOnce)
+			w := &streamWriter{layout: layout, pageSize: 2}
+			g := configMapsGroupResource()
+
+			index, err := w.listObjects(0, g, "app", "", client, testLogger())
+			if err != nil {
+				t.Fatalf("listObjects: %v", err)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("list calls = %d, want %d", *calls, tt.wantCalls)
+			}
+			if !g.streamed || len(index.Items) != 5 || index.GetResourceVersion() != "42" {
+				t.Fatalf("streamed = %v, %d index entries, resourceVersion %q", g.streamed, len(index.Items), index.GetResourceVersion())
+			}
+			for _, entry := range index.Items {
+				if _, ok := entry.Object["data"]; ok {
+					t.Errorf("index entry %s kept the object data", entry.GetName())
+				}
+				if entry.GetUID() != types.UID("uid-"+entry.GetName()) {
+					t.Errorf("index entry %s lost its UID", entry.GetName())
+				}
+				data, err := os.ReadFile(filepath.Join(exportDir, filepath.FromSlash(layout.objectPath(entry))))
+				if err != nil {
+					t.Fatalf("streamed file: %v", err)
+				}
+				if !strings.Contains(string(data), "key: xxx") {
+					t.Errorf("streamed file for %s is missing data:\n%s", entry.GetName(), data)
+				}
+			}
+			if w.written != 5+map[bool]int{true: 2}[tt.expireOnce] || len(w.errors()) != 0 {
+				t.Errorf("written = %d, errors = %v", w.written, w.errors())
+			}
+		})
+	}
+}
+
+func TestStreamWriter_skipsUpToDateObjects(t *testing.T) {
+	exportDir := t.TempDir()
+	layout := exportLayout{exportDir: exportDir, namespaces: []string{"app"}}
+	if err := os.MkdirAll(layout.resourceDir("app"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	obj := streamedConfigMap("a")
+	path := layout.objectPath(obj)
+	if err := os.WriteFile(filepath.Join(exportDir, path), []byte("previous"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w := &streamWriter{layout: layout, previous: map[string]file.ExportedObject{path: {Path: path, UID: "uid-a", ResourceVersion: "1"}}}
+	if wrote, err := w.write(&obj); err != nil || wrote {
+		t.Fatalf("write(unchanged) = %v, %v", wrote, err)
+	}
+	obj.SetResourceVersion("2")
+	if wrote, err := w.write(&obj); err != nil || !wrote {
+		t.Fatalf("write(changed) = %v, %v", wrote, err)
+	}
+	if w.upToDate != 1 || w.written != 1 {
+		t.Errorf("upToDate = %d, written = %d", w.upToDate, w.written)
+	}
+}
+
+func exportedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() == file.ExportManifestFileName {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestRun_StreamMatchesBufferedExport(t *testing.T) {
+	filter, err := newResourceFilter(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := writeOfflineDump(t)
+	run := func(stream bool) (string, *file.ExportManifest) {
+		exportDir := filepath.Join(t.TempDir(), "export")
+		o := &ExportOptions{
+			exportDir:               exportDir,
+			fromFile:                dump,
+			namespaces:              []string{"app"},
+			resourceFilter:          filter,
+			followClusterReferences: true,
+			stream:                  stream,
+		}
+		if err := o.run(); err != nil {
+			t.Fatalf("run(stream=%v): %v", stream, err)
+		}
+		manifest, err := file.ReadExportManifest(exportDir)
+		if err != nil || manifest == nil {
+			t.Fatalf("ReadExportManifest: %v, %v", manifest, err)
+		}
+		return exportDir, manifest
+	}
+
+	bufferedDir, buffered := run(false)
+	streamedDir, streamed := run(true)
+	if got, want := exportedFiles(t, streamedDir), exportedFiles(t, bufferedDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed files = %v\nwant %v", got, want)
+	}
+	for _, f := range exportedFiles(t, bufferedDir) {
+		want, _ := os.ReadFile(filepath.Join(bufferedDir, f))
+		got, _ := os.ReadFile(filepath.Join(streamedDir, f))
+		if string(got) != string(want) {
+			t.Errorf("%s differs:\n%s\nwant:\n%s", f, got, want)
+		}
+	}
+	if !reflect.DeepEqual(streamed.Resources, buffered.Resources) {
+		t.Errorf("streamed manifest resources = %+v\nwant %+v", streamed.Resources, buffered.Resources)
+	}
+}
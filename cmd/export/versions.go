@@ -0,0 +1,224 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+// alternateVersionsAll is the --alternate-versions value that selects every served version.
+const alternateVersionsAll = "all"
+
+// versionSelection is the parsed --alternate-versions flag.
+type versionSelection struct {
+	all      bool
+	versions []schema.GroupVersion
+}
+
+// parseAlternateVersions parses --alternate-versions: "all", or group/version entries such
+// as autoscaling/v2 ("v1" for the core group).
+func parseAlternateVersions(values []string) (versionSelection, error) {
+	var s versionSelection
+	for _, v := range values {
+		if v == alternateVersionsAll {
+			s.all = true
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(v)
+		if err != nil || gv.Version == "" {
+			return s, fmt.Errorf("--alternate-versions entry %q is not %q or group/version", v, alternateVersionsAll)
+		}
+		s.versions = append(s.versions, gv)
+	}
+	if s.all && len(s.versions) > 0 {
+		return s, fmt.Errorf("--alternate-versions=%s cannot be combined with explicit versions", alternateVersionsAll)
+	}
+	return s, nil
+}
+
+func (s versionSelection) empty() bool {
+	return !s.all && len(s.versions) == 0
+}
+
+func (s versionSelection) selects(gv schema.GroupVersion) bool {
+	if s.all {
+		return true
+	}
+	for _, v := range s.versions {
+		if v == gv {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverServedResources returns the API resource lists of every served group version,
+// filtered to types that support list and get. Partial discovery failures are handled as in
+// discoverPreferredResources.
+func discoverServedResources(discoveryClient discovery.DiscoveryInterface, log logrus.FieldLogger) ([]*metav1.APIResourceList, error) {
+	_, lists, err := discoveryClient.ServerGroupsAndResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) || len(lists) == 0 {
+			log.Errorf("Failed to discover served resources: %v", err)
+			return nil, err
+		}
+		log.Warnf("Some API groups failed discovery, continuing with available groups: %v", err)
+	}
+	return discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "get"}}, lists), nil
+}
+
+// alternateVersion is an exported resource type to list again at another served version.
+// names holds the namespace/name of every object exported for it in namespace ("" for
+// cluster-scoped objects).
+type alternateVersion struct {
+	namespace string
+	g         *groupResource
+	names     map[string]bool
+}
+
+// alternateVersionsOf returns one alternateVersion per exported resource type, namespace,
+// and other served version of its group that selection picks, sorted by namespace and
+// group version. Explicitly selected versions that are not served are logged.
+func alternateVersionsOf(selection versionSelection, exported map[string][]*groupResource, served []*metav1.APIResourceList, log logrus.FieldLogger) []*alternateVersion {
+	servedGroupVersions := map[schema.GroupVersion]bool{}
+	byKey := map[string]*alternateVersion{}
+	for _, list := range served {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || !selection.selects(gv) {
+			continue
+		}
+		servedGroupVersions[gv] = true
+		for namespace, resources := range exported {
+			for _, g := range resources {
+				if g == nil || g.objects == nil || len(g.objects.Items) == 0 || g.APIGroup != gv.Group || g.APIVersion == gv.Version {
+					continue
+				}
+				for _, resource := range list.APIResources {
+					if resource.Name != g.APIResource.Name {
+						continue
+					}
+					key := namespace + "/" + gv.String() + "/" + resource.Name
+					alt, ok := byKey[key]
+					if !ok {
+						alt = &alternateVersion{
+							namespace: namespace,
+							g: &groupResource{
+								APIGroup:        gv.Group,
+								APIVersion:      gv.Version,
+								APIGroupVersion: gv.String(),
+								APIResource:     resource,
+							},
+							names: map[string]bool{},
+						}
+						byKey[key] = alt
+					}
+					for _, obj := range g.objects.Items {
+						alt.names[obj.GetNamespace()+"/"+obj.GetName()] = true
+					}
+				}
+			}
+		}
+	}
+	for _, gv := range selection.versions {
+		if !servedGroupVersions[gv] {
+			log.Warnf("API version %q is not served by the source; no objects are exported at it", gv.String())
+		}
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	alts := make([]*alternateVersion, 0, len(keys))
+	for _, key := range keys {
+		alts = append(alts, byKey[key])
+	}
+	return alts
+}
+
+// exportAlternateVersions is --alternate-versions: it lists every exported resource type
+// again at the other served versions selected, and writes the objects that were exported
+// under versions/<group>/<version>/ in the same layout as the export root. Only objects
+// already exported are kept, so resource filters, the RBAC filter, and --skip-owned apply
+// unchanged. The versions directory is rewritten on every run. A list that fails does not
+// fail the export; it is returned as skipped.
+func (o *ExportOptions) exportAlternateVersions(source exportSource, d dynamic.Interface, namespaces []string, exported map[string][]*groupResource, log logrus.FieldLogger) ([]file.ExportedResource, []file.SkippedResource, []error) {
+	selection, err := parseAlternateVersions(o.alternateVersions)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if selection.empty() {
+		return nil, nil, nil
+	}
+	if err := os.RemoveAll(filepath.Join(o.exportDir, file.VersionsDirName)); err != nil {
+		return nil, nil, []error{err}
+	}
+	served, err := source.servedResources(log)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	now := time.Now()
+	var resources []file.ExportedResource
+	var skipped []file.SkippedResource
+	var errs []error
+	for _, alt := range alternateVersionsOf(selection, exported, served, log) {
+		list, err := getObjects(source.requestTimeout(), alt.g, alt.namespace, o.labelSelector, d, log)
+		if err != nil {
+			log.Warnf("Cannot list %s at %s: %v", groupResourceName(alt.g.APIGroup, alt.g.APIResource.Name), alt.g.APIGroupVersion, err)
+			s := alt.g.skipped(file.SkipReasonListError, err.Error())
+			s.Namespace = alt.namespace
+			skipped = append(skipped, s)
+			continue
+		}
+		objects := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{}}
+		objects.SetResourceVersion(list.GetResourceVersion())
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if !alt.names[obj.GetNamespace()+"/"+obj.GetName()] {
+				continue
+			}
+			if _, err := o.protectSecret(obj, now, log); err != nil {
+				return nil, nil, []error{err}
+			}
+			objects.Items = append(objects.Items, *obj)
+		}
+		if len(objects.Items) == 0 {
+			continue
+		}
+		alt.g.objects = objects
+
+		versionDir := file.AlternateVersionDir(alt.g.APIGroup, alt.g.APIVersion)
+		layout := exportLayout{exportDir: filepath.Join(o.exportDir, filepath.FromSlash(versionDir)), namespaces: namespaces}
+		dir := layout.clusterResourceDir()
+		if alt.namespace != "" {
+			dir = layout.resourceDir(alt.namespace)
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, writeResources([]*groupResource{alt.g}, layout.clusterResourceDir(), layout.resourceDir(alt.namespace), log)...)
+		for _, r := range buildExportedResources(layout, []*groupResource{alt.g}) {
+			for i := range r.Objects {
+				r.Objects[i].Path = path.Join(versionDir, r.Objects[i].Path)
+			}
+			resources = append(resources, r)
+		}
+		log.Debugf("Exported %d %s at %s", len(objects.Items), alt.g.APIResource.Name, alt.g.APIGroupVersion)
+	}
+	sortExportedResources(resources)
+	return resources, skipped, errs
+}
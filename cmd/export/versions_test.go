@@ -0,0 +1,132 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestParseAlternateVersions(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    []schema.GroupVersion
+		all     bool
+		wantErr string
+	}{
+		{values: nil},
+		{values: []string{"all"}, all: true},
+		{values: []string{"autoscaling/v2", "v1"}, want: []schema.GroupVersion{{Group: "autoscaling", Version: "v2"}, {Version: "v1"}}},
+		{values: []string{"autoscaling/"}, wantErr: "is not"},
+		{values: []string{"a/b/c"}, wantErr: "is not"},
+		{values: []string{"all", "apps/v1"}, wantErr: "cannot be combined"},
+	}
+	for _, tt := range tests {
+		got, err := parseAlternateVersions(tt.values)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseAlternateVersions(%v) error = %v, want %q", tt.values, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("parseAlternateVersions(%v): %v", tt.values, err)
+		}
+		if got.all != tt.all || len(got.versions) != len(tt.want) {
+			t.Fatalf("parseAlternateVersions(%v) = %+v", tt.values, got)
+		}
+		for i := range tt.want {
+			if got.versions[i] != tt.want[i] {
+				t.Errorf("parseAlternateVersions(%v)[%d] = %v, want %v", tt.values, i, got.versions[i], tt.want[i])
+			}
+		}
+	}
+}
+
+// servedSource is an exportSource that only answers servedResources and requestTimeout.
+type servedSource struct {
+	exportSource
+	served []*metav1.APIResourceList
+}
+
+func (s *servedSource) servedResources(logrus.FieldLogger) ([]*metav1.APIResourceList, error) {
+	return s.served, nil
+}
+
+func (s *servedSource) requestTimeout() time.Duration { return 0 }
+
+func hpa(apiVersion, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "HorizontalPodAutoscaler",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "app"},
+		"spec":       map[string]interface{}{"maxReplicas": int64(3)},
+	}}
+}
+
+func TestExportAlternateVersions(t *testing.T) {
+	hpaResource := metav1.APIResource{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler", Namespaced: true, Verbs: metav1.Verbs{"list", "get"}}
+	served := []*metav1.APIResourceList{
+		{GroupVersion: "autoscaling/v2", APIResources: []metav1.APIResource{hpaResource}},
+		{GroupVersion: "autoscaling/v1", APIResources: []metav1.APIResource{hpaResource}},
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}}},
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "autoscaling", Version: "v1", Resource: "horizontalpodautoscalers"}: "HorizontalPodAutoscalerList",
+		{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}: "HorizontalPodAutoscalerList",
+	}, hpa("autoscaling/v1", "web"), hpa("autoscaling/v1", "pruned"), hpa("autoscaling/v2", "web"))
+
+	exported := map[string][]*groupResource{
+		"app": {{
+			APIGroup:        "autoscaling",
+			APIVersion:      "v2",
+			APIGroupVersion: "autoscaling/v2",
+			APIResource:     hpaResource,
+			objects:         &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*hpa("autoscaling/v2", "web")}},
+		}},
+	}
+
+	for _, values := range [][]string{{"all"}, {"autoscaling/v1"}} {
+		exportDir := t.TempDir()
+		o := &ExportOptions{exportDir: exportDir, alternateVersions: values}
+		resources, skipped, errs := o.exportAlternateVersions(&servedSource{served: served}, client, []string{"app"}, exported, testLogger())
+		if len(errs) != 0 || len(skipped) != 0 {
+			t.Fatalf("exportAlternateVersions(%v): errs %v, skipped %v", values, errs, skipped)
+		}
+		if len(resources) != 1 || resources[0].Version != "v1" || resources[0].Count != 1 {
+			t.Fatalf("exportAlternateVersions(%v) = %+v", values, resources)
+		}
+		want := "versions/autoscaling/v1/resources/app/HorizontalPodAutoscaler_autoscaling_v1_app_web.yaml"
+		if got := resources[0].Objects[0].Path; got != want {
+			t.Errorf("path = %q, want %q", got, want)
+		}
+		data, err := os.ReadFile(filepath.Join(exportDir, filepath.FromSlash(want)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "apiVersion: autoscaling/v1") {
+			t.Errorf("alternate version file:\n%s", data)
+		}
+		// Reading the export root skips versions/, so transform does not see duplicates.
+		files, err := file.ReadFiles(context.TODO(), exportDir)
+		if err != nil || len(files) != 0 {
+			t.Errorf("ReadFiles(export root) = %d files, %v", len(files), err)
+		}
+	}
+
+	o := &ExportOptions{exportDir: t.TempDir(), alternateVersions: []string{"autoscaling/v2beta2"}}
+	resources, _, errs := o.exportAlternateVersions(&servedSource{served: served}, client, []string{"app"}, exported, testLogger())
+	if len(resources) != 0 || len(errs) != 0 {
+		t.Errorf("unserved version: resources %+v, errs %v", resources, errs)
+	}
+}
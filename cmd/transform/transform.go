@@ -3,6 +3,7 @@ package transform
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -12,6 +13,8 @@ import (
 	cranelib "github.com/konveyor/crane-lib/transform"
 	"github.com/konveyor/crane/cmd/transform/listplugins"
 	"github.com/konveyor/crane/cmd/transform/optionals"
+	"github.com/konveyor/crane/internal/archive"
+	"github.com/konveyor/crane/internal/buildinfo"
 	"github.com/konveyor/crane/internal/file"
 	"github.com/konveyor/crane/internal/flags"
 	"github.com/konveyor/crane/internal/kustomize"
@@ -42,12 +45,16 @@ type Options struct {
 
 type Flags struct {
 	ExportDir         string   `mapstructure:"export-dir"`
+	ExportArchive     string   `mapstructure:"export-archive"`
 	PluginDir         string   `mapstructure:"plugin-dir"`
 	TransformDir      string   `mapstructure:"transform-dir"`
 	SkipPlugins       []string `mapstructure:"skip-plugins"`
 	OptionalFlags     string   `mapstructure:"optional-flags"`
 	StageOptionals    []string `mapstructure:"stage-optionals"`
 	Overwrite         bool     `mapstructure:"overwrite"`
+	Concurrency       int      `mapstructure:"concurrency"`
+	StaleOnly         bool     `mapstructure:"stale-only"`
+	Merge             bool     `mapstructure:"merge"`
 	// Kustomize arguments
 	KustomizeArgs string `mapstructure:"kustomize-args"`
 	// Instructions file
@@ -64,6 +71,19 @@ func (o *Options) Complete(c *cobra.Command, args []string) error {
 func (o *Options) Validate() error {
 	log := o.globalFlags.GetLoggerOrDefault()
 
+	if o.ExportArchive != "" {
+		info, err := os.Stat(o.ExportArchive)
+		if err != nil {
+			log.Debugf("Export archive %q: %v", o.ExportArchive, err)
+			return fmt.Errorf("export-archive %q: %w", o.ExportArchive, err)
+		}
+		if !info.Mode().IsRegular() {
+			log.Debugf("Export archive %q is not a regular file", o.ExportArchive)
+			return fmt.Errorf("export-archive %q is not a file", o.ExportArchive)
+		}
+		return nil
+	}
+
 	exportDir, err := filepath.Abs(o.ExportDir)
 	if err != nil {
 		log.Debugf("Failed to resolve export-dir %q: %v", o.ExportDir, err)
@@ -164,9 +184,13 @@ func addFlagsForOptions(o *Flags, cmd *cobra.Command) {
 	home := os.Getenv("HOME")
 	defaultPluginDir := home + plugin.DefaultLocalPluginDir
 	cmd.Flags().StringVarP(&o.ExportDir, "export-dir", "e", "export", "The path where the kubernetes resources are saved")
+	cmd.Flags().StringVar(&o.ExportArchive, "export-archive", "", "Read the exported resources from an archive written by crane export --output-archive instead of --export-dir")
 	cmd.Flags().StringVarP(&o.TransformDir, "transform-dir", "t", "transform", "The path where files that contain the transformations are saved")
 	cmd.Flags().StringVar(&o.InstructionsFile, "instructions-file", "", "Path to the transform instructions file")
 	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", false, "Overwrite existing stage directories even if they contain user modifications")
+	cmd.Flags().BoolVar(&o.Merge, "merge", false, "Regenerate existing stages and three-way merge hand edits to patches and kustomization.yaml into the new plugin output; overlapping edits are written to .conflict files")
+	cmd.Flags().BoolVar(&o.StaleOnly, "stale-only", false, "Regenerate only stages whose input, plugin, or optional flags changed since they were generated; keep up-to-date stages as they are")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 1, "Number of resources a stage runs its plugin on in parallel (0 or 1 runs them one at a time)")
 
 	cmd.Flags().StringVar(&o.OptionalFlags, "optional-flags", "", "JSON string holding flag value pairs to be passed to all plugins (e.g. '{\"registry-replacement\": \"docker.io=quay.io\"}')")
 	cmd.Flags().StringArrayVar(&o.StageOptionals, "stage-optionals", nil, "Per-stage optional flags as StageName=JSON, repeatable (e.g. --stage-optionals 'KubernetesPlugin={\"registry-replacement\":\"docker.io=quay.io\"}')")
@@ -190,6 +214,25 @@ func (o *Options) run() error {
 		return err
 	}
 
+	var exportFS fs.FS
+	if o.ExportArchive != "" {
+		// The archive replaces the export directory; it is read in memory, not unpacked.
+		exportDir, err = filepath.Abs(o.ExportArchive)
+		if err != nil {
+			log.Errorf("Failed to resolve export archive path %q: %v", o.ExportArchive, err)
+			return err
+		}
+		exportFS, err = archive.Open(exportDir)
+		if err != nil {
+			log.Errorf("Failed to open export archive: %v", err)
+			return err
+		}
+		log.Infof("Reading exported resources from archive %s", exportDir)
+		checkExportManifest(exportFS, exportDir, log)
+	} else {
+		checkExportManifest(os.DirFS(exportDir), exportDir, log)
+	}
+
 	pluginDir, err := filepath.Abs(o.PluginDir)
 	if err != nil {
 		log.Errorf("Failed to resolve plugin directory path %q: %v", o.PluginDir, err)
@@ -209,8 +252,12 @@ func (o *Options) run() error {
 	if o.InstructionsFile != "" && len(o.StageOptionals) > 0 {
 		return fmt.Errorf("use either --instructions-file or --stage-optionals, not both")
 	}
+	if o.Merge && o.Overwrite {
+		return fmt.Errorf("use either --merge or --overwrite, not both")
+	}
 
 	var instructionStages []string
+	var instructionStageInputs map[string][]string
 	var instructionStageOptionals map[string]map[string]string
 	if o.InstructionsFile != "" {
 		instructionsFilePath, err := filepath.Abs(o.InstructionsFile)
@@ -223,7 +270,16 @@ func (o *Options) run() error {
 			log.Errorf("Failed to load instructions file %q: %v", instructionsFilePath, err)
 			return err
 		}
-		instructionStages = internalTransform.GenerateStageDirNames(cfg.StageNames())
+		graph, err := cfg.StageGraph()
+		if err != nil {
+			return fmt.Errorf("invalid instructions file %q: %w", instructionsFilePath, err)
+		}
+		stageNames := make([]string, len(graph))
+		for i, s := range graph {
+			stageNames[i] = s.Name
+		}
+		instructionStages = internalTransform.GenerateStageDirNames(stageNames)
+		instructionStageInputs = internalTransform.StageDirInputs(graph)
 		instructionStageOptionals, err = cfg.StageOptionals()
 		if err != nil {
 			return fmt.Errorf("invalid instructions file %q: %w", instructionsFilePath, err)
@@ -268,15 +324,20 @@ func (o *Options) run() error {
 	orchestrator := &internalTransform.Orchestrator{
 		Log:                log.WithField("command", "transform").Logger,
 		ExportDir:          exportDir,
+		ExportFS:           exportFS,
 		TransformDir:       transformDir,
 		PluginDir:          pluginDir,
 		SkipPlugins:        o.SkipPlugins,
 		OptionalFlags:      optionalFlags,
 		StageOptionalFlags: stageOptionalFlags,
 		Overwrite:          o.Overwrite,
-		CraneVersion:       "v1.0.0", // TODO: Get from build version
+		CraneVersion:       buildinfo.Version,
 		NewlyCreatedStages: make(map[string]bool),
 		KustomizeArgs:      kustomizeArgs,
+		StageInputs:        instructionStageInputs,
+		Concurrency:        o.Concurrency,
+		StaleOnly:          o.StaleOnly,
+		Merge:              o.Merge,
 	}
 
 	// Determine which stages to run
@@ -385,6 +446,36 @@ func (o *Options) run() error {
 	return nil
 }
 
+// checkExportManifest logs where the export came from and warns about export files that
+// were modified, removed, or added after crane export wrote its manifest. source names the
+// export directory or archive that fsys was opened from. Exports without a manifest (older
+// crane versions) are accepted silently.
+func checkExportManifest(fsys fs.FS, source string, log *logrus.Logger) {
+	manifest, err := file.ReadExportManifestFS(fsys)
+	if err != nil {
+		log.Warnf("Cannot read export manifest in %q: %v", source, err)
+		return
+	}
+	if manifest == nil {
+		log.Debugf("No %s in %q; skipping export provenance checks", file.ExportManifestFileName, source)
+		return
+	}
+	from := manifest.Server
+	if manifest.Source != "" {
+		from = manifest.Source
+	}
+	log.Infof("Export produced by crane %s from %s (context %q, namespaces %s)",
+		manifest.CraneVersion, from, manifest.Context, strings.Join(manifest.Namespaces, ", "))
+	problems, err := file.VerifyExportFilesFS(fsys, manifest)
+	if err != nil {
+		log.Warnf("Cannot verify export files: %v", err)
+		return
+	}
+	for _, p := range problems {
+		log.Warnf("Export file changed after export: %s", p)
+	}
+}
+
 // parseStageOptionals parses --stage-optionals values from "StageName=JSON" format
 // into a map of stage name to optional flags.
 func parseStageOptionals(values []string) (map[string]map[string]string, error) {
@@ -1,12 +1,14 @@
 package transform
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	cranelib "github.com/konveyor/crane-lib/transform"
+	"github.com/konveyor/crane/internal/file"
 	"github.com/konveyor/crane/internal/flags"
 	internalTransform "github.com/konveyor/crane/internal/transform"
 	"github.com/sirupsen/logrus"
@@ -1088,6 +1090,42 @@ func TestValidate_ExportDir(t *testing.T) {
 	}
 }
 
+func TestValidate_ExportArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	archivePath := filepath.Join(tmpDir, "export.tar.gz")
+	if err := os.WriteFile(archivePath, []byte("x"), 0o644); err != nil {
+		t.Fatalf("failed to create archive fixture: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		archive string
+		wantErr string
+	}{
+		{name: "missing archive", archive: filepath.Join(tmpDir, "missing.tar.gz"), wantErr: "export-archive"},
+		{name: "archive is directory", archive: tmpDir, wantErr: "is not a file"},
+		{name: "valid archive ignores missing export dir", archive: archivePath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{Flags: Flags{
+				ExportDir:     filepath.Join(tmpDir, "missing-export"),
+				ExportArchive: tt.archive,
+			}}
+			err := o.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestParseStageOptionals(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -1273,3 +1311,40 @@ func TestValidate_MissingExportDir_FailsBeforeRun(t *testing.T) {
 		t.Fatalf("expected transform dir to not exist after validation failure, got stat err: %v", statErr)
 	}
 }
+
+func TestCheckExportManifest_WarnsOnModifiedFiles(t *testing.T) {
+	exportDir := t.TempDir()
+	cmPath := filepath.Join(exportDir, "resources", "app", "cm.yaml")
+	if err := os.MkdirAll(filepath.Dir(cmPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cmPath, []byte("kind: ConfigMap\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	digests, err := file.DigestExportFiles(exportDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := &file.ExportManifest{CraneVersion: "v0.1.0", Namespaces: []string{"app"}, Files: digests}
+	if err := file.WriteExportManifest(exportDir, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&out)
+
+	checkExportManifest(os.DirFS(exportDir), exportDir, log)
+	if strings.Contains(out.String(), "changed after export") {
+		t.Fatalf("unexpected warning for untouched export: %s", out.String())
+	}
+
+	if err := os.WriteFile(cmPath, []byte("kind: ConfigMap\ndata: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	checkExportManifest(os.DirFS(exportDir), exportDir, log)
+	if !strings.Contains(out.String(), "resources/app/cm.yaml: modified since export") {
+		t.Fatalf("expected modification warning, got: %s", out.String())
+	}
+}
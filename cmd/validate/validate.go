@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/konveyor/crane/internal/archive"
 	"github.com/konveyor/crane/internal/flags"
 	internalValidate "github.com/konveyor/crane/internal/validate"
 	"github.com/sirupsen/logrus"
@@ -23,6 +24,7 @@ type ValidateOptions struct {
 	log              *logrus.Logger
 
 	inputDir         string
+	exportArchive    string
 	validateDir      string
 	outputFormat     string
 	apiResourcesFile string
@@ -89,14 +91,30 @@ func determineClusterContext(cf *genericclioptions.ConfigFlags) (string, error)
 func (o *ValidateOptions) Validate(cmd *cobra.Command) error {
 	log := o.globalFlags.GetLoggerOrDefault()
 
-	info, err := os.Stat(o.inputDir)
-	if err != nil {
-		log.Debugf("Input directory %q: %v", o.inputDir, err)
-		return fmt.Errorf("input-dir %q: %w", o.inputDir, err)
-	}
-	if !info.IsDir() {
-		log.Debugf("Input path %q is not a directory", o.inputDir)
-		return fmt.Errorf("input-dir %q is not a directory", o.inputDir)
+	if o.exportArchive != "" {
+		if cmd.Flags().Changed("input-dir") {
+			log.Debugf("--export-archive and --input-dir are mutually exclusive")
+			return fmt.Errorf("--export-archive and --input-dir are mutually exclusive")
+		}
+		info, err := os.Stat(o.exportArchive)
+		if err != nil {
+			log.Debugf("Export archive %q: %v", o.exportArchive, err)
+			return fmt.Errorf("export-archive %q: %w", o.exportArchive, err)
+		}
+		if !info.Mode().IsRegular() {
+			log.Debugf("Export archive %q is not a regular file", o.exportArchive)
+			return fmt.Errorf("export-archive %q is not a file", o.exportArchive)
+		}
+	} else {
+		info, err := os.Stat(o.inputDir)
+		if err != nil {
+			log.Debugf("Input directory %q: %v", o.inputDir, err)
+			return fmt.Errorf("input-dir %q: %w", o.inputDir, err)
+		}
+		if !info.IsDir() {
+			log.Debugf("Input path %q is not a directory", o.inputDir)
+			return fmt.Errorf("input-dir %q is not a directory", o.inputDir)
+		}
 	}
 
 	o.outputFormat = strings.ToLower(o.outputFormat)
@@ -147,7 +165,20 @@ func (o *ValidateOptions) Run() error {
 	log := o.globalFlags.GetLoggerOrDefault()
 
 	log.Infof("Starting validate")
-	log.Debugf("Input directory: %s", o.inputDir)
+	source := o.inputDir
+	scanOpts := internalValidate.ScanOptions{Dirs: []string{o.inputDir}}
+	if o.exportArchive != "" {
+		fsys, err := archive.Open(o.exportArchive)
+		if err != nil {
+			log.Errorf("Failed to open export archive: %v", err)
+			return err
+		}
+		source = o.exportArchive
+		scanOpts = internalValidate.ScanOptions{FS: fsys, FSName: o.exportArchive}
+		log.Debugf("Export archive: %s", o.exportArchive)
+	} else {
+		log.Debugf("Input directory: %s", o.inputDir)
+	}
 	log.Debugf("Validate directory: %s", o.validateDir)
 	log.Debugf("Output format: %s", o.outputFormat)
 	if o.apiResourcesFile != "" {
@@ -156,7 +187,7 @@ func (o *ValidateOptions) Run() error {
 		log.Debugf("Mode: live")
 	}
 
-	entries, err := internalValidate.ScanManifests(internalValidate.ScanOptions{Dirs: []string{o.inputDir}}, log)
+	entries, err := internalValidate.ScanManifests(scanOpts, log)
 	if err != nil {
 		return fmt.Errorf("scanning manifests: %w", err)
 	}
@@ -164,8 +195,8 @@ func (o *ValidateOptions) Run() error {
 	log.Infof("Scanned %d distinct GVK+namespace tuples", len(entries))
 
 	if len(entries) == 0 {
-		log.Debugf("No manifests found in %q: nothing to validate", o.inputDir)
-		return fmt.Errorf("no manifests found in %s: nothing to validate", o.inputDir)
+		log.Debugf("No manifests found in %q: nothing to validate", source)
+		return fmt.Errorf("no manifests found in %s: nothing to validate", source)
 	}
 
 	var report *internalValidate.ValidationReport
@@ -276,6 +307,9 @@ GVK matching).
 
 Pipeline: export → transform → apply → validate
 
+Use --export-archive to check an export archive written by crane export
+--output-archive directly, without unpacking it.
+
 Use --api-resources to validate offline against a captured API surface
 JSON file (produced by scripts/capture-api-surface.sh) when the target
 cluster is not directly reachable. Otherwise, supply kubeconfig/context
@@ -308,6 +342,7 @@ failed (or another error occurred).`,
 	}
 
 	cmd.Flags().StringVarP(&o.inputDir, "input-dir", "i", "output", "The path to the apply output directory containing final manifests")
+	cmd.Flags().StringVar(&o.exportArchive, "export-archive", "", "Validate the exported manifests in an archive written by crane export --output-archive instead of --input-dir")
 	cmd.Flags().StringVar(&o.validateDir, "validate-dir", "validate", "The path where validation results and failures are saved")
 	cmd.Flags().StringVarP(&o.outputFormat, "output", "o", "json", "Report file format: json or yaml")
 	cmd.Flags().StringVar(&o.apiResourcesFile, "api-resources", "", "Path to API surface JSON file from capture-api-surface.sh for offline validation (mutually exclusive with --context/--kubeconfig/--server/--token/--cluster/--user)")
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/konveyor/crane/internal/archive"
 	"github.com/konveyor/crane/internal/flags"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -76,6 +77,33 @@ func TestValidate_Flags(t *testing.T) {
 			wantErr:  true,
 			errMatch: "not a directory",
 		},
+		{
+			name: "missing export-archive",
+			setup: func(t *testing.T) *ValidateOptions {
+				return &ValidateOptions{
+					inputDir:      t.TempDir(),
+					exportArchive: filepath.Join(t.TempDir(), "missing.tar.gz"),
+					outputFormat:  "yaml",
+				}
+			},
+			wantErr:  true,
+			errMatch: "export-archive",
+		},
+		{
+			name: "export-archive replaces input-dir",
+			setup: func(t *testing.T) *ValidateOptions {
+				f := filepath.Join(t.TempDir(), "export.tar.gz")
+				if err := os.WriteFile(f, []byte("x"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				return &ValidateOptions{
+					inputDir:      filepath.Join(t.TempDir(), "missing"),
+					exportArchive: f,
+					outputFormat:  "yaml",
+				}
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid output format",
 			setup: func(t *testing.T) *ValidateOptions {
@@ -396,6 +424,39 @@ func TestRun_EmptyInputDirReturnsError(t *testing.T) {
 	}
 }
 
+func TestRun_ExportArchiveWithoutManifestsReturnsError(t *testing.T) {
+	exportDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(exportDir, "failures", "app"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(exportDir, "failures", "app", "pods.yaml"), []byte("apiVersion: v1\nkind: Pod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(t.TempDir(), "export.tar.gz")
+	if err := archive.Create(exportDir, archivePath, archive.FormatTarGz); err != nil {
+		t.Fatal(err)
+	}
+	apiFile := filepath.Join(t.TempDir(), "api-resources.json")
+	if err := os.WriteFile(apiFile, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &ValidateOptions{
+		configFlags:      genericclioptions.NewConfigFlags(true),
+		IOStreams:        genericclioptions.NewTestIOStreamsDiscard(),
+		exportArchive:    archivePath,
+		validateDir:      filepath.Join(t.TempDir(), "validate"),
+		outputFormat:     "json",
+		apiResourcesFile: apiFile,
+		globalFlags:      &flags.GlobalFlags{},
+	}
+
+	err := o.Run()
+	if err == nil || !strings.Contains(err.Error(), "no manifests found in "+archivePath) {
+		t.Fatalf("expected 'no manifests found' error naming the archive, got: %v", err)
+	}
+}
+
 func TestValidateCommand_RejectsArgs(t *testing.T) {
 	streams := genericclioptions.NewTestIOStreamsDiscard()
 	cmd := NewValidateCommand(streams, nil)
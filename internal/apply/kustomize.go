@@ -24,7 +24,8 @@ type KustomizeApplier struct {
 	OutputDir         string
 	KustomizeArgs     []string
 	SkipClusterScoped bool
-	Ordered           bool // Enable ordered resource filenames with dependency-aware prefixes
+	Ordered           bool   // Enable ordered resource filenames with dependency-aware prefixes
+	SecretsKey        []byte // AES key for Secrets encrypted by crane export --secrets-key-file
 }
 
 // ApplySingleStage applies a single transform stage to produce output
@@ -47,6 +48,14 @@ func (k *KustomizeApplier) ApplySingleStage(stageName string) error {
 		return fmt.Errorf("kustomization.yaml not found in stage: %s", stageName)
 	}
 
+	stages, err := internalTransform.DiscoverStages(k.TransformDir)
+	if err != nil {
+		return fmt.Errorf("failed to discover stages: %w", err)
+	}
+	if err := k.checkStages(pipelineUpTo(stages, stageName)); err != nil {
+		return err
+	}
+
 	// Run kustomize build
 	k.Log.Infof("Building stage: %s", stageName)
 	output, err := k.runKustomizeBuild(stageDir)
@@ -62,6 +71,11 @@ func (k *KustomizeApplier) ApplySingleStage(stageName string) error {
 		}
 	}
 
+	output, err = k.decryptSecrets(output)
+	if err != nil {
+		return err
+	}
+
 	// Write output to output directory
 	outputPath := filepath.Join(k.OutputDir, stageName+".yaml")
 	if err := os.MkdirAll(k.OutputDir, 0700); err != nil {
@@ -76,6 +90,40 @@ func (k *KustomizeApplier) ApplySingleStage(stageName string) error {
 	return nil
 }
 
+// checkStages warns about the stages whose input changed since crane transform generated
+// them, and lists the files of each stage that were edited by hand.
+func (k *KustomizeApplier) checkStages(stages []internalTransform.Stage) error {
+	statuses, err := internalTransform.CheckStages(k.TransformDir, stages, k.Log)
+	if err != nil {
+		return fmt.Errorf("failed to check stages: %w", err)
+	}
+	var stale []string
+	for _, status := range statuses {
+		for _, edit := range status.Edited {
+			k.Log.Infof("Stage %s: user-edited %s", status.Stage, edit)
+		}
+		if status.Stale != "" {
+			k.Log.Warnf("Stage %s is stale: %s", status.Stage, status.Stale)
+			stale = append(stale, status.Stage)
+		}
+	}
+	if len(stale) > 0 {
+		k.Log.Warnf("Applying stale stage(s) %s; run crane transform --stale-only to regenerate them", strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+// pipelineUpTo returns the stages that feed the output of the stage named stageName: every
+// stage up to and including it. It returns nil when stages has no such stage.
+func pipelineUpTo(stages []internalTransform.Stage, stageName string) []internalTransform.Stage {
+	for i, stage := range stages {
+		if stage.DirName == stageName {
+			return stages[:i+1]
+		}
+	}
+	return nil
+}
+
 // ApplyMultiStage applies a multi-stage transform pipeline
 func (k *KustomizeApplier) ApplyMultiStage(stageSelector internalTransform.StageSelector) error {
 	// Discover stages
@@ -93,6 +141,10 @@ func (k *KustomizeApplier) ApplyMultiStage(stageSelector internalTransform.Stage
 	// Get the last (final) stage
 	lastStage := selectedStages[len(selectedStages)-1]
 
+	if err := k.checkStages(pipelineUpTo(stages, lastStage.DirName)); err != nil {
+		return err
+	}
+
 	k.Log.Infof("Applying final stage: %s", lastStage.DirName)
 
 	// Run kustomize build on the last stage
@@ -109,6 +161,11 @@ func (k *KustomizeApplier) ApplyMultiStage(stageSelector internalTransform.Stage
 		}
 	}
 
+	output, err = k.decryptSecrets(output)
+	if err != nil {
+		return err
+	}
+
 	// Write to output.yaml (single file with all resources)
 	outputPath := filepath.Join(k.OutputDir, "output.yaml")
 	if err := os.MkdirAll(k.OutputDir, 0700); err != nil {
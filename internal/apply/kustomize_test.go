@@ -1,11 +1,13 @@
 package apply
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	internalTransform "github.com/konveyor/crane/internal/transform"
 	"github.com/sirupsen/logrus"
 )
 
@@ -658,3 +660,58 @@ func findInString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestApplySingleStage_WarnsAboutStaleStages(t *testing.T) {
+	tmpDir := t.TempDir()
+	exportDir := filepath.Join(tmpDir, "export")
+	transformDir := filepath.Join(tmpDir, "transform")
+	writeExport := func(value string) {
+		t.Helper()
+		configMapYAML := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: default\ndata:\n  key: " + value + "\n"
+		if err := os.MkdirAll(filepath.Join(exportDir, "default"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(exportDir, "default", "configmap.yaml"), []byte(configMapYAML), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeExport("one")
+	for _, stage := range []string{"10_first", "20_second"} {
+		if err := os.MkdirAll(filepath.Join(transformDir, stage), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	quiet := logrus.New()
+	quiet.SetLevel(logrus.ErrorLevel)
+	o := &internalTransform.Orchestrator{
+		Log:                quiet,
+		ExportDir:          exportDir,
+		TransformDir:       transformDir,
+		PluginDir:          "/nonexistent",
+		NewlyCreatedStages: map[string]bool{"10_first": true, "20_second": true},
+	}
+	if err := o.RunMultiStage(internalTransform.StageSelector{}); err != nil {
+		t.Fatalf("RunMultiStage failed: %v", err)
+	}
+
+	apply := func() string {
+		t.Helper()
+		var logs bytes.Buffer
+		log := logrus.New()
+		log.SetOutput(&logs)
+		applier := &KustomizeApplier{Log: log, TransformDir: transformDir, OutputDir: t.TempDir()}
+		if err := applier.ApplySingleStage("20_second"); err != nil {
+			t.Fatalf("ApplySingleStage: %v", err)
+		}
+		return logs.String()
+	}
+	if logs := apply(); strings.Contains(logs, "stale") {
+		t.Errorf("fresh pipeline logged:\n%s", logs)
+	}
+
+	// The export changes: the first stage, which feeds the applied one, is stale.
+	writeExport("two")
+	if logs := apply(); !strings.Contains(logs, "Stage 10_first is stale") || !strings.Contains(logs, "crane transform --stale-only") {
+		t.Errorf("expected a stale warning for 10_first, got:\n%s", logs)
+	}
+}
@@ -0,0 +1,93 @@
+package apply
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/konveyor/crane/internal/secrets"
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// decryptSecrets decrypts the Secrets that crane export encrypted with --secrets-key-file
+// in a multi-document YAML stream. Without SecretsKey, encrypted Secrets are an error:
+// the API server would reject them, or store the ciphertext as their data. Redacted
+// Secrets are reported either way.
+func (k *KustomizeApplier) decryptSecrets(yamlData []byte) ([]byte, error) {
+	decoder := yamlv3.NewDecoder(strings.NewReader(string(yamlData)))
+	var result bytes.Buffer
+	first := true
+	decrypted, encrypted := 0, 0
+
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode YAML document: %w", err)
+		}
+		if doc == nil {
+			continue
+		}
+
+		var buf bytes.Buffer
+		encoder := yamlv3.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML document: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close YAML encoder: %w", err)
+		}
+		docBytes := buf.Bytes()
+
+		jsonData, err := yaml.YAMLToJSON(docBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert YAML to JSON: %w", err)
+		}
+
+		u := unstructured.Unstructured{}
+		if err := u.UnmarshalJSON(jsonData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal resource: %w", err)
+		}
+
+		if secrets.IsSecret(u) {
+			if fields := secrets.RedactedFields(u.GetAnnotations()); len(fields) > 0 {
+				k.Log.Warnf("Secret %s/%s was exported with redacted values (%s); set them before applying", u.GetNamespace(), u.GetName(), strings.Join(fields, ", "))
+			}
+			if secrets.IsEncrypted(u) {
+				if k.SecretsKey == nil {
+					encrypted++
+				} else {
+					if err := secrets.Decrypt(&u, k.SecretsKey); err != nil {
+						return nil, fmt.Errorf("failed to decrypt Secret: %w", err)
+					}
+					if docBytes, err = yaml.Marshal(u.Object); err != nil {
+						return nil, fmt.Errorf("failed to marshal resource: %w", err)
+					}
+					decrypted++
+				}
+			}
+		}
+
+		if !first {
+			result.WriteString("---\n")
+		}
+		result.Write(docBytes)
+		first = false
+	}
+
+	if encrypted > 0 {
+		return nil, fmt.Errorf("%d Secret(s) are encrypted; pass --secrets-key-file to decrypt them", encrypted)
+	}
+	if decrypted == 0 {
+		return yamlData, nil
+	}
+	k.Log.Infof("Decrypted %d Secret(s)", decrypted)
+	return result.Bytes(), nil
+}
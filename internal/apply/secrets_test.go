@@ -0,0 +1,109 @@
+package apply
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/konveyor/crane/internal/secrets"
+	internalTransform "github.com/konveyor/crane/internal/transform"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+var testSecretsKey = bytes.Repeat([]byte{0x24}, 32)
+
+func encryptedSecretYAML(t *testing.T) []byte {
+	t.Helper()
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "app"},
+		"data":       map[string]interface{}{"password": "czNjcjN0"},
+	}}
+	if err := secrets.Encrypt(obj, testSecretsKey, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestApplyMultiStage_DecryptsSecrets(t *testing.T) {
+	transformDir := t.TempDir()
+	stageDir := filepath.Join(transformDir, "10_KubernetesPlugin")
+	if err := os.MkdirAll(stageDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: app\ndata:\n  mode: fast\n"
+	files := map[string][]byte{
+		"kustomization.yaml": []byte("resources:\n- secret.yaml\n- configmap.yaml\n"),
+		"secret.yaml":        encryptedSecretYAML(t),
+		"configmap.yaml":     []byte(configMap),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(stageDir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		key          []byte
+		wantPassword string
+		wantSOPS     bool
+		wantErr      string
+	}{
+		{name: "with key", key: testSecretsKey, wantPassword: "password: czNjcjN0"},
+		{name: "without key", wantErr: "1 Secret(s) are encrypted; pass --secrets-key-file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applier := &KustomizeApplier{
+				Log:          logrus.New(),
+				TransformDir: transformDir,
+				OutputDir:    filepath.Join(t.TempDir(), "output"),
+				SecretsKey:   tt.key,
+			}
+			err := applier.ApplyMultiStage(internalTransform.StageSelector{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ApplyMultiStage error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(filepath.Join(applier.OutputDir, "output.yaml")); !os.IsNotExist(err) {
+					t.Errorf("output.yaml was written with encrypted Secrets: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyMultiStage: %v", err)
+			}
+			output, err := os.ReadFile(filepath.Join(applier.OutputDir, "output.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(output), tt.wantPassword) {
+				t.Errorf("output.yaml missing %q:\n%s", tt.wantPassword, output)
+			}
+			if got := strings.Contains(string(output), "sops:"); got != tt.wantSOPS {
+				t.Errorf("sops metadata present = %v, want %v:\n%s", got, tt.wantSOPS, output)
+			}
+			if !strings.Contains(string(output), "mode: fast") {
+				t.Errorf("output.yaml lost the ConfigMap:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestDecryptSecrets_WrongKey(t *testing.T) {
+	applier := &KustomizeApplier{Log: logrus.New(), SecretsKey: bytes.Repeat([]byte{0x01}, 32)}
+	if _, err := applier.decryptSecrets(encryptedSecretYAML(t)); err == nil {
+		t.Fatal("expected error decrypting with the wrong key")
+	}
+}
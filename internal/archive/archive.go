@@ -0,0 +1,387 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats accepted by Create. Open detects the format from the file contents.
+const (
+	// FormatTarGz is a gzip-compressed tarball of the export directory tree.
+	FormatTarGz = "tar.gz"
+	// FormatOCI is an OCI image layout tarball holding a single artifact whose only
+	// layer is the FormatTarGz tarball. It can be pushed to a registry with standard
+	// tools, e.g. skopeo copy oci-archive:export.tar docker://registry/export:tag.
+	FormatOCI = "oci"
+)
+
+// Formats lists the values accepted by Create.
+var Formats = []string{FormatTarGz, FormatOCI}
+
+// OCI media types used by FormatOCI.
+const (
+	ArtifactType      = "application/vnd.konveyor.crane.export.v1"
+	LayerMediaType    = "application/vnd.oci.image.layer.v1.tar+gzip"
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	indexMediaType    = "application/vnd.oci.image.index.v1+json"
+	emptyMediaType    = "application/vnd.oci.empty.v1+json"
+	layoutFileName    = "oci-layout"
+	indexFileName     = "index.json"
+	layerTitle        = "export.tar.gz"
+)
+
+type descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Data         []byte            `json:"data,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	ArtifactType  string       `json:"artifactType,omitempty"`
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+}
+
+type ociIndex struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Manifests     []descriptor `json:"manifests"`
+}
+
+// ValidFormat reports whether format is accepted by Create.
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Create writes the directory tree under dir to an archive at dest. Paths inside the
+// archive are relative to dir, so the archive has the same layout as the directory.
+func Create(dir, dest, format string) (err error) {
+	if !ValidFormat(format) {
+		return fmt.Errorf("unsupported archive format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("create archive %q: %w", dest, err)
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close archive %q: %w", dest, cerr)
+		}
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+
+	switch format {
+	case FormatOCI:
+		err = writeOCILayout(out, dir)
+	default:
+		err = writeTarGz(out, dir)
+	}
+	if err != nil {
+		return fmt.Errorf("write archive %q: %w", dest, err)
+	}
+	return nil
+}
+
+// writeTarGz writes the tree under dir as a gzip-compressed tarball. Only directories
+// and regular files are archived.
+func writeTarGz(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." || !(d.IsDir() || d.Type().IsRegular()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeOCILayout writes an OCI image layout tarball whose single manifest references
+// the tar.gz of dir as its only layer.
+func writeOCILayout(w io.Writer, dir string) error {
+	layer, err := os.CreateTemp("", "crane-export-layer-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(layer.Name())
+	defer layer.Close()
+
+	h := sha256.New()
+	if err := writeTarGz(io.MultiWriter(layer, h), dir); err != nil {
+		return err
+	}
+	size, err := layer.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := layer.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	layerDigest := "sha256:" + hex.EncodeToString(h.Sum(nil))
+
+	emptyConfig := []byte("{}")
+	manifest, err := json.Marshal(ociManifest{
+		SchemaVersion: 2,
+		MediaType:     manifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config:        descriptor{MediaType: emptyMediaType, Digest: digestOf(emptyConfig), Size: int64(len(emptyConfig)), Data: emptyConfig},
+		Layers: []descriptor{{
+			MediaType:   LayerMediaType,
+			Digest:      layerDigest,
+			Size:        size,
+			Annotations: map[string]string{"org.opencontainers.image.title": layerTitle},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	index, err := json.Marshal(ociIndex{
+		SchemaVersion: 2,
+		MediaType:     indexMediaType,
+		Manifests: []descriptor{{
+			MediaType:    manifestMediaType,
+			ArtifactType: ArtifactType,
+			Digest:       digestOf(manifest),
+			Size:         int64(len(manifest)),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	for _, d := range []string{"blobs/", "blobs/sha256/"} {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: d, Mode: 0755}); err != nil {
+			return err
+		}
+	}
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{layoutFileName, []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		{indexFileName, index},
+		{blobPath(digestOf(manifest)), manifest},
+		{blobPath(digestOf(emptyConfig)), emptyConfig},
+	} {
+		if err := writeTarFile(tw, f.name, int64(len(f.data)), bytes.NewReader(f.data)); err != nil {
+			return err
+		}
+	}
+	if err := writeTarFile(tw, blobPath(layerDigest), size, layer); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: size}); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func blobPath(digest string) string {
+	return path.Join("blobs", strings.Replace(digest, ":", "/", 1))
+}
+
+// Open reads an archive written by Create into memory and returns its export tree as a
+// read-only file system, without unpacking it to disk. Both formats are detected from
+// the file contents.
+func Open(archivePath string) (fs.FS, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open archive %q: %w", archivePath, err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, fmt.Errorf("read archive %q: %w", archivePath, err)
+	}
+	var fsys *memFS
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		fsys, err = readTarGz(br)
+	} else {
+		fsys, err = readOCILayout(br)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read archive %q: %w", archivePath, err)
+	}
+	return fsys, nil
+}
+
+func readTarGz(r io.Reader) (*memFS, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return readTar(gz)
+}
+
+// readTar loads the directories and regular files of a tarball. Entries with absolute
+// or parent-relative names are rejected; other entry types are ignored.
+func readTar(r io.Reader) (*memFS, error) {
+	fsys := newMemFS()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fsys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(path.Clean(strings.TrimPrefix(hdr.Name, "./")), "/")
+		if name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid entry name %q", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if _, err := fsys.addDir(name, hdr.FileInfo().Mode(), hdr.ModTime); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("read entry %q: %w", hdr.Name, err)
+			}
+			if err := fsys.addFile(name, data, hdr.FileInfo().Mode(), hdr.ModTime); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// readOCILayout finds the export artifact in an OCI image layout tarball and loads its
+// layer.
+func readOCILayout(r io.Reader) (*memFS, error) {
+	layout, err := readTar(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.Stat(layout, layoutFileName); err != nil {
+		return nil, errors.New("not a tar.gz archive or OCI image layout")
+	}
+
+	var index ociIndex
+	if err := readJSON(layout, indexFileName, &index); err != nil {
+		return nil, err
+	}
+	var manifest *ociManifest
+	for _, d := range index.Manifests {
+		if d.ArtifactType != "" && d.ArtifactType != ArtifactType {
+			continue
+		}
+		m := &ociManifest{}
+		if err := readJSON(layout, blobPath(d.Digest), m); err != nil {
+			return nil, err
+		}
+		if m.ArtifactType == ArtifactType {
+			manifest = m
+			break
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("OCI image layout has no manifest with artifactType %s", ArtifactType)
+	}
+	for _, l := range manifest.Layers {
+		if l.MediaType != LayerMediaType {
+			continue
+		}
+		data, err := fs.ReadFile(layout, blobPath(l.Digest))
+		if err != nil {
+			return nil, fmt.Errorf("read layer %s: %w", l.Digest, err)
+		}
+		if digestOf(data) != l.Digest {
+			return nil, fmt.Errorf("layer %s: digest mismatch", l.Digest)
+		}
+		return readTarGz(bytes.NewReader(data))
+	}
+	return nil, fmt.Errorf("OCI manifest has no %s layer", LayerMediaType)
+}
+
+func readJSON(fsys fs.FS, name string, v interface{}) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	return nil
+}
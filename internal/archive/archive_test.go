@@ -0,0 +1,118 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCreateAndOpen(t *testing.T) {
+	files := map[string]string{
+		"manifest.json":                          `{"craneVersion":"v0.1.0"}`,
+		"resources/app/ConfigMap__v1_app_c.yaml": "kind: ConfigMap\n",
+		"resources/app/_cluster/ClusterRole_rbac.authorization.k8s.io_v1_clusterscoped_r.yaml": "kind: ClusterRole\n",
+		"failures/app/pods.yaml": "error\n",
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, files)
+	if err := os.MkdirAll(filepath.Join(dir, "failures", "_cluster"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "export."+format)
+			if err := Create(dir, dest, format); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			fsys, err := Open(dest)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if err := fstest.TestFS(fsys, "manifest.json", "resources/app/ConfigMap__v1_app_c.yaml", "failures/_cluster"); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range files {
+				got, err := fs.ReadFile(fsys, name)
+				if err != nil {
+					t.Fatalf("ReadFile(%q): %v", name, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateRejectsUnknownFormat(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "export.zip")
+	if err := Create(t.TempDir(), dest, "zip"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("expected no archive to be written, got %v", err)
+	}
+}
+
+func TestOpenRejectsUnsafeEntries(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	data := []byte("x")
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../escape.yaml", Mode: 0600, Size: int64(len(data))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bad.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Fatal("expected error for entry outside the archive root")
+	}
+}
+
+func TestOpenRejectsPlainTarWithoutOCILayout(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "resources/", Mode: 0700}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "plain.tar")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Fatal("expected error for tarball that is not an OCI image layout")
+	}
+}
@@ -0,0 +1,180 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// memFS is a read-only in-memory file system holding the contents of an archive.
+type memFS struct {
+	entries map[string]*memEntry
+}
+
+type memEntry struct {
+	name     string
+	data     []byte
+	mode     fs.FileMode
+	modTime  time.Time
+	children map[string]*memEntry
+}
+
+func newMemFS() *memFS {
+	return &memFS{entries: map[string]*memEntry{
+		".": {name: ".", mode: fs.ModeDir | 0755, children: map[string]*memEntry{}},
+	}}
+}
+
+// addDir adds the directory name and any missing parents.
+func (m *memFS) addDir(name string, mode fs.FileMode, modTime time.Time) (*memEntry, error) {
+	if e, ok := m.entries[name]; ok {
+		if !e.mode.IsDir() {
+			return nil, fmt.Errorf("%q is both a file and a directory", name)
+		}
+		if name != "." {
+			e.mode, e.modTime = fs.ModeDir|mode.Perm(), modTime
+		}
+		return e, nil
+	}
+	parent, err := m.addDir(path.Dir(name), 0755, modTime)
+	if err != nil {
+		return nil, err
+	}
+	e := &memEntry{name: path.Base(name), mode: fs.ModeDir | mode.Perm(), modTime: modTime, children: map[string]*memEntry{}}
+	m.entries[name] = e
+	parent.children[e.name] = e
+	return e, nil
+}
+
+// addFile adds the regular file name, replacing any earlier file with the same name.
+func (m *memFS) addFile(name string, data []byte, mode fs.FileMode, modTime time.Time) error {
+	if e, ok := m.entries[name]; ok && e.mode.IsDir() {
+		return fmt.Errorf("%q is both a file and a directory", name)
+	}
+	parent, err := m.addDir(path.Dir(name), 0755, modTime)
+	if err != nil {
+		return err
+	}
+	e := &memEntry{name: path.Base(name), data: data, mode: mode.Perm(), modTime: modTime}
+	m.entries[name] = e
+	parent.children[e.name] = e
+	return nil
+}
+
+func (m *memFS) lookup(op, name string) (*memEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	e, err := m.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.mode.IsDir() {
+		return &memDir{entry: e, path: name}, nil
+	}
+	return &memFile{entry: e, Reader: bytes.NewReader(e.data)}, nil
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	e, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	e, err := m.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	return append([]byte(nil), e.data...), nil
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := m.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return e.sortedChildren(), nil
+}
+
+// memEntry implements fs.FileInfo and fs.DirEntry.
+func (e *memEntry) Name() string               { return e.name }
+func (e *memEntry) Size() int64                { return int64(len(e.data)) }
+func (e *memEntry) Mode() fs.FileMode          { return e.mode }
+func (e *memEntry) ModTime() time.Time         { return e.modTime }
+func (e *memEntry) IsDir() bool                { return e.mode.IsDir() }
+func (e *memEntry) Sys() interface{}           { return nil }
+func (e *memEntry) Type() fs.FileMode          { return e.mode.Type() }
+func (e *memEntry) Info() (fs.FileInfo, error) { return e, nil }
+
+func (e *memEntry) sortedChildren() []fs.DirEntry {
+	out := make([]fs.DirEntry, 0, len(e.children))
+	for _, c := range e.children {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out
+}
+
+type memFile struct {
+	entry *memEntry
+	*bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	entry   *memEntry
+	path    string
+	pending []fs.DirEntry
+	read    bool
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		d.pending = d.entry.sortedChildren()
+		d.read = true
+	}
+	if n <= 0 {
+		out := d.pending
+		d.pending = nil
+		return out, nil
+	}
+	if len(d.pending) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.pending) {
+		n = len(d.pending)
+	}
+	out := d.pending[:n]
+	d.pending = d.pending[n:]
+	return out, nil
+}
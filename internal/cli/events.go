@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventWriter writes a machine-readable event stream: one JSON object per line, with the
+// event name under "event", the time under "time", and the event's fields. It is safe for
+// concurrent use, and a nil EventWriter discards events, so callers need not check whether
+// an event stream was requested.
+type EventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+	now func() time.Time
+}
+
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{enc: json.NewEncoder(w), now: time.Now}
+}
+
+// Emit writes event with fields. Write errors are kept for Err and later events are
+// dropped.
+func (e *EventWriter) Emit(event string, fields map[string]interface{}) {
+	if e == nil {
+		return
+	}
+	line := make(map[string]interface{}, len(fields)+2)
+	for k, v := range fields {
+		line[k] = v
+	}
+	line["event"] = event
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return
+	}
+	line["time"] = e.now().UTC().Format(time.RFC3339Nano)
+	e.err = e.enc.Encode(line)
+}
+
+// Err returns the first error writing an event.
+func (e *EventWriter) Err() error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
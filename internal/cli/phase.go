@@ -6,12 +6,19 @@ import (
 	"time"
 )
 
+// progressInterval is the minimum time between two Progress lines of a phase.
+const progressInterval = time.Second
+
 type PhaseTracker struct {
-	w        io.Writer
-	total    int
-	current  int
-	name     string
-	runStart time.Time
+	w          io.Writer
+	total      int
+	current    int
+	name       string
+	runStart   time.Time
+	phaseStart time.Time
+	// lastProgress is when Progress last printed; interval is the minimum time between lines.
+	lastProgress time.Time
+	interval     time.Duration
 }
 
 func NewPhaseTracker(w io.Writer, totalPhases int) *PhaseTracker {
@@ -19,15 +26,40 @@ func NewPhaseTracker(w io.Writer, totalPhases int) *PhaseTracker {
 		w:        w,
 		total:    totalPhases,
 		runStart: time.Now(),
+		interval: progressInterval,
 	}
 }
 
 func (p *PhaseTracker) Start(name string) {
 	p.current++
 	p.name = name
+	p.phaseStart = time.Now()
+	p.lastProgress = time.Time{}
 	fmt.Fprintf(p.w, "[%d/%d] %s ...\n", p.current, p.total, name)
 }
 
+// Progress reports that done of total items of the current phase are finished, followed
+// by detail and an estimate of the time left based on the pace so far. Lines are printed
+// at most once per second, except the one for the last item, so it can be called for
+// every item.
+func (p *PhaseTracker) Progress(done, total int, detail string) {
+	now := time.Now()
+	if done < total && now.Sub(p.lastProgress) < p.interval {
+		return
+	}
+	p.lastProgress = now
+	line := fmt.Sprintf("[%d/%d] %s ... %d/%d", p.current, p.total, p.name, done, total)
+	if detail != "" {
+		line += "  " + detail
+	}
+	if done > 0 && done < total {
+		elapsed := now.Sub(p.phaseStart)
+		eta := time.Duration(float64(elapsed) / float64(done) * float64(total-done))
+		line += fmt.Sprintf("  ETA %s", eta.Round(time.Second))
+	}
+	fmt.Fprintf(p.w, "%s\n", line)
+}
+
 func (p *PhaseTracker) End(status string, detail string) {
 	line := fmt.Sprintf("[%d/%d] %s ... %s", p.current, p.total, p.name, status)
 	if detail != "" {
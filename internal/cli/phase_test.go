@@ -155,3 +155,48 @@ func TestPrintTransferSummaryFailed(t *testing.T) {
 		t.Errorf("Done should not appear for failed, got: %q", output)
 	}
 }
+
+func TestPhaseProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPhaseTracker(&buf, 2)
+	p.Start("Listing resources")
+	p.phaseStart = time.Now().Add(-10 * time.Second)
+
+	p.Progress(1, 4, "deployments.apps")
+	// Throttled: printed less than a second after the previous line.
+	p.Progress(2, 4, "services")
+	// The last item is always printed.
+	p.Progress(4, 4, "configmaps")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3: %q", len(lines), buf.String())
+	}
+	if lines[1] != "[1/2] Listing resources ... 1/4  deployments.apps  ETA 30s" {
+		t.Errorf("progress line = %q", lines[1])
+	}
+	if lines[2] != "[1/2] Listing resources ... 4/4  configmaps" {
+		t.Errorf("last progress line = %q", lines[2])
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEventWriter(&buf)
+	e.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	e.Emit("gvr-listed", map[string]interface{}{"resource": "deployments", "objects": 3})
+	e.Emit("discovery-done", nil)
+
+	want := `{"event":"gvr-listed","objects":3,"resource":"deployments","time":"2024-01-02T03:04:05Z"}
+{"event":"discovery-done","time":"2024-01-02T03:04:05Z"}
+`
+	if buf.String() != want || e.Err() != nil {
+		t.Errorf("events =\n%s\nwant\n%s (err %v)", buf.String(), want, e.Err())
+	}
+
+	var none *EventWriter
+	none.Emit("discovery-done", nil)
+	if none.Err() != nil {
+		t.Error("nil EventWriter returned an error")
+	}
+}
@@ -0,0 +1,148 @@
+// Package diff compares two sets of exported objects, pairing them by export filename and
+// reporting the fields that differ once server-populated fields are removed.
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/konveyor/crane/internal/normalize"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Compare pairs the objects in left and right by file.GetResourceFilename and returns the
+// objects that exist on one side only and those whose normalized content differs. A
+// Secret that crane export encrypted or redacted on either side is compared by key set
+// only. The inputs are not modified. When one side holds two objects with the same filename, the
+// last one wins. Objects in the report are sorted by key.
+func Compare(left, right []unstructured.Unstructured) *Report {
+	l, r := byFilename(left), byFilename(right)
+	report := &Report{Objects: []ObjectDiff{}}
+	for key, obj := range l {
+		other, ok := r[key]
+		if !ok {
+			report.Objects = append(report.Objects, objectDiff(key, obj, StatusRemoved, nil))
+			report.Removed++
+			continue
+		}
+		// An encrypted or redacted Secret only tells which keys the other side should have.
+		switch {
+		case normalize.SecretValuesHidden(*obj) && !normalize.SecretValuesHidden(*other):
+			normalize.HideSecretValues(other)
+		case normalize.SecretValuesHidden(*other) && !normalize.SecretValuesHidden(*obj):
+			normalize.HideSecretValues(obj)
+		}
+		fields := Fields(obj.Object, other.Object)
+		if len(fields) == 0 {
+			report.Unchanged++
+			continue
+		}
+		report.Objects = append(report.Objects, objectDiff(key, other, StatusChanged, fields))
+		report.Changed++
+	}
+	for key, obj := range r {
+		if _, ok := l[key]; !ok {
+			report.Objects = append(report.Objects, objectDiff(key, obj, StatusAdded, nil))
+			report.Added++
+		}
+	}
+	sort.Slice(report.Objects, func(i, j int) bool { return report.Objects[i].Key < report.Objects[j].Key })
+	return report
+}
+
+// byFilename returns copies of objs normalized by normalize.ForDiff, keyed by export
+// filename.
+func byFilename(objs []unstructured.Unstructured) map[string]*unstructured.Unstructured {
+	out := make(map[string]*unstructured.Unstructured, len(objs))
+	for i := range objs {
+		obj := objs[i].DeepCopy()
+		normalize.ForDiff(obj)
+		out[file.GetResourceFilename(*obj)] = obj
+	}
+	return out
+}
+
+func objectDiff(key string, obj *unstructured.Unstructured, status ObjectStatus, fields []FieldChange) ObjectDiff {
+	return ObjectDiff{
+		Key:        key,
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Status:     status,
+		Fields:     fields,
+	}
+}
+
+// Fields returns the fields that differ between left and right, sorted by path. Maps are
+// compared key by key and lists index by index, so a changed container image is reported
+// as spec.template.spec.containers[0].image rather than as a new containers list.
+func Fields(left, right map[string]interface{}) []FieldChange {
+	var changes []FieldChange
+	diffMaps("", left, right, &changes)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func diffValues(path string, left, right interface{}, changes *[]FieldChange) {
+	switch l := left.(type) {
+	case map[string]interface{}:
+		if r, ok := right.(map[string]interface{}); ok {
+			diffMaps(path, l, r, changes)
+			return
+		}
+	case []interface{}:
+		if r, ok := right.([]interface{}); ok {
+			diffLists(path, l, r, changes)
+			return
+		}
+	}
+	if !reflect.DeepEqual(left, right) {
+		*changes = append(*changes, FieldChange{Path: path, Status: StatusChanged, Old: left, New: right})
+	}
+}
+
+func diffMaps(path string, left, right map[string]interface{}, changes *[]FieldChange) {
+	for k, l := range left {
+		r, ok := right[k]
+		if !ok {
+			*changes = append(*changes, FieldChange{Path: fieldPath(path, k), Status: StatusRemoved, Old: l})
+			continue
+		}
+		diffValues(fieldPath(path, k), l, r, changes)
+	}
+	for k, r := range right {
+		if _, ok := left[k]; !ok {
+			*changes = append(*changes, FieldChange{Path: fieldPath(path, k), Status: StatusAdded, New: r})
+		}
+	}
+}
+
+func diffLists(path string, left, right []interface{}, changes *[]FieldChange) {
+	for i := 0; i < len(left) || i < len(right); i++ {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(right):
+			*changes = append(*changes, FieldChange{Path: p, Status: StatusRemoved, Old: left[i]})
+		case i >= len(left):
+			*changes = append(*changes, FieldChange{Path: p, Status: StatusAdded, New: right[i]})
+		default:
+			diffValues(p, left[i], right[i], changes)
+		}
+	}
+}
+
+// fieldPath appends key to path with a dot, or in brackets when the key itself contains
+// dots or slashes, as label and annotation keys often do.
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, "./[] ") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,174 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/konveyor/crane/internal/secrets"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func parseObjects(t *testing.T, docs ...string) []unstructured.Unstructured {
+	t.Helper()
+	var out []unstructured.Unstructured
+	for _, doc := range docs {
+		obj := unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, obj)
+	}
+	return out
+}
+
+func TestFields(t *testing.T) {
+	left := map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "web"}},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"ports":    []interface{}{int64(80), int64(443)},
+			"paused":   true,
+		},
+	}
+	right := map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "api"}},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"ports":    []interface{}{int64(80)},
+			"strategy": "Recreate",
+		},
+	}
+	want := []FieldChange{
+		{Path: `metadata.labels["app.kubernetes.io/name"]`, Status: StatusChanged, Old: "web", New: "api"},
+		{Path: "spec.paused", Status: StatusRemoved, Old: true},
+		{Path: "spec.ports[1]", Status: StatusRemoved, Old: int64(443)},
+		{Path: "spec.replicas", Status: StatusChanged, Old: int64(2), New: int64(3)},
+		{Path: "spec.strategy", Status: StatusAdded, New: "Recreate"},
+	}
+	if got := Fields(left, right); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := Fields(left, left); len(got) != 0 {
+		t.Errorf("Fields(left, left) = %+v", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	left := parseObjects(t,
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: web, namespace: app, uid: a, resourceVersion: \"1\"}\nspec: {replicas: 2}\nstatus: {replicas: 2}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: settings, namespace: app, uid: b}\ndata: {key: value}\n",
+		"apiVersion: v1\nkind: Secret\nmetadata: {name: old, namespace: app}\n",
+	)
+	right := parseObjects(t,
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: web, namespace: app, uid: c, resourceVersion: \"9\"}\nspec: {replicas: 3}\nstatus: {replicas: 3}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: settings, namespace: app, uid: d}\ndata: {key: value}\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata: {name: new, namespace: app}\n",
+	)
+	report := Compare(left, right)
+
+	if report.Added != 1 || report.Removed != 1 || report.Changed != 1 || report.Unchanged != 1 || !report.HasDrift() {
+		t.Fatalf("report counts = %+v", report)
+	}
+	var got []string
+	for _, obj := range report.Objects {
+		got = append(got, string(obj.Status)+" "+obj.Key)
+	}
+	want := []string{
+		"added ConfigMap__v1_app_new.yaml",
+		"changed Deployment_apps_v1_app_web.yaml",
+		"removed Secret__v1_app_old.yaml",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("objects = %v, want %v", got, want)
+	}
+	if fields := report.Objects[1].Fields; len(fields) != 1 || fields[0].Path != "spec.replicas" {
+		t.Errorf("changed fields = %+v, want only spec.replicas", fields)
+	}
+	if _, ok := left[0].Object["status"]; !ok {
+		t.Error("Compare modified its input")
+	}
+
+	if Compare(left, left).HasDrift() {
+		t.Error("comparing an export with itself reported drift")
+	}
+}
+
+// TestCompare_CraneExportFields checks that what crane export adds to objects, Helm
+// release annotations and encrypted or redacted Secret values, is not reported as drift.
+func TestCompare_CraneExportFields(t *testing.T) {
+	secret := func(password string) unstructured.Unstructured {
+		return parseObjects(t, "apiVersion: v1\nkind: Secret\nmetadata: {name: db, namespace: app}\ndata: {password: "+password+"}\n")[0]
+	}
+	encrypted := func() unstructured.Unstructured {
+		obj := secret("czNjcjN0")
+		if err := secrets.Encrypt(&obj, bytes.Repeat([]byte{0x24}, 32), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	redacted := secret("czNjcjN0")
+	if _, err := secrets.Redact(&redacted); err != nil {
+		t.Fatal(err)
+	}
+	service := "apiVersion: v1\nkind: Service\nmetadata: {name: web, namespace: app}\n"
+	helmService := "apiVersion: v1\nkind: Service\nmetadata: {name: web, namespace: app, annotations: {crane.konveyor.io/helm-release: app/web}}\n"
+
+	tests := []struct {
+		name        string
+		left, right []unstructured.Unstructured
+		wantDrift   bool
+	}{
+		{name: "two encrypted exports", left: []unstructured.Unstructured{encrypted()}, right: []unstructured.Unstructured{encrypted()}},
+		{name: "encrypted export and live", left: []unstructured.Unstructured{encrypted()}, right: []unstructured.Unstructured{secret("bmV3")}},
+		{name: "redacted export and live", left: []unstructured.Unstructured{redacted}, right: []unstructured.Unstructured{secret("bmV3")}},
+		{name: "Helm-owned object and live", left: parseObjects(t, helmService), right: parseObjects(t, service)},
+		{
+			name:      "redacted export and live with another key",
+			left:      []unstructured.Unstructured{redacted},
+			right:     parseObjects(t, "apiVersion: v1\nkind: Secret\nmetadata: {name: db, namespace: app}\ndata: {password: bmV3, token: dA==}\n"),
+			wantDrift: true,
+		},
+		{name: "plain Secrets compare by value", left: []unstructured.Unstructured{secret("czNjcjN0")}, right: []unstructured.Unstructured{secret("bmV3")}, wantDrift: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Compare(tt.left, tt.right)
+			if report.HasDrift() != tt.wantDrift {
+				t.Errorf("HasDrift() = %v, want %v: %+v", report.HasDrift(), tt.wantDrift, report.Objects)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	report := Compare(
+		parseObjects(t, "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: settings, namespace: app}\ndata: {key: a}\n"),
+		parseObjects(t, "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: settings, namespace: app}\ndata: {key: b}\n"),
+	)
+	report.Left, report.Right = "before", "after"
+
+	var text bytes.Buffer
+	FormatText(&text, report)
+	for _, want := range []string{"changed  ConfigMap__v1_app_settings.yaml", `~ data.key: "a" -> "b"`, "1 changed", "Result: DRIFT"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := FormatJSON(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Left != "before" || decoded.Changed != 1 || decoded.Objects[0].Fields[0].New != "b" {
+		t.Errorf("decoded report = %+v", decoded)
+	}
+}
@@ -0,0 +1,52 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FormatText writes one line per added, removed, or changed object to w, followed by the
+// changed fields of each changed object and a summary.
+func FormatText(w io.Writer, report *Report) {
+	fmt.Fprintf(w, "Comparing %s with %s\n\n", report.Left, report.Right)
+	for _, obj := range report.Objects {
+		fmt.Fprintf(w, "%-8s %s\n", obj.Status, obj.Key)
+		for _, f := range obj.Fields {
+			switch f.Status {
+			case StatusAdded:
+				fmt.Fprintf(w, "  + %s: %s\n", f.Path, formatValue(f.New))
+			case StatusRemoved:
+				fmt.Fprintf(w, "  - %s: %s\n", f.Path, formatValue(f.Old))
+			default:
+				fmt.Fprintf(w, "  ~ %s: %s -> %s\n", f.Path, formatValue(f.Old), formatValue(f.New))
+			}
+		}
+	}
+	if len(report.Objects) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Summary: %d added, %d removed, %d changed, %d unchanged\n",
+		report.Added, report.Removed, report.Changed, report.Unchanged)
+	if report.HasDrift() {
+		fmt.Fprintf(w, "Result: DRIFT — %d object(s) differ\n", len(report.Objects))
+	} else {
+		fmt.Fprintf(w, "Result: NO DRIFT\n")
+	}
+}
+
+// formatValue renders a field value on one line as compact JSON.
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// FormatJSON writes the report as indented JSON to w.
+func FormatJSON(w io.Writer, report *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
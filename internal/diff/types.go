@@ -0,0 +1,54 @@
+package diff
+
+import "fmt"
+
+// ObjectStatus says how an object differs between the two sides of a diff.
+type ObjectStatus string
+
+const (
+	// StatusAdded objects exist only on the right side.
+	StatusAdded ObjectStatus = "added"
+	// StatusRemoved objects exist only on the left side.
+	StatusRemoved ObjectStatus = "removed"
+	// StatusChanged objects exist on both sides with different fields.
+	StatusChanged ObjectStatus = "changed"
+)
+
+// FieldChange is one field that differs between the two sides of a changed object.
+// Old is unset for added fields and New is unset for removed ones.
+type FieldChange struct {
+	Path   string       `json:"path"`
+	Status ObjectStatus `json:"status"`
+	Old    interface{}  `json:"old,omitempty"`
+	New    interface{}  `json:"new,omitempty"`
+}
+
+// ObjectDiff is one object that is added, removed, or changed. Key is the export filename
+// both sides are paired by.
+type ObjectDiff struct {
+	Key        string        `json:"key"`
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Namespace  string        `json:"namespace,omitempty"`
+	Name       string        `json:"name"`
+	Status     ObjectStatus  `json:"status"`
+	Fields     []FieldChange `json:"fields,omitempty"`
+}
+
+// Report is the complete output of a diff.
+type Report struct {
+	Left      string       `json:"left"`
+	Right     string       `json:"right"`
+	Objects   []ObjectDiff `json:"objects"`
+	Added     int          `json:"added"`
+	Removed   int          `json:"removed"`
+	Changed   int          `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// HasDrift returns true if any object was added, removed, or changed.
+func (r *Report) HasDrift() bool { return len(r.Objects) > 0 }
+
+// ErrDriftDetected is returned when the two sides differ, giving CI/CD pipelines a non-zero
+// exit code.
+var ErrDriftDetected = fmt.Errorf("drift detected: one or more objects differ")
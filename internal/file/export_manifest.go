@@ -0,0 +1,222 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Reasons recorded in ExportManifest.Skipped.
+const (
+	SkipReasonExcluded           = "Excluded"
+	SkipReasonNotIncluded        = "NotIncluded"
+	SkipReasonNotAdmitted        = "NotAdmitted"
+	SkipReasonNoObjects          = "NoObjects"
+	SkipReasonForbidden          = "Forbidden"
+	SkipReasonListError          = "ListError"
+	SkipReasonOperatorManagedCRD = "OperatorManagedCRD"
+)
+
+// ExportManifest describes what crane export captured. It is written to
+// ExportManifestFileName at the root of the export directory. Source names the dump an
+// offline export was read from. Secrets is "encrypted" or "redacted" when Secret values
+// were not written as-is. AlternateVersions has the same form as Resources, for the copies
+// of objects written at other served API versions under VersionsDirName.
+type ExportManifest struct {
+	CraneVersion      string             `json:"craneVersion"`
+	BuildCommit       string             `json:"buildCommit,omitempty"`
+	Server            string             `json:"server,omitempty"`
+	Source            string             `json:"source,omitempty"`
+	Context           string             `json:"context,omitempty"`
+	Namespaces        []string           `json:"namespaces"`
+	LabelSelector     string             `json:"labelSelector,omitempty"`
+	Resources         []ExportedResource `json:"resources"`
+	AlternateVersions []ExportedResource `json:"alternateVersions,omitempty"`
+	Skipped           []SkippedResource  `json:"skipped,omitempty"`
+	Secrets           string             `json:"secrets,omitempty"`
+	Files             []FileDigest       `json:"files"`
+}
+
+// ExportedResource records the objects exported for one GVR in one namespace (empty for
+// cluster-scoped resources). ResourceVersion is the resourceVersion of the list call.
+// Attempts is set when a request for the resource had to be retried, and is the most
+// attempts any single request needed.
+type ExportedResource struct {
+	Namespace       string           `json:"namespace,omitempty"`
+	Group           string           `json:"group,omitempty"`
+	Version         string           `json:"version"`
+	Resource        string           `json:"resource"`
+	Kind            string           `json:"kind,omitempty"`
+	Count           int              `json:"count"`
+	ResourceVersion string           `json:"resourceVersion,omitempty"`
+	Attempts        int              `json:"attempts,omitempty"`
+	Objects         []ExportedObject `json:"objects"`
+}
+
+// ExportedObject identifies one written manifest. Path is relative to the export
+// directory and always uses forward slashes.
+type ExportedObject struct {
+	Path            string `json:"path"`
+	UID             string `json:"uid"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// SkippedResource records a GVR that was discovered but not exported, and why. Name is
+// set when a single object was skipped, such as an operator-managed CRD. Attempts is as
+// for ExportedResource, so a ListError after retries shows how often it was tried.
+type SkippedResource struct {
+	Namespace string `json:"namespace,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Resource  string `json:"resource"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message,omitempty"`
+	Attempts  int    `json:"attempts,omitempty"`
+}
+
+// FileDigest is the sha256 of a file in the export directory, relative to its root.
+type FileDigest struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// ReadExportManifest reads the export manifest from exportDir. It returns nil without
+// an error when the directory has no manifest.
+func ReadExportManifest(exportDir string) (*ExportManifest, error) {
+	return readExportManifest(os.DirFS(exportDir), filepath.Join(exportDir, ExportManifestFileName))
+}
+
+// ReadExportManifestFS is ReadExportManifest for an export tree in fsys, such as an
+// export archive.
+func ReadExportManifestFS(fsys fs.FS) (*ExportManifest, error) {
+	return readExportManifest(fsys, ExportManifestFileName)
+}
+
+func readExportManifest(fsys fs.FS, path string) (*ExportManifest, error) {
+	data, err := fs.ReadFile(fsys, ExportManifestFileName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read export manifest %q: %w", path, err)
+	}
+	m := &ExportManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse export manifest %q: %w", path, err)
+	}
+	return m, nil
+}
+
+// WriteExportManifest writes m to the export manifest in exportDir.
+func WriteExportManifest(exportDir string, m *ExportManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal export manifest: %w", err)
+	}
+	path := filepath.Join(exportDir, ExportManifestFileName)
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("write export manifest %q: %w", path, err)
+	}
+	return nil
+}
+
+// DigestExportFiles returns the sha256 of every regular file under exportDir except the
+// export manifest itself, sorted by path.
+func DigestExportFiles(exportDir string) ([]FileDigest, error) {
+	digests, err := DigestExportFilesFS(os.DirFS(exportDir))
+	if err != nil {
+		return nil, fmt.Errorf("digest export directory %q: %w", exportDir, err)
+	}
+	return digests, nil
+}
+
+// DigestExportFilesFS is DigestExportFiles for an export tree in fsys.
+func DigestExportFilesFS(fsys fs.FS) ([]FileDigest, error) {
+	digests := []FileDigest{}
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || path == ExportManifestFileName {
+			return nil
+		}
+		sum, err := sha256File(fsys, path)
+		if err != nil {
+			return err
+		}
+		digests = append(digests, FileDigest{Path: path, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(digests, func(i, j int) bool { return digests[i].Path < digests[j].Path })
+	return digests, nil
+}
+
+// VerifyExportFiles compares the files under exportDir with the digests in m and returns
+// one message per modified, missing, or unrecorded file.
+func VerifyExportFiles(exportDir string, m *ExportManifest) ([]string, error) {
+	current, err := DigestExportFiles(exportDir)
+	if err != nil {
+		return nil, err
+	}
+	return verifyExportFiles(current, m), nil
+}
+
+// VerifyExportFilesFS is VerifyExportFiles for an export tree in fsys.
+func VerifyExportFilesFS(fsys fs.FS, m *ExportManifest) ([]string, error) {
+	current, err := DigestExportFilesFS(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return verifyExportFiles(current, m), nil
+}
+
+func verifyExportFiles(current []FileDigest, m *ExportManifest) []string {
+	onDisk := make(map[string]string, len(current))
+	for _, d := range current {
+		onDisk[d.Path] = d.SHA256
+	}
+
+	problems := []string{}
+	recorded := make(map[string]bool, len(m.Files))
+	for _, d := range m.Files {
+		recorded[d.Path] = true
+		sum, ok := onDisk[d.Path]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s: missing", d.Path))
+		case sum != d.SHA256:
+			problems = append(problems, fmt.Sprintf("%s: modified since export", d.Path))
+		}
+	}
+	for _, d := range current {
+		if !recorded[d.Path] {
+			problems = append(problems, fmt.Sprintf("%s: not recorded in %s", d.Path, ExportManifestFileName))
+		}
+	}
+	return problems
+}
+
+func sha256File(fsys fs.FS, path string) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,127 @@
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/konveyor/crane/internal/file"
+)
+
+func TestExportManifestRoundTrip(t *testing.T) {
+	dir := createTestDir(t)
+	if m, err := file.ReadExportManifest(dir); err != nil || m != nil {
+		t.Fatalf("expected nil manifest without error for missing file, got %v, %v", m, err)
+	}
+
+	want := &file.ExportManifest{
+		CraneVersion:  "v0.1.0",
+		Server:        "https://api.example.com:6443",
+		Context:       "source",
+		Namespaces:    []string{"app"},
+		LabelSelector: "tier=web",
+		Resources: []file.ExportedResource{{
+			Namespace: "app", Version: "v1", Resource: "configmaps", Kind: "ConfigMap", Count: 1, ResourceVersion: "9",
+			Objects: []file.ExportedObject{{Path: "resources/app/c.yaml", UID: "u1", ResourceVersion: "3"}},
+		}},
+		Skipped: []file.SkippedResource{{Namespace: "app", Version: "v1", Resource: "events", Kind: "Event", Reason: file.SkipReasonExcluded}},
+		Files:   []file.FileDigest{{Path: "resources/app/c.yaml", SHA256: "abc"}},
+	}
+	if err := file.WriteExportManifest(dir, want); err != nil {
+		t.Fatalf("WriteExportManifest: %v", err)
+	}
+	got, err := file.ReadExportManifest(dir)
+	if err != nil {
+		t.Fatalf("ReadExportManifest: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	writeFile(t, filepath.Join(dir, file.ExportManifestFileName), "{")
+	if _, err := file.ReadExportManifest(dir); err == nil {
+		t.Error("expected error for malformed manifest")
+	}
+}
+
+func TestDigestExportFiles(t *testing.T) {
+	dir := createTestDir(t)
+	writeFile(t, filepath.Join(dir, "resources", "app", "b.yaml"), "b")
+	writeFile(t, filepath.Join(dir, "failures", "app", "a.yaml"), "a")
+	writeFile(t, filepath.Join(dir, file.ExportManifestFileName), "{}")
+
+	got, err := file.DigestExportFiles(dir)
+	if err != nil {
+		t.Fatalf("DigestExportFiles: %v", err)
+	}
+	want := []file.FileDigest{
+		// sha256("a") and sha256("b")
+		{Path: "failures/app/a.yaml", SHA256: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"},
+		{Path: "resources/app/b.yaml", SHA256: "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestVerifyExportFiles(t *testing.T) {
+	dir := createTestDir(t)
+	writeFile(t, filepath.Join(dir, "resources", "app", "same.yaml"), "same")
+	writeFile(t, filepath.Join(dir, "resources", "app", "edited.yaml"), "before")
+	writeFile(t, filepath.Join(dir, "resources", "app", "deleted.yaml"), "gone")
+
+	digests, err := file.DigestExportFiles(dir)
+	if err != nil {
+		t.Fatalf("DigestExportFiles: %v", err)
+	}
+	m := &file.ExportManifest{Files: digests}
+
+	problems, err := file.VerifyExportFiles(dir, m)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("expected clean verification, got %v, %v", problems, err)
+	}
+
+	writeFile(t, filepath.Join(dir, "resources", "app", "edited.yaml"), "after")
+	if err := os.Remove(filepath.Join(dir, "resources", "app", "deleted.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "resources", "app", "added.yaml"), "new")
+
+	problems, err = file.VerifyExportFiles(dir, m)
+	if err != nil {
+		t.Fatalf("VerifyExportFiles: %v", err)
+	}
+	want := []string{
+		"resources/app/deleted.yaml: missing",
+		"resources/app/edited.yaml: modified since export",
+		"resources/app/added.yaml: not recorded in manifest.json",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %v, want %v", problems, want)
+	}
+}
+
+func TestVerifyExportFilesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"resources/app/a.yaml":      {Data: []byte("a")},
+		file.ExportManifestFileName: {Data: []byte(`{"files":[{"path":"resources/app/a.yaml","sha256":"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}]}`)},
+	}
+	m, err := file.ReadExportManifestFS(fsys)
+	if err != nil || m == nil {
+		t.Fatalf("ReadExportManifestFS: %v, %v", m, err)
+	}
+	if problems, err := file.VerifyExportFilesFS(fsys, m); err != nil || len(problems) != 0 {
+		t.Fatalf("expected clean verification, got %v, %v", problems, err)
+	}
+
+	fsys["resources/app/a.yaml"] = &fstest.MapFile{Data: []byte("changed")}
+	problems, err := file.VerifyExportFilesFS(fsys, m)
+	if err != nil {
+		t.Fatalf("VerifyExportFilesFS: %v", err)
+	}
+	if want := []string{"resources/app/a.yaml: modified since export"}; !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %v, want %v", problems, want)
+	}
+}
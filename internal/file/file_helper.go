@@ -4,8 +4,10 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -23,10 +25,17 @@ type File struct {
 func ReadFiles(ctx context.Context, dir string) ([]File, error) {
 	log := logrus.New()
 
-	files, err := ioutil.ReadDir(dir)
+	entries, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read directory %q: %w", dir, err)
 	}
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, f := range entries {
+		if f.IsDir() && IsDerivedExportDir(f.Name()) {
+			continue
+		}
+		files = append(files, f)
+	}
 	return readFiles(ctx, dir, files, log)
 }
 
@@ -35,7 +44,7 @@ func readFiles(ctx context.Context, path string, files []os.FileInfo, log *logru
 	for _, file := range files {
 		filePath := fmt.Sprintf("%v/%v", path, file.Name())
 		if file.IsDir() {
-			if file.Name() == "failures" {
+			if file.Name() == FailuresDirName {
 				continue
 			}
 			newFiles, err := ioutil.ReadDir(filePath)
@@ -48,19 +57,16 @@ func readFiles(ctx context.Context, path string, files []os.FileInfo, log *logru
 			}
 			jsonFiles = append(jsonFiles, files...)
 		} else {
+			if file.Name() == ExportManifestFileName {
+				continue
+			}
 			data, err := ioutil.ReadFile(filePath)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read file %q: %w", filePath, err)
 			}
-			json, err := yaml.YAMLToJSON(data)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse YAML in file %q: %w", filePath, err)
-			}
-
-			u := unstructured.Unstructured{}
-			err = u.UnmarshalJSON(json)
+			u, err := parseResource(filePath, data)
 			if err != nil {
-				return nil, fmt.Errorf("file %q is not a valid Kubernetes resource: %w", filePath, err)
+				return nil, err
 			}
 
 			jsonFiles = append(jsonFiles, File{
@@ -73,6 +79,60 @@ func readFiles(ctx context.Context, path string, files []os.FileInfo, log *logru
 	return jsonFiles, nil
 }
 
+// ReadFilesFS is ReadFiles for a directory tree in fsys, such as an export archive opened
+// with archive.Open. Paths are slash-separated and relative to the root of fsys.
+func ReadFilesFS(ctx context.Context, fsys fs.FS) ([]File, error) {
+	jsonFiles := []File{}
+	err := fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == FailuresDirName || IsDerivedExportDir(filePath) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == ExportManifestFileName {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file %q: %w", filePath, err)
+		}
+		u, err := parseResource(filePath, data)
+		if err != nil {
+			return err
+		}
+		jsonFiles = append(jsonFiles, File{
+			Info:         info,
+			Unstructured: u,
+			Path:         filePath,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jsonFiles, nil
+}
+
+func parseResource(filePath string, data []byte) (unstructured.Unstructured, error) {
+	u := unstructured.Unstructured{}
+	json, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return u, fmt.Errorf("failed to parse YAML in file %q: %w", filePath, err)
+	}
+	if err := u.UnmarshalJSON(json); err != nil {
+		return u, fmt.Errorf("file %q is not a valid Kubernetes resource: %w", filePath, err)
+	}
+	return u, nil
+}
+
 // Directory name constants for stage structure
 // These can be changed if different naming is preferred (e.g., "input-resources" instead of "input")
 const (
@@ -80,8 +140,46 @@ const (
 	PatchesDirName      = "patches"  // patches directory within a stage
 	OutputDirName       = "output"   // output directory within a stage
 	NewResourcesDirName = "new"      // plugin-generated new resources directory within a stage
+	IgnoredDirName      = "ignored"  // patch operations the runner dropped as conflicting, within a stage
 )
 
+// StageMetadataFileName is the record crane transform keeps in each stage directory of how
+// it generated the stage.
+const StageMetadataFileName = ".crane-metadata.json"
+
+// ExportManifestFileName is the export index written by crane export at the root of the
+// export directory. It is not a Kubernetes resource and is skipped when reading manifests.
+const ExportManifestFileName = "manifest.json"
+
+// FailuresDirName holds the list and get errors written by crane export; ReadFiles skips it.
+const FailuresDirName = "failures"
+
+// Directories at the root of an export that hold other renderings of, or metadata about,
+// the objects under resources/. ReadFiles skips them only at the root, so a namespace with
+// the same name is still read.
+const (
+	VersionsDirName       = "versions"        // objects at other served API versions, crane export --alternate-versions
+	CleanResourcesDirName = "resources-clean" // objects without server-populated fields, crane export --normalize
+	HelmDirName           = "helm"            // Helm releases decoded from their release Secrets by crane export
+	PrunedDirName         = "pruned"          // objects dropped by crane export --skip-owned
+)
+
+// IsDerivedExportDir reports whether name, a directory at the root of an export, holds
+// copies of or metadata about the exported objects rather than the objects to migrate.
+func IsDerivedExportDir(name string) bool {
+	return name == VersionsDirName || name == CleanResourcesDirName || name == HelmDirName || name == PrunedDirName
+}
+
+// AlternateVersionDir returns the slash-separated directory, relative to the export root,
+// that holds objects exported at group/version. It has the same resources/ layout as the
+// export root; the core group is written as "core".
+func AlternateVersionDir(group, version string) string {
+	if group == "" {
+		group = "core"
+	}
+	return path.Join(VersionsDirName, group, version)
+}
+
 //TODO: @shawn-hurley Add errors for these methods to validate that the correct struct values are set.
 type PathOpts struct {
 	TransformDir      string
@@ -140,6 +238,12 @@ func (opts *PathOpts) GetPatchesDir(stageName string) string {
 	return filepath.Join(opts.GetStageDir(stageName), PatchesDirName)
 }
 
+// GetIgnoredDir returns the path to the ignored operations directory within a stage
+// Format: <transformDir>/<stageName>/ignored
+func (opts *PathOpts) GetIgnoredDir(stageName string) string {
+	return filepath.Join(opts.GetStageDir(stageName), IgnoredDirName)
+}
+
 // GetKustomizationPath returns the path to kustomization.yaml within a stage
 // Format: <transformDir>/<stageName>/kustomization.yaml
 func (opts *PathOpts) GetKustomizationPath(stageName string) string {
@@ -149,7 +253,7 @@ func (opts *PathOpts) GetKustomizationPath(stageName string) string {
 // GetMetadataPath returns the path to .crane-metadata.json within a stage
 // Format: <transformDir>/<stageName>/.crane-metadata.json
 func (opts *PathOpts) GetMetadataPath(stageName string) string {
-	return filepath.Join(opts.GetStageDir(stageName), ".crane-metadata.json")
+	return filepath.Join(opts.GetStageDir(stageName), StageMetadataFileName)
 }
 
 // GetResourceTypeFilePath returns the path to a resource type file within a stage
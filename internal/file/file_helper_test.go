@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/konveyor/crane/internal/file"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -109,15 +110,87 @@ metadata:
   namespace: default
 `
 	writeFile(t, filepath.Join(dir, "cm.yaml"), validYAML)
-	// files in "failures" dir should be skipped, even if invalid
+	// files in "failures" and "pruned" dirs should be skipped, even if invalid
 	writeFile(t, filepath.Join(dir, "failures", "bad.yaml"), "null")
+	writeFile(t, filepath.Join(dir, file.PrunedDirName, "app.yaml"), "- kind: Pod\n")
+	// alternate API versions, clean copies, Helm releases, and pruned objects at the export root are skipped; namespaces named versions or pruned are not
+	writeFile(t, filepath.Join(dir, file.AlternateVersionDir("", "v1"), "cm.yaml"), validYAML)
+	writeFile(t, filepath.Join(dir, file.CleanResourcesDirName, "default", "cm.yaml"), validYAML)
+	writeFile(t, filepath.Join(dir, file.HelmDirName, "web.yaml"), "name: web\nrevision: 2\n")
+	writeFile(t, filepath.Join(dir, "resources", file.VersionsDirName, "cm.yaml"), validYAML)
+	writeFile(t, filepath.Join(dir, "resources", file.PrunedDirName, "cm.yaml"), validYAML)
 
 	files, err := file.ReadFiles(context.TODO(), dir)
 	if err != nil {
 		t.Fatalf("expected no error (failures dir should be skipped), got: %v", err)
 	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files (skipping failures, pruned, versions, resources-clean, and helm dirs), got %d", len(files))
+	}
+}
+
+func TestReadFilesSkipsExportManifest(t *testing.T) {
+	dir := createTestDir(t)
+	validYAML := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-cm
+  namespace: default
+`
+	writeFile(t, filepath.Join(dir, "resources", "default", "cm.yaml"), validYAML)
+	writeFile(t, filepath.Join(dir, file.ExportManifestFileName), `{"resources":[]}`)
+
+	files, err := file.ReadFiles(context.TODO(), dir)
+	if err != nil {
+		t.Fatalf("expected no error (export manifest should be skipped), got: %v", err)
+	}
 	if len(files) != 1 {
-		t.Fatalf("expected 1 file (skipping failures dir), got %d", len(files))
+		t.Fatalf("expected 1 file (skipping export manifest), got %d", len(files))
+	}
+}
+
+func TestAlternateVersionDir(t *testing.T) {
+	if got := file.AlternateVersionDir("", "v1"); got != "versions/core/v1" {
+		t.Errorf("core group dir = %q", got)
+	}
+	if got := file.AlternateVersionDir("autoscaling", "v2"); got != "versions/autoscaling/v2" {
+		t.Errorf("autoscaling dir = %q", got)
+	}
+}
+
+func TestReadFilesFS(t *testing.T) {
+	validYAML := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-cm
+  namespace: default
+`
+	fsys := fstest.MapFS{
+		"resources/default/cm.yaml":                       {Data: []byte(validYAML)},
+		"failures/default/bad.yaml":                       {Data: []byte("null")},
+		file.PrunedDirName + "/default.yaml":              {Data: []byte("- kind: Pod\n")},
+		file.ExportManifestFileName:                       {Data: []byte(`{"resources":[]}`)},
+		"versions/apps/v1beta2/resources/default/cm.yaml": {Data: []byte(validYAML)},
+		"resources/" + file.PrunedDirName + "/cm.yaml":    {Data: []byte(validYAML)},
+	}
+
+	files, err := file.ReadFilesFS(context.TODO(), fsys)
+	if err != nil {
+		t.Fatalf("ReadFilesFS: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if files[0].Path != "resources/default/cm.yaml" || files[0].Unstructured.GetName() != "test-cm" {
+		t.Errorf("unexpected file %q (%s)", files[0].Path, files[0].Unstructured.GetName())
+	}
+	if files[1].Path != "resources/pruned/cm.yaml" {
+		t.Errorf("expected the pruned namespace to be read, got %q", files[1].Path)
+	}
+
+	fsys["resources/default/bad.yaml"] = &fstest.MapFile{Data: []byte("null")}
+	if _, err := file.ReadFilesFS(context.TODO(), fsys); err == nil || !strings.Contains(err.Error(), "resources/default/bad.yaml") {
+		t.Errorf("expected error naming the invalid file, got %v", err)
 	}
 }
 
@@ -440,6 +513,14 @@ func TestKustomizeLayoutPaths(t *testing.T) {
 		}
 	})
 
+	t.Run("GetIgnoredDir", func(t *testing.T) {
+		result := opts.GetIgnoredDir("10_kubernetes")
+		expected := "/transform/10_kubernetes/ignored"
+		if result != expected {
+			t.Errorf("expected %v, got %v", expected, result)
+		}
+	})
+
 	t.Run("GetKustomizationPath", func(t *testing.T) {
 		result := opts.GetKustomizationPath("10_kubernetes")
 		expected := "/transform/10_kubernetes/kustomization.yaml"
@@ -0,0 +1,207 @@
+// Package helm decodes the Helm 3 release records that Helm stores as Secrets in a
+// release's namespace, so an export can record which chart produced its objects.
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// ReleaseSecretType is the type of the Secrets Helm 3 stores releases in. Each revision of
+// a release is one Secret named sh.helm.release.v1.<release>.v<revision>.
+const ReleaseSecretType = "helm.sh/release.v1"
+
+// ReleaseAnnotation is set by crane export on objects that belong to a Helm release, to
+// "<namespace>/<release>", so a transform stage can tell chart-managed objects apart.
+const ReleaseAnnotation = "crane.konveyor.io/helm-release"
+
+// Helm 3.2 and later annotate the objects it installs with the release that owns them.
+const (
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// Chart identifies the chart a release was installed from.
+type Chart struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+}
+
+// Release is one revision of a Helm release. Values are the values supplied at install or
+// upgrade, not the chart defaults, and Manifest is the rendered manifest Helm applied.
+type Release struct {
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Revision  int                    `json:"revision"`
+	Status    string                 `json:"status,omitempty"`
+	Chart     Chart                  `json:"chart"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+	Manifest  string                 `json:"manifest,omitempty"`
+}
+
+// Key returns "<namespace>/<name>", the value of ReleaseAnnotation.
+func (r *Release) Key() string { return r.Namespace + "/" + r.Name }
+
+// storedRelease is the part of Helm's release record that is decoded.
+type storedRelease struct {
+	Name string `json:"name"`
+	Info struct {
+		Status string `json:"status"`
+	} `json:"info"`
+	Chart struct {
+		Metadata Chart `json:"metadata"`
+	} `json:"chart"`
+	Config    map[string]interface{} `json:"config"`
+	Manifest  string                 `json:"manifest"`
+	Version   int                    `json:"version"`
+	Namespace string                 `json:"namespace"`
+}
+
+// IsReleaseSecret reports whether obj is a core v1 Secret holding a Helm 3 release.
+func IsReleaseSecret(obj unstructured.Unstructured) bool {
+	if obj.GetAPIVersion() != "v1" || obj.GetKind() != "Secret" {
+		return false
+	}
+	t, _, _ := unstructured.NestedString(obj.Object, "type")
+	return t == ReleaseSecretType
+}
+
+// DecodeSecret decodes the release stored in a Helm release Secret. The release record is
+// gzipped JSON, base64-encoded by Helm and again as Secret data.
+func DecodeSecret(obj unstructured.Unstructured) (*Release, error) {
+	data, _, _ := unstructured.NestedString(obj.Object, "data", "release")
+	if data == "" {
+		return nil, fmt.Errorf("secret %s/%s has no release data", obj.GetNamespace(), obj.GetName())
+	}
+	encoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s: decode data: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	r, err := decodeRelease(string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	if r.Namespace == "" {
+		r.Namespace = obj.GetNamespace()
+	}
+	return r, nil
+}
+
+// decodeRelease decodes a release record as Helm stores it. Helm 3 always gzips records;
+// uncompressed JSON is accepted as well.
+func decodeRelease(data string) (*Release, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode release: %w", err)
+	}
+	if bytes.HasPrefix(b, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("decompress release: %w", err)
+		}
+		defer zr.Close()
+		if b, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("decompress release: %w", err)
+		}
+	}
+	var stored storedRelease
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, fmt.Errorf("parse release: %w", err)
+	}
+	return &Release{
+		Name:      stored.Name,
+		Namespace: stored.Namespace,
+		Revision:  stored.Version,
+		Status:    stored.Info.Status,
+		Chart:     stored.Chart.Metadata,
+		Values:    stored.Config,
+		Manifest:  stored.Manifest,
+	}, nil
+}
+
+// ObjectKey identifies an object by group, kind, namespace, and name; the version is left
+// out so objects exported at another version than the chart used still match.
+type ObjectKey struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// KeyOf returns the ObjectKey of obj.
+func KeyOf(obj unstructured.Unstructured) ObjectKey {
+	return ObjectKey{
+		Group:     obj.GroupVersionKind().Group,
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+}
+
+// ManifestObjects returns the keys of the objects in the release's rendered manifest.
+// Objects without a namespace are returned twice, in the release namespace and without
+// one, since the manifest does not say whether their kind is namespaced. Documents that
+// do not parse are ignored.
+func (r *Release) ManifestObjects() []ObjectKey {
+	var keys []ObjectKey
+	for _, doc := range strings.Split(r.Manifest, "\n---") {
+		var meta struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &meta); err != nil || meta.Kind == "" || meta.Metadata.Name == "" {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(meta.APIVersion)
+		if err != nil {
+			continue
+		}
+		key := ObjectKey{Group: gv.Group, Kind: meta.Kind, Namespace: meta.Metadata.Namespace, Name: meta.Metadata.Name}
+		if key.Namespace != "" {
+			keys = append(keys, key)
+			continue
+		}
+		keys = append(keys, key)
+		key.Namespace = r.Namespace
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// RecordedRelease returns the "<namespace>/<release>" that Helm recorded on obj, or "" when
+// it recorded none: the release a release Secret stores, from its name label, or the
+// release of an installed object, from its meta.helm.sh annotations.
+func RecordedRelease(obj unstructured.Unstructured) string {
+	if IsReleaseSecret(obj) {
+		if name := obj.GetLabels()["name"]; name != "" {
+			return obj.GetNamespace() + "/" + name
+		}
+		return ""
+	}
+	annotations := obj.GetAnnotations()
+	name := annotations[helmReleaseNameAnnotation]
+	if name == "" {
+		return ""
+	}
+	namespace := annotations[helmReleaseNamespaceAnnotation]
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+	return namespace + "/" + name
+}
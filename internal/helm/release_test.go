@@ -0,0 +1,118 @@
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testManifest = `---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: app
+`
+
+// releaseSecret returns a Secret as Helm stores revision of release name in namespace.
+func releaseSecret(t *testing.T, namespace, name string, revision int, record string) unstructured.Unstructured {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(record)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stored := base64.StdEncoding.EncodeToString(buf.Bytes())
+	obj := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       ReleaseSecretType,
+		"data":       map[string]interface{}{"release": base64.StdEncoding.EncodeToString([]byte(stored))},
+	}}
+	obj.SetNamespace(namespace)
+	obj.SetName(fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, revision))
+	obj.SetLabels(map[string]string{"name": name, "owner": "helm"})
+	return obj
+}
+
+func TestDecodeSecret(t *testing.T) {
+	record := `{"name":"web","namespace":"app","version":3,"info":{"status":"deployed"},
+"chart":{"metadata":{"name":"web","version":"1.2.0","appVersion":"2.0"},"templates":[]},
+"config":{"replicas":2},"manifest":"---\nkind: Service\n"}`
+	obj := releaseSecret(t, "app", "web", 3, record)
+	if !IsReleaseSecret(obj) {
+		t.Fatal("IsReleaseSecret = false")
+	}
+	r, err := DecodeSecret(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Release{
+		Name:      "web",
+		Namespace: "app",
+		Revision:  3,
+		Status:    "deployed",
+		Chart:     Chart{Name: "web", Version: "1.2.0", AppVersion: "2.0"},
+		Values:    map[string]interface{}{"replicas": float64(2)},
+		Manifest:  "---\nkind: Service\n",
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("DecodeSecret = %+v, want %+v", r, want)
+	}
+
+	unstructured.RemoveNestedField(obj.Object, "data")
+	if _, err := DecodeSecret(obj); err == nil {
+		t.Error("expected an error for a Secret without release data")
+	}
+	unstructured.SetNestedField(obj.Object, "Opaque", "type")
+	if IsReleaseSecret(obj) {
+		t.Error("IsReleaseSecret = true for an Opaque Secret")
+	}
+}
+
+func TestManifestObjects(t *testing.T) {
+	r := &Release{Name: "web", Namespace: "app", Manifest: testManifest}
+	want := []ObjectKey{
+		{Kind: "Service", Name: "web"},
+		{Kind: "Service", Namespace: "app", Name: "web"},
+		{Group: "apps", Kind: "Deployment", Namespace: "app", Name: "web"},
+	}
+	if got := r.ManifestObjects(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ManifestObjects = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecordedRelease(t *testing.T) {
+	installed := unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap"}}
+	installed.SetNamespace("app")
+	installed.SetAnnotations(map[string]string{helmReleaseNameAnnotation: "web", helmReleaseNamespaceAnnotation: "app"})
+	unowned := unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap"}}
+
+	for _, tt := range []struct {
+		obj  unstructured.Unstructured
+		want string
+	}{
+		{obj: releaseSecret(t, "app", "web", 1, "{}"), want: "app/web"},
+		{obj: installed, want: "app/web"},
+		{obj: unowned, want: ""},
+	} {
+		if got := RecordedRelease(tt.obj); got != tt.want {
+			t.Errorf("RecordedRelease(%s) = %q, want %q", tt.obj.GetKind(), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package normalize removes the fields the API server populates from exported objects, so
+// that two exports of the same objects differ only where a user changed something.
+package normalize
+
+import (
+	"github.com/konveyor/crane/internal/helm"
+	"github.com/konveyor/crane/internal/secrets"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// metadataFields are set by the API server on every object.
+var metadataFields = []string{
+	"uid",
+	"resourceVersion",
+	"generation",
+	"creationTimestamp",
+	"deletionTimestamp",
+	"deletionGracePeriodSeconds",
+	"selfLink",
+	"managedFields",
+}
+
+// annotations are written by clients and controllers to record state, not intent.
+var annotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+	"endpoints.kubernetes.io/last-change-trigger-time",
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"pv.kubernetes.io/provisioned-by",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// craneAnnotations are added by crane export to record how it exported an object.
+var craneAnnotations = []string{
+	helm.ReleaseAnnotation,
+	secrets.RedactedFieldsAnnotation,
+}
+
+// HiddenValue stands in for the values of a Secret that crane export encrypted or
+// redacted, see ForDiff.
+const HiddenValue = "<hidden>"
+
+// jobLabels are added by the Job controller to a Job, its pod template, and its Pods.
+var jobLabels = []string{
+	"controller-uid",
+	"batch.kubernetes.io/controller-uid",
+	"job-name",
+	"batch.kubernetes.io/job-name",
+}
+
+// Object removes status and the server-populated metadata, annotations, and spec fields
+// from obj in place: the uid of ownerReferences, the allocated clusterIP of a Service, the
+// bound volumeName of a PersistentVolumeClaim, the nodeName of a Pod, and the selector and
+// labels the Job controller generates. Fields set by users, such as a headless Service's
+// clusterIP "None", are kept. Empty metadata maps left behind are removed.
+func Object(obj *unstructured.Unstructured) {
+	unstructured.RemoveNestedField(obj.Object, "status")
+	for _, f := range metadataFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", f)
+	}
+	removeKeys(obj.Object, annotations, "metadata", "annotations")
+	if refs, ok, _ := unstructured.NestedSlice(obj.Object, "metadata", "ownerReferences"); ok {
+		for _, ref := range refs {
+			if m, ok := ref.(map[string]interface{}); ok {
+				delete(m, "uid")
+			}
+		}
+		_ = unstructured.SetNestedSlice(obj.Object, refs, "metadata", "ownerReferences")
+	}
+	// Pod templates carry a null creationTimestamp.
+	unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(obj.Object, "spec", "jobTemplate", "spec", "template", "metadata", "creationTimestamp")
+
+	group := obj.GroupVersionKind().Group
+	switch {
+	case group == "" && obj.GetKind() == "Service":
+		if ip, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); ip != "None" {
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		}
+		unstructured.RemoveNestedField(obj.Object, "spec", "healthCheckNodePort")
+	case group == "" && obj.GetKind() == "PersistentVolumeClaim":
+		unstructured.RemoveNestedField(obj.Object, "spec", "volumeName")
+	case group == "" && obj.GetKind() == "Pod":
+		unstructured.RemoveNestedField(obj.Object, "spec", "nodeName")
+		removeKeys(obj.Object, jobLabels, "metadata", "labels")
+	case group == "batch" && obj.GetKind() == "Job":
+		removeKeys(obj.Object, jobLabels, "metadata", "labels")
+		if manual, _, _ := unstructured.NestedBool(obj.Object, "spec", "manualSelector"); !manual {
+			unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+		}
+		removeKeys(obj.Object, jobLabels, "spec", "template", "metadata", "labels")
+	}
+
+	for _, fields := range [][]string{
+		{"metadata", "annotations"},
+		{"metadata", "labels"},
+		{"metadata", "ownerReferences"},
+		{"spec", "template", "metadata", "labels"},
+	} {
+		removeIfEmpty(obj.Object, fields...)
+	}
+}
+
+// ForDiff normalizes obj like Object, and also removes what crane export added to it: its
+// annotations and the sops block of an encrypted Secret. The values of a Secret that was
+// encrypted or redacted are replaced with HiddenValue, since the ciphertext changes on
+// every export and redacted values are empty, so such Secrets compare by key set only.
+func ForDiff(obj *unstructured.Unstructured) {
+	hidden := secrets.IsSecret(*obj) && (secrets.IsEncrypted(*obj) || len(secrets.RedactedFields(obj.GetAnnotations())) > 0)
+	removeKeys(obj.Object, craneAnnotations, "metadata", "annotations")
+	if hidden {
+		unstructured.RemoveNestedField(obj.Object, "sops")
+	}
+	Object(obj)
+	if hidden {
+		HideSecretValues(obj)
+	}
+}
+
+// HideSecretValues replaces every data and stringData value of a Secret with HiddenValue,
+// so that it compares equal to a Secret with the same keys. Other objects are unchanged.
+func HideSecretValues(obj *unstructured.Unstructured) {
+	if !secrets.IsSecret(*obj) {
+		return
+	}
+	for _, field := range []string{"data", "stringData"} {
+		if values, ok := obj.Object[field].(map[string]interface{}); ok {
+			for k := range values {
+				values[k] = HiddenValue
+			}
+		}
+	}
+}
+
+// SecretValuesHidden reports whether obj is a Secret whose values ForDiff hid.
+func SecretValuesHidden(obj unstructured.Unstructured) bool {
+	if !secrets.IsSecret(obj) {
+		return false
+	}
+	found := false
+	for _, field := range []string{"data", "stringData"} {
+		values, _ := obj.Object[field].(map[string]interface{})
+		for _, v := range values {
+			if v != HiddenValue {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+// removeKeys deletes keys from the string map at fields.
+func removeKeys(obj map[string]interface{}, keys []string, fields ...string) {
+	m, ok, _ := unstructured.NestedFieldNoCopy(obj, fields...)
+	values, isMap := m.(map[string]interface{})
+	if !ok || !isMap {
+		return
+	}
+	for _, k := range keys {
+		delete(values, k)
+	}
+}
+
+// removeIfEmpty deletes the map or slice at fields when it holds nothing.
+func removeIfEmpty(obj map[string]interface{}, fields ...string) {
+	v, ok, _ := unstructured.NestedFieldNoCopy(obj, fields...)
+	if !ok {
+		return
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			return
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			return
+		}
+	case nil:
+	default:
+		return
+	}
+	unstructured.RemoveNestedField(obj, fields...)
+}
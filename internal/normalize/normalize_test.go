@@ -0,0 +1,157 @@
+package normalize
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestObject(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "server metadata and status",
+			in: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: app
+  uid: 1234
+  resourceVersion: "42"
+  generation: 3
+  creationTimestamp: "2024-01-01T00:00:00Z"
+  managedFields: [{manager: kubectl}]
+  annotations:
+    deployment.kubernetes.io/revision: "3"
+    kubectl.kubernetes.io/last-applied-configuration: "{}"
+    team: web
+  ownerReferences: [{apiVersion: v1, kind: Foo, name: owner, uid: abc}]
+spec:
+  template:
+    metadata:
+      creationTimestamp: null
+      labels: {app: web}
+status:
+  replicas: 1
+`,
+			want: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: app
+  annotations: {team: web}
+  ownerReferences: [{apiVersion: v1, kind: Foo, name: owner}]
+spec:
+  template:
+    metadata:
+      labels: {app: web}
+`,
+		},
+		{
+			name: "allocated clusterIP",
+			in:   "apiVersion: v1\nkind: Service\nmetadata: {name: web}\nspec: {clusterIP: 10.0.0.1, clusterIPs: [10.0.0.1], ports: [{port: 80}]}\n",
+			want: "apiVersion: v1\nkind: Service\nmetadata: {name: web}\nspec: {ports: [{port: 80}]}\n",
+		},
+		{
+			name: "headless Service keeps clusterIP None",
+			in:   "apiVersion: v1\nkind: Service\nmetadata: {name: web}\nspec: {clusterIP: None, clusterIPs: [None]}\n",
+			want: "apiVersion: v1\nkind: Service\nmetadata: {name: web}\nspec: {clusterIP: None, clusterIPs: [None]}\n",
+		},
+		{
+			name: "bound PersistentVolumeClaim",
+			in:   "apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata: {name: data, annotations: {pv.kubernetes.io/bind-completed: \"yes\"}}\nspec: {volumeName: pvc-123}\n",
+			want: "apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata: {name: data}\nspec: {}\n",
+		},
+		{
+			name: "generated Job selector",
+			in: `apiVersion: batch/v1
+kind: Job
+metadata: {name: migrate, labels: {controller-uid: abc, job-name: migrate}}
+spec:
+  selector: {matchLabels: {controller-uid: abc}}
+  template:
+    metadata: {labels: {controller-uid: abc, job-name: migrate, app: db}}
+`,
+			want: `apiVersion: batch/v1
+kind: Job
+metadata: {name: migrate}
+spec:
+  template:
+    metadata: {labels: {app: db}}
+`,
+		},
+		{
+			name: "Service of another group is left alone",
+			in:   "apiVersion: serving.knative.dev/v1\nkind: Service\nmetadata: {name: web}\nspec: {clusterIP: 10.0.0.1}\n",
+			want: "apiVersion: serving.knative.dev/v1\nkind: Service\nmetadata: {name: web}\nspec: {clusterIP: 10.0.0.1}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(tt.in), &obj.Object); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			Object(&obj)
+			if !reflect.DeepEqual(obj.Object, want) {
+				got, _ := yaml.Marshal(obj.Object)
+				t.Errorf("Object() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "crane annotations",
+			in:   "apiVersion: v1\nkind: Service\nmetadata: {name: web, annotations: {crane.konveyor.io/helm-release: app/web}}\n",
+			want: "apiVersion: v1\nkind: Service\nmetadata: {name: web}\n",
+		},
+		{
+			name: "encrypted Secret",
+			in:   "apiVersion: v1\nkind: Secret\nmetadata: {name: db}\ndata: {password: 'ENC[AES256_GCM,data:abc]'}\nsops: {mac: xyz}\n",
+			want: "apiVersion: v1\nkind: Secret\nmetadata: {name: db}\ndata: {password: <hidden>}\n",
+		},
+		{
+			name: "redacted Secret",
+			in:   "apiVersion: v1\nkind: Secret\nmetadata: {name: db, annotations: {crane.konveyor.io/redacted-fields: data.password}}\ndata: {password: ''}\n",
+			want: "apiVersion: v1\nkind: Secret\nmetadata: {name: db}\ndata: {password: <hidden>}\n",
+		},
+		{
+			name: "plain Secret keeps its values",
+			in:   "apiVersion: v1\nkind: Secret\nmetadata: {name: db}\ndata: {password: czNjcjN0}\n",
+			want: "apiVersion: v1\nkind: Secret\nmetadata: {name: db}\ndata: {password: czNjcjN0}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(tt.in), &obj.Object); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			ForDiff(&obj)
+			if !reflect.DeepEqual(obj.Object, want) {
+				got, _ := yaml.Marshal(obj.Object)
+				t.Errorf("ForDiff() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -54,7 +54,7 @@ func getBinaryPlugins(path string, files []os.FileInfo, logger *logrus.Logger) (
 			if err != nil {
 				return nil, err
 			}
-			pluginList = append(pluginList, newPlugin)
+			pluginList = append(pluginList, newBinaryPlugin(filePath, newPlugin, logger))
 		}
 	}
 	return pluginList, nil
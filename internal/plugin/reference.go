@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/konveyor/crane-lib/transform"
+)
+
+// lastAppliedAnnotation is the client-side apply record kubectl leaves on objects.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// referenceLabelFlag is the optional flag of ReferencePlugin.
+const referenceLabelFlag = "reference-label"
+
+// ReferencePlugin is the reference plugin for the ProtocolNDJSON protocol, built from
+// ./internal/plugin/reference. It removes the kubectl last-applied-configuration
+// annotation and, with the reference-label optional flag (key=value), adds a label.
+type ReferencePlugin struct{}
+
+func (ReferencePlugin) Metadata() transform.PluginMetadata {
+	return transform.PluginMetadata{
+		Name:            "ReferencePlugin",
+		Version:         "v1",
+		RequestVersion:  []transform.Version{transform.V1},
+		ResponseVersion: []transform.Version{transform.V1},
+		OptionalFields: []transform.OptionalFields{{
+			FlagName: referenceLabelFlag,
+			Help:     "Label to add to every resource, as key=value",
+			Example:  "migrated-by=crane",
+		}},
+	}
+}
+
+func (ReferencePlugin) Run(request transform.PluginRequest) (transform.PluginResponse, error) {
+	var ops []map[string]interface{}
+	if _, ok := request.GetAnnotations()[lastAppliedAnnotation]; ok {
+		ops = append(ops, map[string]interface{}{"op": "remove", "path": "/metadata/annotations/" + escapePointer(lastAppliedAnnotation)})
+	}
+	if label := request.Extras[referenceLabelFlag]; label != "" {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return transform.PluginResponse{}, fmt.Errorf("invalid %s %q: expected key=value", referenceLabelFlag, label)
+		}
+		if request.GetLabels() == nil {
+			ops = append(ops, map[string]interface{}{"op": "add", "path": "/metadata/labels", "value": map[string]interface{}{}})
+		}
+		ops = append(ops, map[string]interface{}{"op": "add", "path": "/metadata/labels/" + escapePointer(key), "value": value})
+	}
+	response := transform.PluginResponse{Version: string(transform.V1)}
+	if len(ops) == 0 {
+		return response, nil
+	}
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return response, err
+	}
+	response.Patches, err = jsonpatch.DecodePatch(data)
+	return response, err
+}
+
+// escapePointer escapes s for use as a JSON pointer token.
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
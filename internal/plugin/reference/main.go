@@ -0,0 +1,19 @@
+// Command reference is the reference binary plugin for crane's persistent ndjson plugin
+// protocol. Build it into the plugin directory to try the protocol:
+//
+//	go build -o ~/.local/share/crane/plugins/ReferencePlugin ./internal/plugin/reference
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/konveyor/crane/internal/plugin"
+)
+
+func main() {
+	if err := plugin.ServePlugin(plugin.ReferencePlugin{}, os.Stdin, os.Stdout, os.Getenv); err != nil {
+		fmt.Fprintf(os.Stderr, "ReferencePlugin: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,308 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/konveyor/crane-lib/transform"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Binary plugins can offer a persistent mode in which crane starts the plugin once and
+// streams resources to it, instead of starting it once per resource. Crane lists the
+// protocols it speaks in ProtocolsEnv when it asks for the plugin metadata, and a plugin
+// that supports one lists it in the "protocols" field of its metadata. Crane then starts
+// the plugin with ProtocolEnv set to the protocol. Plugins that list no protocols keep
+// the exec-per-resource protocol of crane-lib.
+const (
+	ProtocolsEnv = "CRANE_PLUGIN_PROTOCOLS"
+	ProtocolEnv  = "CRANE_PLUGIN_PROTOCOL"
+
+	// ProtocolNDJSON streams one StreamRequest per line on the plugin's stdin and reads
+	// one StreamResponse per line from its stdout, in order. The plugin exits when its
+	// stdin is closed.
+	ProtocolNDJSON = "ndjson"
+)
+
+// metadataRequest is what crane-lib writes to a binary plugin's stdin to ask for its
+// metadata.
+const metadataRequest = "{}"
+
+// probeTimeout bounds the metadata request made to find the protocols of a plugin.
+const probeTimeout = 10 * time.Second
+
+// StreamMetadata is the metadata a plugin prints, extended with the protocols it supports.
+type StreamMetadata struct {
+	transform.PluginMetadata
+	Protocols []string `json:"protocols,omitempty"`
+}
+
+// StreamRequest is one resource sent to a persistent plugin, with the optional flags of
+// the stage.
+type StreamRequest struct {
+	Object map[string]interface{} `json:"object"`
+	Extras map[string]string      `json:"extras,omitempty"`
+}
+
+// StreamResponse is a persistent plugin's answer to one StreamRequest. Error reports that
+// the plugin could not transform this resource; the session goes on.
+type StreamResponse struct {
+	Response *transform.PluginResponse `json:"response,omitempty"`
+	Error    string                    `json:"error,omitempty"`
+}
+
+// newBinaryPlugin wraps legacy, the crane-lib plugin at path, so that it uses a persistent
+// process if the plugin supports ProtocolNDJSON. The plugin is asked for its protocols on
+// the first Run rather than here, so loading plugins does not start each one twice.
+func newBinaryPlugin(path string, legacy transform.Plugin, log logrus.FieldLogger) transform.Plugin {
+	return &streamPlugin{path: path, legacy: legacy, log: log}
+}
+
+// probeProtocols asks the plugin at path for its metadata and returns the protocols it lists.
+func probeProtocols(path string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(), ProtocolsEnv+"="+ProtocolNDJSON)
+	cmd.Stdin = bytes.NewBufferString(metadataRequest)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var metadata StreamMetadata
+	if err := json.Unmarshal(out, &metadata); err != nil {
+		return nil, fmt.Errorf("parse metadata: %w", err)
+	}
+	return metadata.Protocols, nil
+}
+
+// streamPlugin runs a plugin that supports ProtocolNDJSON. Each concurrent Run uses its own
+// plugin process; processes are started on demand and kept until Close, which the
+// orchestrator calls at the end of each stage. A plugin that does not list the protocol
+// uses the exec-per-resource protocol, and so does one whose process fails, for the rest
+// of the run.
+type streamPlugin struct {
+	path   string
+	legacy transform.Plugin
+	log    logrus.FieldLogger
+
+	probe       sync.Once
+	mu          sync.Mutex
+	idle        []*streamProcess
+	perResource bool
+}
+
+func (p *streamPlugin) Metadata() transform.PluginMetadata {
+	return p.legacy.Metadata()
+}
+
+func (p *streamPlugin) Run(request transform.PluginRequest) (transform.PluginResponse, error) {
+	p.probe.Do(p.negotiate)
+	proc, err := p.acquire()
+	if err != nil {
+		p.fallBack(err)
+		return p.legacy.Run(request)
+	}
+	if proc == nil {
+		return p.legacy.Run(request)
+	}
+	response, runErr, err := proc.run(request)
+	if err != nil {
+		proc.stop()
+		p.fallBack(err)
+		return p.legacy.Run(request)
+	}
+	p.release(proc)
+	return response, runErr
+}
+
+// Close stops the plugin processes. The next Run starts new ones.
+func (p *streamPlugin) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+	var errs []error
+	for _, proc := range idle {
+		if err := proc.stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("stop plugin %s: %v", p.path, errs)
+	}
+	return nil
+}
+
+// negotiate asks the plugin for its protocols and falls back to the exec-per-resource
+// protocol unless it lists ProtocolNDJSON.
+func (p *streamPlugin) negotiate() {
+	protocols, err := probeProtocols(p.path)
+	if err != nil {
+		p.log.Debugf("Plugin %s does not report stream protocols (%v), using exec per resource", p.path, err)
+	}
+	for _, protocol := range protocols {
+		if protocol == ProtocolNDJSON {
+			p.log.Debugf("Plugin %s supports the %s protocol, using a persistent process per stage", p.path, ProtocolNDJSON)
+			return
+		}
+	}
+	p.mu.Lock()
+	p.perResource = true
+	p.mu.Unlock()
+}
+
+// acquire returns an idle plugin process or starts one, or nil once the plugin has
+// fallen back to the exec-per-resource protocol.
+func (p *streamPlugin) acquire() (*streamProcess, error) {
+	p.mu.Lock()
+	if p.perResource {
+		p.mu.Unlock()
+		return nil, nil
+	}
+	if n := len(p.idle); n > 0 {
+		proc := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return proc, nil
+	}
+	p.mu.Unlock()
+	return startStreamProcess(p.path)
+}
+
+func (p *streamPlugin) release(proc *streamProcess) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.perResource {
+		proc.stop()
+		return
+	}
+	p.idle = append(p.idle, proc)
+}
+
+func (p *streamPlugin) fallBack(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.perResource {
+		return
+	}
+	p.perResource = true
+	p.log.Warnf("Plugin %s failed in %s mode: %v; falling back to running it once per resource", p.path, ProtocolNDJSON, err)
+	for _, proc := range p.idle {
+		proc.stop()
+	}
+	p.idle = nil
+}
+
+// streamProcess is one running plugin process in a ProtocolNDJSON session.
+type streamProcess struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	enc   *json.Encoder
+	dec   *json.Decoder
+}
+
+func startStreamProcess(path string) (*streamProcess, error) {
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), ProtocolEnv+"="+ProtocolNDJSON)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &streamProcess{cmd: cmd, stdin: stdin, enc: json.NewEncoder(stdin), dec: json.NewDecoder(stdout)}, nil
+}
+
+// run sends request to the process and returns the plugin's response and the error it
+// reported for the resource. err is set when the session itself failed.
+func (s *streamProcess) run(request transform.PluginRequest) (response transform.PluginResponse, runErr error, err error) {
+	if err := s.enc.Encode(StreamRequest{Object: request.Object, Extras: request.Extras}); err != nil {
+		return response, nil, fmt.Errorf("send request: %w", err)
+	}
+	var reply StreamResponse
+	if err := s.dec.Decode(&reply); err != nil {
+		return response, nil, fmt.Errorf("read response: %w", err)
+	}
+	if reply.Error != "" {
+		return response, fmt.Errorf("%s", reply.Error), nil
+	}
+	if reply.Response == nil {
+		return response, nil, fmt.Errorf("response has neither a result nor an error")
+	}
+	return *reply.Response, nil, nil
+}
+
+// stop closes the process's stdin, which ends the session, and waits for it to exit.
+func (s *streamProcess) stop() error {
+	s.stdin.Close()
+	return s.cmd.Wait()
+}
+
+// ServePlugin runs p as a binary plugin that supports ProtocolNDJSON, reading from in and
+// writing to out. Started with ProtocolEnv set, it serves a stream session until in is
+// closed. Otherwise it answers a metadata request with p's metadata and the protocols it
+// supports, or transforms the single resource read from in, as crane-lib plugins do.
+func ServePlugin(p transform.Plugin, in io.Reader, out io.Writer, getenv func(string) string) error {
+	if protocol := getenv(ProtocolEnv); protocol != "" {
+		if protocol != ProtocolNDJSON {
+			return fmt.Errorf("unsupported protocol %q", protocol)
+		}
+		return serveStream(p, in, out)
+	}
+
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if s := string(bytes.TrimSpace(data)); s == "" || s == metadataRequest {
+		return json.NewEncoder(out).Encode(StreamMetadata{PluginMetadata: p.Metadata(), Protocols: []string{ProtocolNDJSON}})
+	}
+	var request transform.PluginRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return fmt.Errorf("decode resource: %w", err)
+	}
+	response, err := p.Run(request)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(out).Encode(response)
+}
+
+// serveStream answers StreamRequests from in until it is closed.
+func serveStream(p transform.Plugin, in io.Reader, out io.Writer) error {
+	dec := json.NewDecoder(in)
+	enc := json.NewEncoder(out)
+	for {
+		var request StreamRequest
+		if err := dec.Decode(&request); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("decode request: %w", err)
+		}
+		var reply StreamResponse
+		response, err := p.Run(transform.PluginRequest{Unstructured: unstructured.Unstructured{Object: request.Object}, Extras: request.Extras})
+		if err != nil {
+			reply.Error = err.Error()
+		} else {
+			reply.Response = &response
+		}
+		if err := enc.Encode(reply); err != nil {
+			return fmt.Errorf("encode response: %w", err)
+		}
+	}
+}
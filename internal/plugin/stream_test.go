@@ -0,0 +1,251 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/konveyor/crane-lib/transform"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// testPluginEnv makes the test binary act as a binary plugin, so the conformance tests can
+// start it: "reference" serves ReferencePlugin, "legacy" only knows the exec-per-resource
+// protocol, and "crash" offers ProtocolNDJSON but exits on its first streamed request.
+const testPluginEnv = "CRANE_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testPluginEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "reference":
+		if err := ServePlugin(ReferencePlugin{}, os.Stdin, os.Stdout, os.Getenv); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "legacy":
+		fmt.Println(`{"name":"LegacyPlugin","version":"v1"}`)
+	case "crash":
+		if os.Getenv(ProtocolEnv) != "" {
+			os.Exit(3)
+		}
+		ServePlugin(ReferencePlugin{}, os.Stdin, os.Stdout, os.Getenv)
+	}
+	os.Exit(0)
+}
+
+// fakeLegacyPlugin stands in for the crane-lib exec-per-resource plugin.
+type fakeLegacyPlugin struct {
+	calls atomic.Int32
+}
+
+func (f *fakeLegacyPlugin) Metadata() transform.PluginMetadata {
+	return transform.PluginMetadata{Name: "ReferencePlugin", Version: "v1"}
+}
+
+func (f *fakeLegacyPlugin) Run(transform.PluginRequest) (transform.PluginResponse, error) {
+	f.calls.Add(1)
+	return transform.PluginResponse{Version: "legacy"}, nil
+}
+
+// testPlugin returns the plugin newBinaryPlugin makes of the test binary acting as mode.
+func testPlugin(t *testing.T, mode string, legacy transform.Plugin) transform.Plugin {
+	t.Helper()
+	t.Setenv(testPluginEnv, mode)
+	path, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return newBinaryPlugin(path, legacy, log)
+}
+
+func referenceRequest(name string, extras map[string]string) transform.PluginRequest {
+	u := unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind("ConfigMap")
+	u.SetName(name)
+	u.SetAnnotations(map[string]string{lastAppliedAnnotation: "{}"})
+	return transform.PluginRequest{Unstructured: u, Extras: extras}
+}
+
+func patchPaths(t *testing.T, response transform.PluginResponse) string {
+	t.Helper()
+	var paths []string
+	for _, op := range response.Patches {
+		path, err := op.Path()
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, op.Kind()+" "+path)
+	}
+	return strings.Join(paths, ", ")
+}
+
+func TestServePlugin(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	var out bytes.Buffer
+	if err := ServePlugin(ReferencePlugin{}, strings.NewReader(metadataRequest), &out, noEnv); err != nil {
+		t.Fatal(err)
+	}
+	var metadata StreamMetadata
+	if err := json.Unmarshal(out.Bytes(), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Name != "ReferencePlugin" || len(metadata.Protocols) != 1 || metadata.Protocols[0] != ProtocolNDJSON {
+		t.Errorf("metadata = %s", out.String())
+	}
+
+	streamEnv := func(key string) string {
+		if key == ProtocolEnv {
+			return ProtocolNDJSON
+		}
+		return ""
+	}
+	in := `{"object":{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}},"extras":{"reference-label":"team=a"}}
+{"object":{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"b"}},"extras":{"reference-label":"bad"}}
+`
+	out.Reset()
+	if err := ServePlugin(ReferencePlugin{}, strings.NewReader(in), &out, streamEnv); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d response lines, want 2:\n%s", len(lines), out.String())
+	}
+	var first, second StreamResponse
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	if first.Response == nil || patchPaths(t, *first.Response) != "add /metadata/labels, add /metadata/labels/team" {
+		t.Errorf("first response = %s", lines[0])
+	}
+	if second.Response != nil || !strings.Contains(second.Error, "expected key=value") {
+		t.Errorf("second response = %s", lines[1])
+	}
+}
+
+// TestStreamPlugin_Conformance runs the reference plugin as a persistent process, as the
+// transform orchestrator does, from several workers at once.
+func TestStreamPlugin_Conformance(t *testing.T) {
+	legacy := &fakeLegacyPlugin{}
+	p := testPlugin(t, "reference", legacy)
+	stream, ok := p.(*streamPlugin)
+	if !ok {
+		t.Fatalf("newBinaryPlugin returned %T, want a stream plugin", p)
+	}
+	if stream.Metadata().Name != "ReferencePlugin" {
+		t.Errorf("Metadata().Name = %q", stream.Metadata().Name)
+	}
+
+	for round := 0; round < 2; round++ {
+		var wg sync.WaitGroup
+		errs := make(chan error, 40)
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 10; i++ {
+					response, err := p.Run(referenceRequest(fmt.Sprintf("cm-%d-%d", w, i), map[string]string{referenceLabelFlag: "team=a"}))
+					if err != nil {
+						errs <- err
+						continue
+					}
+					if got := patchPaths(t, response); got != "remove /metadata/annotations/kubectl.kubernetes.io~1last-applied-configuration, add /metadata/labels, add /metadata/labels/team" {
+						errs <- fmt.Errorf("patches = %s", got)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+
+		// A resource the plugin rejects fails alone; the session goes on.
+		if _, err := p.Run(referenceRequest("bad", map[string]string{referenceLabelFlag: "bad"})); err == nil || !strings.Contains(err.Error(), "expected key=value") {
+			t.Errorf("expected the plugin's error for an invalid label, got %v", err)
+		}
+		if _, err := p.Run(referenceRequest("after", nil)); err != nil {
+			t.Errorf("Run after a rejected resource: %v", err)
+		}
+		// Close ends the stage; the next round starts new processes.
+		if err := stream.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+	if n := legacy.calls.Load(); n != 0 {
+		t.Errorf("exec-per-resource plugin was called %d time(s)", n)
+	}
+}
+
+func TestNewBinaryPlugin_LegacyPlugin(t *testing.T) {
+	legacy := &fakeLegacyPlugin{}
+	p := testPlugin(t, "legacy", legacy)
+	for i := 0; i < 2; i++ {
+		response, err := p.Run(referenceRequest("cm", nil))
+		if err != nil || response.Version != "legacy" {
+			t.Fatalf("Run = %+v, %v; want the exec-per-resource plugin's response", response, err)
+		}
+	}
+	if n := legacy.calls.Load(); n != 2 {
+		t.Errorf("exec-per-resource plugin was called %d time(s), want 2", n)
+	}
+}
+
+// TestServePlugin_ExecPerResource runs the reference plugin once for a resource, as the
+// crane-lib binary plugin does, and checks that the stage flags reach it.
+func TestServePlugin_ExecPerResource(t *testing.T) {
+	path, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := json.Marshal(referenceRequest("cm", map[string]string{referenceLabelFlag: "team=a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), testPluginEnv+"=reference")
+	cmd.Stdin = bytes.NewReader(request)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("plugin: %v", err)
+	}
+	var response transform.PluginResponse
+	if err := json.Unmarshal(out, &response); err != nil {
+		t.Fatalf("decode response %s: %v", out, err)
+	}
+	if got := patchPaths(t, response); got != "remove /metadata/annotations/kubectl.kubernetes.io~1last-applied-configuration, add /metadata/labels, add /metadata/labels/team" {
+		t.Errorf("patches = %s", got)
+	}
+}
+
+func TestStreamPlugin_FallsBackWhenProcessFails(t *testing.T) {
+	legacy := &fakeLegacyPlugin{}
+	p := testPlugin(t, "crash", legacy)
+	if _, ok := p.(*streamPlugin); !ok {
+		t.Fatalf("newBinaryPlugin returned %T, want a stream plugin", p)
+	}
+	for i := 0; i < 3; i++ {
+		response, err := p.Run(referenceRequest("cm", nil))
+		if err != nil || response.Version != "legacy" {
+			t.Fatalf("Run = %+v, %v; want the exec-per-resource plugin's response", response, err)
+		}
+	}
+	if n := legacy.calls.Load(); n != 3 {
+		t.Errorf("exec-per-resource plugin was called %d time(s), want 3", n)
+	}
+}
@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Modes for protecting Secret values in an export, recorded in the export manifest.
+const (
+	ModeEncrypted = "encrypted"
+	ModeRedacted  = "redacted"
+)
+
+// RedactedFieldsAnnotation lists the data and stringData fields of a Secret whose values
+// were removed by Redact, e.g. "data.password,stringData.token". Redacted values are empty
+// strings, so a redacted Secret applied as-is creates a Secret without credentials.
+const RedactedFieldsAnnotation = "crane.konveyor.io/redacted-fields"
+
+// valueFields are the Secret fields whose values are encrypted or redacted.
+var valueFields = []string{"data", "stringData"}
+
+// IsSecret reports whether obj is a core v1 Secret.
+func IsSecret(obj unstructured.Unstructured) bool {
+	return obj.GetAPIVersion() == "v1" && obj.GetKind() == "Secret"
+}
+
+// Redact replaces every data and stringData value of a Secret with an empty string and
+// lists the affected fields in RedactedFieldsAnnotation. It returns the redacted fields.
+func Redact(obj *unstructured.Unstructured) ([]string, error) {
+	var redacted []string
+	for _, field := range valueFields {
+		values, err := secretValues(obj, field)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range sortedKeys(values) {
+			values[k] = ""
+			redacted = append(redacted, field+"."+k)
+		}
+	}
+	if len(redacted) == 0 {
+		return nil, nil
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[RedactedFieldsAnnotation] = strings.Join(redacted, ",")
+	obj.SetAnnotations(annotations)
+	return redacted, nil
+}
+
+// RedactedFields returns the fields listed in RedactedFieldsAnnotation, or nil when the
+// object was not redacted.
+func RedactedFields(annotations map[string]string) []string {
+	v := strings.TrimSpace(annotations[RedactedFieldsAnnotation])
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// secretValues returns the map stored in obj.Object[field], or nil when the field is unset.
+// Values must be strings, as they are for Secrets read from the API server.
+func secretValues(obj *unstructured.Unstructured, field string) (map[string]interface{}, error) {
+	raw, ok := obj.Object[field]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s %s/%s: %s is not a map", obj.GetKind(), obj.GetNamespace(), obj.GetName(), field)
+	}
+	for k, v := range values {
+		if _, ok := v.(string); !ok {
+			return nil, fmt.Errorf("%s %s/%s: %s.%s is not a string", obj.GetKind(), obj.GetNamespace(), obj.GetName(), field, k)
+		}
+	}
+	return values, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,51 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRedact(t *testing.T) {
+	obj := newSecret()
+	fields, err := Redact(obj)
+	if err != nil {
+		t.Fatalf("Redact: %v", err)
+	}
+	want := []string{"data.empty", "data.password", "stringData.user"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+	if got := RedactedFields(obj.GetAnnotations()); !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactedFields = %v, want %v", got, want)
+	}
+	for _, path := range [][]string{{"data", "password"}, {"stringData", "user"}} {
+		if v, _, _ := unstructured.NestedString(obj.Object, path...); v != "" {
+			t.Errorf("%v = %q, want empty", path, v)
+		}
+	}
+}
+
+func TestRedactWithoutValues(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "empty", "namespace": "app"},
+	}}
+	fields, err := Redact(obj)
+	if err != nil {
+		t.Fatalf("Redact: %v", err)
+	}
+	if fields != nil || obj.GetAnnotations() != nil {
+		t.Errorf("expected nothing to be redacted, got %v and annotations %v", fields, obj.GetAnnotations())
+	}
+}
+
+func TestRedactRejectsNonStringValues(t *testing.T) {
+	obj := newSecret()
+	obj.Object["data"] = map[string]interface{}{"n": int64(1)}
+	if _, err := Redact(obj); err == nil {
+		t.Fatal("expected error for non-string value")
+	}
+}
@@ -0,0 +1,213 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Encrypted Secrets borrow the SOPS value encoding: every data and stringData value is
+// replaced by an ENC[AES256_GCM,...] string whose additional data is the value's path
+// ("data:password:"), and a top-level sops block holds the encrypted MAC. The MAC only
+// covers encrypted values (mac_only_encrypted), so transforms may still edit metadata.
+//
+// They are not SOPS files: the key file holds the 256-bit data key itself rather than a
+// SOPS master key, so the sops block has no key groups and sops cannot decrypt them. Only
+// crane apply, given the same key file, can. age identities are not supported.
+const (
+	sopsField      = "sops"
+	sopsVersion    = "3.9.0"
+	encryptedRegex = "^(data|stringData)$"
+	keySize        = 32
+	nonceSize      = 32
+)
+
+var encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// LoadKey reads a 256-bit AES key from path. The file holds either the 32 raw key bytes
+// or their hex or base64 encoding; surrounding whitespace is ignored for the encoded forms.
+func LoadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file %q: %w", path, err)
+	}
+	if len(data) == keySize {
+		return data, nil
+	}
+	text := bytes.TrimSpace(data)
+	if bytes.Contains(text, []byte("AGE-SECRET-KEY-")) {
+		return nil, fmt.Errorf("key file %q holds an age identity; only 256-bit AES keys are supported (generate one with: openssl rand -hex 32)", path)
+	}
+	if key, err := hex.DecodeString(string(text)); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(string(text)); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key file %q must hold a 256-bit AES key as 32 raw bytes, 64 hex characters, or base64", path)
+}
+
+// IsEncrypted reports whether obj carries a SOPS metadata block written by Encrypt.
+func IsEncrypted(obj unstructured.Unstructured) bool {
+	_, ok := obj.Object[sopsField]
+	return ok
+}
+
+// Encrypt encrypts the data and stringData values of a Secret in place with key and adds
+// the sops metadata block. Empty values are left empty, as SOPS does.
+func Encrypt(obj *unstructured.Unstructured, key []byte, now time.Time) error {
+	if IsEncrypted(*obj) {
+		return fmt.Errorf("%s %s/%s is already encrypted", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	mac := sha512.New()
+	for _, field := range valueFields {
+		values, err := secretValues(obj, field)
+		if err != nil {
+			return err
+		}
+		for _, k := range sortedKeys(values) {
+			plaintext := values[k].(string)
+			mac.Write([]byte(plaintext))
+			if plaintext == "" {
+				continue
+			}
+			if values[k], err = encrypt(aead, plaintext, field+":"+k+":"); err != nil {
+				return err
+			}
+		}
+	}
+	lastModified := now.UTC().Format(time.RFC3339)
+	encryptedMAC, err := encrypt(aead, fmt.Sprintf("%X", mac.Sum(nil)), lastModified)
+	if err != nil {
+		return err
+	}
+	obj.Object[sopsField] = map[string]interface{}{
+		"mac":                encryptedMAC,
+		"lastmodified":       lastModified,
+		"encrypted_regex":    encryptedRegex,
+		"mac_only_encrypted": true,
+		"version":            sopsVersion,
+	}
+	return nil
+}
+
+// Decrypt reverses Encrypt: it decrypts the data and stringData values of obj in place,
+// verifies the MAC, and removes the sops metadata block.
+func Decrypt(obj *unstructured.Unstructured, key []byte) error {
+	id := fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	meta, ok := obj.Object[sopsField].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s has no sops metadata", id)
+	}
+	lastModified, _ := meta["lastmodified"].(string)
+	encryptedMAC, _ := meta["mac"].(string)
+	if lastModified == "" || encryptedMAC == "" {
+		return fmt.Errorf("%s: sops metadata has no mac or lastmodified", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	decrypted := map[string]map[string]interface{}{}
+	mac := sha512.New()
+	for _, field := range valueFields {
+		values, err := secretValues(obj, field)
+		if err != nil {
+			return err
+		}
+		out := make(map[string]interface{}, len(values))
+		for _, k := range sortedKeys(values) {
+			plaintext := values[k].(string)
+			if plaintext != "" {
+				if plaintext, err = decrypt(aead, plaintext, field+":"+k+":"); err != nil {
+					return fmt.Errorf("%s: decrypt %s.%s: %w", id, field, k, err)
+				}
+			}
+			mac.Write([]byte(plaintext))
+			out[k] = plaintext
+		}
+		if values != nil {
+			decrypted[field] = out
+		}
+	}
+	wantMAC, err := decrypt(aead, encryptedMAC, lastModified)
+	if err != nil {
+		return fmt.Errorf("%s: decrypt mac: %w", id, err)
+	}
+	if fmt.Sprintf("%X", mac.Sum(nil)) != wantMAC {
+		return fmt.Errorf("%s: mac mismatch; encrypted values were modified", id)
+	}
+
+	for field, values := range decrypted {
+		obj.Object[field] = values
+	}
+	delete(obj.Object, sopsField)
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, nonceSize)
+}
+
+func encrypt(aead cipher.AEAD, plaintext, additionalData string) (string, error) {
+	iv := make([]byte, nonceSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("generate iv: %w", err)
+	}
+	sealed := aead.Seal(nil, iv, []byte(plaintext), []byte(additionalData))
+	tagStart := len(sealed) - aead.Overhead()
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(sealed[:tagStart]),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(sealed[tagStart:])), nil
+}
+
+func decrypt(aead cipher.AEAD, value, additionalData string) (string, error) {
+	m := encryptedValue.FindStringSubmatch(value)
+	if m == nil {
+		return "", errors.New("value is not in ENC[AES256_GCM,...] format")
+	}
+	if m[4] != "str" {
+		return "", fmt.Errorf("unsupported value type %q", m[4])
+	}
+	var parts [3][]byte
+	for i := range parts {
+		b, err := base64.StdEncoding.DecodeString(m[i+1])
+		if err != nil {
+			return "", fmt.Errorf("decode encrypted value: %w", err)
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+	if len(iv) != nonceSize {
+		return "", fmt.Errorf("iv must be %d bytes, got %d", nonceSize, len(iv))
+	}
+	plaintext, err := aead.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", errors.New("authentication failed; wrong key or tampered value")
+	}
+	return string(plaintext), nil
+}
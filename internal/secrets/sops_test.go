@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, keySize)
+
+func newSecret() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "app"},
+		"type":       "Opaque",
+		"data":       map[string]interface{}{"password": "czNjcjN0", "empty": ""},
+		"stringData": map[string]interface{}{"user": "admin"},
+	}}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	obj := newSecret()
+	want := newSecret().Object
+	if err := Encrypt(obj, testKey, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(*obj) {
+		t.Fatal("expected sops metadata after Encrypt")
+	}
+	password, _, _ := unstructured.NestedString(obj.Object, "data", "password")
+	if !strings.HasPrefix(password, "ENC[AES256_GCM,data:") || strings.Contains(password, "czNjcjN0") {
+		t.Errorf("data.password not encrypted: %q", password)
+	}
+	if empty, _, _ := unstructured.NestedString(obj.Object, "data", "empty"); empty != "" {
+		t.Errorf("empty value should stay empty, got %q", empty)
+	}
+	if lm, _, _ := unstructured.NestedString(obj.Object, "sops", "lastmodified"); lm != "2024-05-01T12:00:00Z" {
+		t.Errorf("lastmodified = %q", lm)
+	}
+	if err := Encrypt(obj, testKey, time.Now()); err == nil {
+		t.Error("expected error encrypting twice")
+	}
+
+	if err := Decrypt(obj, testKey); err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !reflect.DeepEqual(obj.Object, want) {
+		t.Errorf("round trip mismatch:\n got %v\nwant %v", obj.Object, want)
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(obj *unstructured.Unstructured)
+		key     []byte
+		wantErr string
+	}{
+		{name: "wrong key", key: bytes.Repeat([]byte{0x01}, keySize), wantErr: "authentication failed"},
+		{
+			name: "value moved to another key",
+			mutate: func(obj *unstructured.Unstructured) {
+				v, _, _ := unstructured.NestedString(obj.Object, "data", "password")
+				_ = unstructured.SetNestedField(obj.Object, v, "data", "other")
+			},
+			wantErr: "authentication failed",
+		},
+		{
+			name: "value removed",
+			mutate: func(obj *unstructured.Unstructured) {
+				unstructured.RemoveNestedField(obj.Object, "stringData", "user")
+			},
+			wantErr: "mac mismatch",
+		},
+		{
+			name: "metadata edited",
+			mutate: func(obj *unstructured.Unstructured) {
+				obj.SetLabels(map[string]string{"app": "db"})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := newSecret()
+			if err := Encrypt(obj, testKey, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			if tt.mutate != nil {
+				tt.mutate(obj)
+			}
+			key := tt.key
+			if key == nil {
+				key = testKey
+			}
+			err := Decrypt(obj, key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content []byte
+		wantErr string
+	}{
+		{name: "raw", content: testKey},
+		{name: "hex", content: []byte(hex.EncodeToString(testKey) + "\n")},
+		{name: "base64", content: []byte(base64.StdEncoding.EncodeToString(testKey) + "\n")},
+		{name: "short", content: []byte("abcd\n"), wantErr: "256-bit AES key"},
+		{name: "age identity", content: []byte("# created: 2024-05-01\nAGE-SECRET-KEY-1QQQ\n"), wantErr: "age identity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, tt.content, 0600); err != nil {
+				t.Fatal(err)
+			}
+			key, err := LoadKey(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKey: %v", err)
+			}
+			if !bytes.Equal(key, testKey) {
+				t.Errorf("key = %x, want %x", key, testKey)
+			}
+		})
+	}
+}
@@ -22,12 +22,22 @@ var unknownInstructionsFieldRegex = regexp.MustCompile(`line ([0-9]+): field ([^
 // instead of an object containing top-level "stages".
 var rootSequenceInstructionsRegex = regexp.MustCompile(`line ([0-9]+): cannot unmarshal !!seq into .*InstructionsFile`)
 
+// ExportInput is the stage input that names the export directory itself.
+const ExportInput = "export"
+
 // StageEntry represents a single stage in the instructions file.
 // It can be specified as either a plain string (just the name) or an object
 // with name and optional per-stage flags.
+//
+// Inputs lists the stages (by name or output name) whose output the stage reads, or
+// ExportInput for the export. A stage without inputs reads the output of the stage
+// listed before it, or the export if it is listed first. Output names the stage's
+// output for the inputs of later stages; it defaults to the stage name.
 type StageEntry struct {
 	Name      string            `yaml:"name"`
 	Optionals map[string]string `yaml:"optionals,omitempty"`
+	Inputs    []string          `yaml:"inputs,omitempty"`
+	Output    string            `yaml:"output,omitempty"`
 }
 
 type InstructionsFile struct {
@@ -70,8 +80,8 @@ func (f *InstructionsFile) UnmarshalYAML(value *yamlv3.Node) error {
 			// Check for unknown keys in the stage entry
 			for j := 0; j+1 < len(node.Content); j += 2 {
 				key := node.Content[j].Value
-				if key != "name" && key != "optionals" {
-					return fmt.Errorf("stage at index %d: unknown field %q (supported fields: name, optionals)", i, key)
+				if key != "name" && key != "optionals" && key != "inputs" && key != "output" {
+					return fmt.Errorf("stage at index %d: unknown field %q (supported fields: name, optionals, inputs, output)", i, key)
 				}
 			}
 			f.Stages = append(f.Stages, entry)
@@ -160,7 +170,130 @@ func ValidateInstructions(cfg *InstructionsFile) error {
 
 		cfg.Stages[i].Name = stage
 	}
-	return nil
+
+	// Output names share the namespace of stage names, since inputs may use either.
+	outputs := make(map[string]string, len(cfg.Stages))
+	for i := range cfg.Stages {
+		entry := &cfg.Stages[i]
+		entry.Output = strings.TrimSpace(entry.Output)
+		if entry.Output == "" {
+			continue
+		}
+		if !stageTokenRegex.MatchString(entry.Output) {
+			return fmt.Errorf("stage %q: output %q contains invalid characters (allowed: letters, digits, '_' and '-')", entry.Name, entry.Output)
+		}
+		if _, isStage := seen[entry.Output]; isStage && entry.Output != entry.Name {
+			return fmt.Errorf("stage %q: output %q is the name of another stage", entry.Name, entry.Output)
+		}
+		if other, exists := outputs[entry.Output]; exists {
+			return fmt.Errorf("stage %q: output %q is already the output of stage %q", entry.Name, entry.Output, other)
+		}
+		outputs[entry.Output] = entry.Name
+	}
+	for i := range cfg.Stages {
+		entry := &cfg.Stages[i]
+		if entry.Name == ExportInput || entry.Output == ExportInput {
+			return fmt.Errorf("stage %q: %q is reserved for the export input", entry.Name, ExportInput)
+		}
+		for j, input := range entry.Inputs {
+			input = strings.TrimSpace(input)
+			if input == "" {
+				return fmt.Errorf("stage %q: input at index %d is empty", entry.Name, j)
+			}
+			entry.Inputs[j] = input
+		}
+	}
+	_, err := cfg.StageGraph()
+	return err
+}
+
+// StageGraph resolves the inputs of every stage to stage names and returns the stages
+// in execution order: each stage after the stages it reads. Stages keep the order of
+// the instructions file where their inputs allow it. An empty input list stands for
+// the export. It fails on unknown inputs and on cycles.
+func (f *InstructionsFile) StageGraph() ([]ResolvedStage, error) {
+	byName := make(map[string]string, len(f.Stages)*2)
+	for _, s := range f.Stages {
+		byName[s.Name] = s.Name
+		if s.Output != "" {
+			byName[s.Output] = s.Name
+		}
+	}
+
+	inputs := make(map[string][]string, len(f.Stages))
+	for i, s := range f.Stages {
+		if len(s.Inputs) == 0 {
+			inputs[s.Name] = []string{}
+			if i > 0 {
+				inputs[s.Name] = []string{f.Stages[i-1].Name}
+			}
+			continue
+		}
+		resolved := []string{}
+		seen := make(map[string]bool, len(s.Inputs))
+		for _, input := range s.Inputs {
+			if input == ExportInput {
+				continue
+			}
+			name, ok := byName[input]
+			if !ok {
+				return nil, fmt.Errorf("stage %q: unknown input %q (inputs name a stage, a stage output, or %q)", s.Name, input, ExportInput)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("stage %q: input %q is listed more than once", s.Name, input)
+			}
+			seen[name] = true
+			resolved = append(resolved, name)
+		}
+		if len(resolved) > 0 && len(resolved) < len(s.Inputs) {
+			return nil, fmt.Errorf("stage %q: %q cannot be combined with other inputs", s.Name, ExportInput)
+		}
+		inputs[s.Name] = resolved
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(f.Stages))
+	var order []ResolvedStage
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != name {
+				start++
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("stage inputs form a cycle: %s", strings.Join(cycle, " -> "))
+		}
+		state[name] = visiting
+		for _, input := range inputs[name] {
+			if err := visit(input, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		order = append(order, ResolvedStage{Name: name, Inputs: inputs[name]})
+		return nil
+	}
+	for _, s := range f.Stages {
+		if err := visit(s.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// ResolvedStage is a stage of the instructions file with its inputs resolved to stage
+// names. Empty Inputs means the stage reads the export.
+type ResolvedStage struct {
+	Name   string
+	Inputs []string
 }
 
 // StageNames returns the stage names from the instructions file as a string slice.
@@ -192,6 +325,29 @@ func (f *InstructionsFile) StageOptionals() (map[string]map[string]string, error
 	return result, nil
 }
 
+// StageDirInputs maps the directory name of every stage in graph, as generated by
+// GenerateStageDirNames from the graph order, to the directory names of its inputs.
+func StageDirInputs(graph []ResolvedStage) map[string][]string {
+	names := make([]string, len(graph))
+	for i, s := range graph {
+		names[i] = s.Name
+	}
+	dirNames := GenerateStageDirNames(names)
+	dirOf := make(map[string]string, len(graph))
+	for i, s := range graph {
+		dirOf[s.Name] = dirNames[i]
+	}
+	result := make(map[string][]string, len(graph))
+	for i, s := range graph {
+		inputs := make([]string, len(s.Inputs))
+		for j, input := range s.Inputs {
+			inputs[j] = dirOf[input]
+		}
+		result[dirNames[i]] = inputs
+	}
+	return result
+}
+
 // GenerateStageDirNames converts ordered stage tokens into deterministic stage
 // directory names using 10-step numeric prefixes (10_, 20_, 30_, ...).
 func GenerateStageDirNames(stageTokens []string) []string {
@@ -371,3 +371,119 @@ func TestLoadInstructions_RootSequenceFailsWithFriendlyMessage(t *testing.T) {
 		t.Fatalf("expected root mapping guidance in error, got %v", err)
 	}
 }
+
+// Stages with inputs and outputs resolve to an execution order and input directories.
+func TestLoadInstructions_StageGraph(t *testing.T) {
+	tmpDir := t.TempDir()
+	instructionsFilePath := filepath.Join(tmpDir, "graph.yaml")
+
+	content := []byte(`stages:
+  - KubernetesPlugin
+  - name: Merge
+    inputs: [prod, staging]
+  - name: ProdEdits
+    inputs: [KubernetesPlugin]
+    output: prod
+  - name: StagingEdits
+    inputs: [KubernetesPlugin]
+    output: staging
+  - name: Raw
+    inputs: [export]
+`)
+	if err := os.WriteFile(instructionsFilePath, content, 0o600); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadInstructions(instructionsFilePath)
+	if err != nil {
+		t.Fatalf("LoadInstructions failed: %v", err)
+	}
+	graph, err := cfg.StageGraph()
+	if err != nil {
+		t.Fatalf("StageGraph failed: %v", err)
+	}
+	var order []string
+	for _, s := range graph {
+		order = append(order, s.Name)
+	}
+	wantOrder := "KubernetesPlugin,ProdEdits,StagingEdits,Merge,Raw"
+	if strings.Join(order, ",") != wantOrder {
+		t.Fatalf("order = %v, want %s", order, wantOrder)
+	}
+
+	inputs := StageDirInputs(graph)
+	want := map[string]string{
+		"10_KubernetesPlugin": "",
+		"20_ProdEdits":        "10_KubernetesPlugin",
+		"30_StagingEdits":     "10_KubernetesPlugin",
+		"40_Merge":            "20_ProdEdits,30_StagingEdits",
+		"50_Raw":              "",
+	}
+	if len(inputs) != len(want) {
+		t.Fatalf("StageDirInputs = %v, want %v", inputs, want)
+	}
+	for dir, wantInputs := range want {
+		got, ok := inputs[dir]
+		if !ok || strings.Join(got, ",") != wantInputs {
+			t.Errorf("inputs of %s = %v, want %q", dir, got, wantInputs)
+		}
+	}
+}
+
+func TestValidateInstructions_StageGraphErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  []StageEntry
+		wantErr string
+	}{
+		{
+			name:    "cycle",
+			stages:  []StageEntry{{Name: "A", Inputs: []string{"C"}}, {Name: "B", Inputs: []string{"A"}}, {Name: "C", Inputs: []string{"B"}}},
+			wantErr: "cycle: A -> C -> B -> A",
+		},
+		{
+			name:    "stage reads itself",
+			stages:  []StageEntry{{Name: "A", Inputs: []string{"A"}}},
+			wantErr: "cycle: A -> A",
+		},
+		{
+			name:    "unknown input",
+			stages:  []StageEntry{{Name: "A"}, {Name: "B", Inputs: []string{"missing"}}},
+			wantErr: `unknown input "missing"`,
+		},
+		{
+			name:    "export combined with a stage",
+			stages:  []StageEntry{{Name: "A"}, {Name: "B", Inputs: []string{"export", "A"}}},
+			wantErr: "cannot be combined",
+		},
+		{
+			name:    "input listed twice",
+			stages:  []StageEntry{{Name: "A", Output: "out"}, {Name: "B", Inputs: []string{"A", "out"}}},
+			wantErr: "more than once",
+		},
+		{
+			name:    "output is another stage name",
+			stages:  []StageEntry{{Name: "A", Output: "B"}, {Name: "B"}},
+			wantErr: "name of another stage",
+		},
+		{
+			name:    "duplicate output",
+			stages:  []StageEntry{{Name: "A", Output: "out"}, {Name: "B", Output: "out"}},
+			wantErr: "already the output",
+		},
+		{
+			name:    "reserved export name",
+			stages:  []StageEntry{{Name: "export"}},
+			wantErr: "reserved",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateInstructions(&InstructionsFile{Stages: tt.stages})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateInstructions error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package transform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/crane/internal/file"
+)
+
+// ConflictSuffix is appended to the path of a stage file whose user edits and regenerated
+// content overlap, for the file that shows both between conflict markers.
+const ConflictSuffix = ".conflict"
+
+// Conflict markers, in the diff3 style of git.
+const (
+	conflictYours     = "<<<<<<< yours\n"
+	conflictBase      = "||||||| generated before\n"
+	conflictSeparator = "=======\n"
+	conflictGenerated = ">>>>>>> generated now\n"
+)
+
+// maxMatchCells bounds the table matchLines builds. Files whose changed middle is larger
+// are compared as one changed block.
+const maxMatchCells = 1 << 22
+
+// isMergeable reports whether path, relative to the stage directory, is a file that merge
+// runs merge: kustomization.yaml and the files in patches/.
+func isMergeable(path string) bool {
+	if strings.HasSuffix(path, ConflictSuffix) {
+		return false
+	}
+	return path == "kustomization.yaml" || strings.HasPrefix(path, file.PatchesDirName+"/")
+}
+
+// readMergeableFiles returns the content of the mergeable files in stageDir by path.
+func readMergeableFiles(stageDir string) (map[string]string, error) {
+	files := map[string]string{}
+	paths := []string{"kustomization.yaml"}
+	entries, err := os.ReadDir(filepath.Join(stageDir, file.PatchesDirName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			paths = append(paths, file.PatchesDirName+"/"+entry.Name())
+		}
+	}
+	for _, path := range paths {
+		if !isMergeable(path) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(stageDir, filepath.FromSlash(path)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[path] = string(data)
+	}
+	return files, nil
+}
+
+// mergeStageFiles three-way merges the mergeable files of the stage in stageDir, which
+// holds the content crane generated now, with yours, the files as they were before the
+// stage was regenerated, on the base of what crane generated the time before. A file
+// takes the side that changed; when both did, their changes are merged line by line.
+// A file with overlapping changes keeps the regenerated content, so the stage still
+// matches its input/, and both versions are written to a conflict file. The paths of the
+// conflict files are returned.
+func mergeStageFiles(stageDir string, base, yours, generated map[string]string) ([]string, error) {
+	paths := map[string]bool{}
+	for _, files := range []map[string]string{base, yours, generated} {
+		for path := range files {
+			paths[path] = true
+		}
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var conflicts []string
+	for _, path := range sorted {
+		b, hasBase := base[path]
+		y, hasYours := yours[path]
+		g, hasGenerated := generated[path]
+		target := filepath.Join(stageDir, filepath.FromSlash(path))
+		switch {
+		case hasYours == hasBase && y == b, hasYours == hasGenerated && y == g:
+			// Only crane changed the file, or both made the same change: keep it.
+			continue
+		case hasGenerated == hasBase && g == b:
+			// Only you changed the file.
+			if err := restoreFile(target, y, hasYours); err != nil {
+				return nil, err
+			}
+			continue
+		case hasYours && hasGenerated:
+			merged, conflict := merge3(splitLines(b), splitLines(y), splitLines(g))
+			if !conflict {
+				if err := os.WriteFile(target, []byte(strings.Join(merged, "")), 0644); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := os.WriteFile(target+ConflictSuffix, []byte(strings.Join(merged, "")), 0644); err != nil {
+				return nil, err
+			}
+		default:
+			// Removed on one side and changed on the other.
+			hunk := conflictHunk(splitLines(y), splitLines(b), splitLines(g))
+			if err := os.WriteFile(target+ConflictSuffix, []byte(strings.Join(hunk, "")), 0644); err != nil {
+				return nil, err
+			}
+		}
+		conflicts = append(conflicts, path+ConflictSuffix)
+	}
+	return conflicts, nil
+}
+
+// restoreFile writes content to path, or removes path when the file should not exist.
+func restoreFile(path, content string, exists bool) error {
+	if !exists {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// splitLines splits s into lines that keep their line endings.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// merge3 merges yours and generated, both derived from base, line by line as diff3 does.
+// Blocks changed on one side take that side; blocks changed differently on both sides are
+// returned between conflict markers, and conflict is set.
+func merge3(base, yours, generated []string) (merged []string, conflict bool) {
+	matchYours := matchLines(base, yours)
+	matchGenerated := matchLines(base, generated)
+	i, j, k := 0, 0, 0
+	for {
+		// Find the next base line both sides kept.
+		b := i
+		for b < len(base) && (matchYours[b] < 0 || matchGenerated[b] < 0) {
+			b++
+		}
+		yoursEnd, generatedEnd := len(yours), len(generated)
+		if b < len(base) {
+			yoursEnd, generatedEnd = matchYours[b], matchGenerated[b]
+		}
+
+		baseBlock, yoursBlock, generatedBlock := base[i:b], yours[j:yoursEnd], generated[k:generatedEnd]
+		switch {
+		case equalLines(yoursBlock, baseBlock):
+			merged = append(merged, generatedBlock...)
+		case equalLines(generatedBlock, baseBlock), equalLines(yoursBlock, generatedBlock):
+			merged = append(merged, yoursBlock...)
+		default:
+			merged = append(merged, conflictHunk(yoursBlock, baseBlock, generatedBlock)...)
+			conflict = true
+		}
+
+		if b == len(base) {
+			return merged, conflict
+		}
+		merged = append(merged, base[b])
+		i, j, k = b+1, yoursEnd+1, generatedEnd+1
+	}
+}
+
+// conflictHunk returns the three versions of a block between conflict markers.
+func conflictHunk(yours, base, generated []string) []string {
+	hunk := []string{conflictYours}
+	hunk = appendTerminated(hunk, yours)
+	hunk = append(hunk, conflictBase)
+	hunk = appendTerminated(hunk, base)
+	hunk = append(hunk, conflictSeparator)
+	hunk = appendTerminated(hunk, generated)
+	return append(hunk, conflictGenerated)
+}
+
+// appendTerminated appends lines to hunk, ending the last one with a newline so the
+// next marker starts a line of its own.
+func appendTerminated(hunk, lines []string) []string {
+	for i, line := range lines {
+		if i == len(lines)-1 && !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		hunk = append(hunk, line)
+	}
+	return hunk
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLines returns, for each line of a, the index of the line of b it is paired with in
+// a longest common subsequence of a and b, or -1 for lines that are not in b.
+func matchLines(a, b []string) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		match[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		match[len(a)-1-suffix] = len(b) - 1 - suffix
+		suffix++
+	}
+
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 || n*m > maxMatchCells {
+		return match
+	}
+	// lengths[i][j] is the length of a longest common subsequence of a[i:] and b[j:].
+	lengths := make([][]int32, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case a[i] == b[j]:
+			match[prefix+i] = prefix + j
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return match
+}
+
+// changePaths returns the paths of changes as a comma-separated list.
+func changePaths(changes []StageFileChange) string {
+	paths := make([]string, len(changes))
+	for i, c := range changes {
+		paths[i] = c.String()
+	}
+	return strings.Join(paths, ", ")
+}
+
+// checkMergeable returns an error when the stage described by status cannot be merged:
+// it has unresolved conflict files, or user edits outside the files a merge merges. The
+// records in ignored/ are regenerated with the stage, so changes to them do not count.
+func checkMergeable(stage Stage, status StageStatus) error {
+	var conflicts, unmergeable []StageFileChange
+	for _, change := range status.Edited {
+		switch {
+		case strings.HasPrefix(change.Path, file.IgnoredDirName+"/"):
+			continue
+		case strings.HasSuffix(change.Path, ConflictSuffix):
+			conflicts = append(conflicts, change)
+		case !isMergeable(change.Path):
+			unmergeable = append(unmergeable, change)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("stage %s has unresolved merge conflicts (%s); resolve them and delete the %s files",
+			stage.DirName, changePaths(conflicts), ConflictSuffix)
+	}
+	if len(unmergeable) > 0 {
+		return fmt.Errorf("stage %s has user-edited files that cannot be merged (%s); only patches/ and kustomization.yaml are merged, use --overwrite to discard them",
+			stage.DirName, changePaths(unmergeable))
+	}
+	if status.Metadata.Generated == nil && len(status.Edited) > 0 {
+		return fmt.Errorf("stage %s has user-edited files (%s) but its metadata records no generated content to merge them with; use --overwrite to discard them",
+			stage.DirName, changePaths(status.Edited))
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMerge3(t *testing.T) {
+	base := "- op: add\n  path: /metadata/labels\n- op: remove\n  path: /status\n- op: replace\n  path: /spec/replicas\n  value: 1\n"
+	tests := []struct {
+		name         string
+		yours        string
+		generated    string
+		want         string
+		wantConflict bool
+	}{
+		{
+			name:      "only generated changed",
+			yours:     base,
+			generated: strings.Replace(base, "value: 1", "value: 2", 1),
+			want:      strings.Replace(base, "value: 1", "value: 2", 1),
+		},
+		{
+			name:      "only yours changed",
+			yours:     strings.Replace(base, "/status", "/status/conditions", 1),
+			generated: base,
+			want:      strings.Replace(base, "/status", "/status/conditions", 1),
+		},
+		{
+			name:      "changes in different places",
+			yours:     strings.Replace(base, "/metadata/labels", "/metadata/annotations", 1),
+			generated: strings.Replace(base, "value: 1", "value: 2", 1),
+			want:      "- op: add\n  path: /metadata/annotations\n- op: remove\n  path: /status\n- op: replace\n  path: /spec/replicas\n  value: 2\n",
+		},
+		{
+			name:      "same change on both sides",
+			yours:     strings.Replace(base, "value: 1", "value: 3", 1),
+			generated: strings.Replace(base, "value: 1", "value: 3", 1),
+			want:      strings.Replace(base, "value: 1", "value: 3", 1),
+		},
+		{
+			name:         "overlapping changes",
+			yours:        strings.Replace(base, "value: 1", "value: 3", 1),
+			generated:    strings.Replace(base, "value: 1", "value: 2", 1),
+			want:         strings.Replace(base, "  value: 1\n", conflictYours+"  value: 3\n"+conflictBase+"  value: 1\n"+conflictSeparator+"  value: 2\n"+conflictGenerated, 1),
+			wantConflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflict := merge3(splitLines(base), splitLines(tt.yours), splitLines(tt.generated))
+			if got := strings.Join(merged, ""); got != tt.want {
+				t.Errorf("merged:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if conflict != tt.wantConflict {
+				t.Errorf("conflict = %v, want %v", conflict, tt.wantConflict)
+			}
+		})
+	}
+}
+
+// TestRunMultiStage_Merge regenerates a hand-edited stage after the export changed and
+// checks the edits survive, then that overlapping edits are left as conflict files.
+func TestRunMultiStage_Merge(t *testing.T) {
+	tmpDir := t.TempDir()
+	exportDir := filepath.Join(tmpDir, "export")
+	transformDir := filepath.Join(tmpDir, "transform")
+	writeExport := func(names ...string) {
+		t.Helper()
+		if err := os.RemoveAll(exportDir); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(exportDir, "default"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			configMapYAML := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n  namespace: default\n"
+			if err := os.WriteFile(filepath.Join(exportDir, "default", name+".yaml"), []byte(configMapYAML), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeExport("a", "b")
+	if err := os.MkdirAll(filepath.Join(transformDir, "10_custom"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	o := &Orchestrator{
+		Log:                logger,
+		ExportDir:          exportDir,
+		TransformDir:       transformDir,
+		PluginDir:          "/nonexistent",
+		NewlyCreatedStages: map[string]bool{"10_custom": true},
+	}
+	if err := o.RunMultiStage(StageSelector{}); err != nil {
+		t.Fatalf("RunMultiStage failed: %v", err)
+	}
+	o.NewlyCreatedStages = nil
+	o.Merge = true
+
+	opts := file.PathOpts{TransformDir: transformDir}
+	stageDir := opts.GetStageDir("10_custom")
+	kustomizationPath := opts.GetKustomizationPath("10_custom")
+	readFile := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// Hand edits: a label for every resource and a patch of our own.
+	patch := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n  namespace: default\ndata:\n  edited: \"true\"\n"
+	if err := os.WriteFile(filepath.Join(opts.GetPatchesDir("10_custom"), "edit.yaml"), []byte(patch), 0644); err != nil {
+		t.Fatal(err)
+	}
+	edited := "labels:\n- pairs:\n    team: a\npatches:\n- path: patches/edit.yaml\n" + readFile(kustomizationPath)
+	if err := os.WriteFile(kustomizationPath, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The export gains an object: the regenerated kustomization lists it and keeps the edits.
+	writeExport("a", "b", "c")
+	if err := o.RunMultiStage(StageSelector{}); err != nil {
+		t.Fatalf("merge run failed: %v", err)
+	}
+	merged := readFile(kustomizationPath)
+	if !strings.HasPrefix(merged, "labels:\n- pairs:\n    team: a\n") || !strings.Contains(merged, "_c.yaml") {
+		t.Errorf("merged kustomization.yaml:\n%s\nwant the hand edits and the new ConfigMap", merged)
+	}
+	if got := readFile(filepath.Join(opts.GetPatchesDir("10_custom"), "edit.yaml")); got != patch {
+		t.Errorf("hand-written patch = %q, want it kept", got)
+	}
+	output := readFile(filepath.Join(opts.GetStageOutputDir("10_custom"), "default", "ConfigMap__v1_default_a.yaml"))
+	if !strings.Contains(output, "team: a") || !strings.Contains(output, "edited: \"true\"") {
+		t.Errorf("stage output:\n%s\nwant the hand edits applied", output)
+	}
+
+	// Annotating b by hand while the export drops it overlaps.
+	var annotated []string
+	for _, line := range splitLines(merged) {
+		if strings.Contains(line, "_b.yaml") {
+			line = strings.TrimSuffix(line, "\n") + " # keep until cutover\n"
+		}
+		annotated = append(annotated, line)
+	}
+	if err := os.WriteFile(kustomizationPath, []byte(strings.Join(annotated, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeExport("a", "c")
+	err := o.RunMultiStage(StageSelector{})
+	if err == nil || !strings.Contains(err.Error(), "kustomization.yaml"+ConflictSuffix) {
+		t.Fatalf("expected a merge conflict in kustomization.yaml, got %v", err)
+	}
+	if got := readFile(kustomizationPath); strings.Contains(got, "_b.yaml") {
+		t.Errorf("kustomization.yaml with a conflict:\n%s\nwant the regenerated content", got)
+	}
+	conflict := readFile(kustomizationPath + ConflictSuffix)
+	for _, want := range []string{conflictYours, "# keep until cutover", conflictBase, conflictSeparator, conflictGenerated} {
+		if !strings.Contains(conflict, want) {
+			t.Errorf("conflict file is missing %q:\n%s", want, conflict)
+		}
+	}
+
+	// Until the conflict file is resolved, merge runs stop.
+	if err := o.RunMultiStage(StageSelector{}); err == nil || !strings.Contains(err.Error(), "unresolved merge conflicts") {
+		t.Errorf("expected an unresolved conflicts error, got %v", err)
+	}
+	if err := os.Remove(filepath.Join(stageDir, "kustomization.yaml"+ConflictSuffix)); err != nil {
+		t.Fatal(err)
+	}
+}
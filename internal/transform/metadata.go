@@ -0,0 +1,316 @@
+package transform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cranelib "github.com/konveyor/crane-lib/transform"
+	"github.com/konveyor/crane/internal/archive"
+	"github.com/konveyor/crane/internal/file"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// StageMetadata records how crane transform generated a stage, in the stage's
+// .crane-metadata.json. Later runs compare it with the stage's current input to tell
+// whether the stage is stale, and with the files in the stage to tell which of them were
+// edited by hand.
+type StageMetadata struct {
+	CraneVersion string `json:"craneVersion"`
+	// Plugin is the stage plugin; nil for a pass-through stage.
+	Plugin *StagePlugin `json:"plugin,omitempty"`
+	// OptionalFlags are the optional flags the stage plugin was run with.
+	OptionalFlags map[string]string `json:"optionalFlags,omitempty"`
+	// Inputs are the directories (or the export archive) the stage read, relative to the
+	// transform directory when possible. Several inputs were merged in order.
+	Inputs []string `json:"inputs"`
+	// InputHash is the sha256 of the input resources, see HashResources.
+	InputHash string `json:"inputHash"`
+	// Files are the sha256 of the files crane wrote to the stage: input/, new/, patches/
+	// and kustomization.yaml, relative to the stage directory.
+	Files []file.FileDigest `json:"files"`
+	// Generated is the content crane wrote to the files a merge run merges, patches/ and
+	// kustomization.yaml, by path. It is the base of the next three-way merge.
+	Generated map[string]string `json:"generated,omitempty"`
+}
+
+// StagePlugin is the plugin a stage ran.
+type StagePlugin struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// StageStatus compares a stage with its metadata.
+type StageStatus struct {
+	Stage string
+	// Metadata is the recorded metadata, nil when the stage has none (it was never run, or
+	// was generated by an older crane).
+	Metadata *StageMetadata
+	// Stale says why the stage no longer matches its input, and is empty when it does.
+	Stale string
+	// Edited lists the files of the stage that were changed, removed, or added since crane
+	// wrote it.
+	Edited []StageFileChange
+}
+
+// StageFileChange is a file of a stage that differs from what crane wrote.
+type StageFileChange struct {
+	Path string
+	// Change is "edited", "removed" or "added".
+	Change string
+}
+
+func (c StageFileChange) String() string {
+	return c.Path + ": " + c.Change
+}
+
+// ReadStageMetadata reads the metadata of the stage in stageDir. It returns nil without
+// an error when the stage has no metadata.
+func ReadStageMetadata(stageDir string) (*StageMetadata, error) {
+	metadataPath := filepath.Join(stageDir, file.StageMetadataFileName)
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read stage metadata %q: %w", metadataPath, err)
+	}
+	m := &StageMetadata{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse stage metadata %q: %w", metadataPath, err)
+	}
+	return m, nil
+}
+
+// WriteStageMetadata writes m to the metadata of the stage in stageDir.
+func WriteStageMetadata(stageDir string, m *StageMetadata) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal stage metadata: %w", err)
+	}
+	metadataPath := filepath.Join(stageDir, file.StageMetadataFileName)
+	if err := os.WriteFile(metadataPath, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("write stage metadata %q: %w", metadataPath, err)
+	}
+	return nil
+}
+
+// DigestStageFiles returns the sha256 of the files crane writes to the stage in stageDir,
+// sorted by path: everything but the output/ directory and the stage metadata.
+func DigestStageFiles(stageDir string) ([]file.FileDigest, error) {
+	all, err := file.DigestExportFilesFS(os.DirFS(stageDir))
+	if err != nil {
+		return nil, fmt.Errorf("digest stage directory %q: %w", stageDir, err)
+	}
+	digests := []file.FileDigest{}
+	for _, d := range all {
+		if d.Path == file.StageMetadataFileName || strings.HasPrefix(d.Path, file.OutputDirName+"/") {
+			continue
+		}
+		digests = append(digests, d)
+	}
+	return digests, nil
+}
+
+// VerifyStageFiles compares the files in stageDir with the digests in m and returns the
+// files that were edited, removed, or added since crane wrote the stage.
+func VerifyStageFiles(stageDir string, m *StageMetadata) ([]StageFileChange, error) {
+	current, err := DigestStageFiles(stageDir)
+	if err != nil {
+		return nil, err
+	}
+	onDisk := make(map[string]string, len(current))
+	for _, d := range current {
+		onDisk[d.Path] = d.SHA256
+	}
+
+	edited := []StageFileChange{}
+	recorded := make(map[string]bool, len(m.Files))
+	for _, d := range m.Files {
+		recorded[d.Path] = true
+		sum, ok := onDisk[d.Path]
+		switch {
+		case !ok:
+			edited = append(edited, StageFileChange{Path: d.Path, Change: "removed"})
+		case sum != d.SHA256:
+			edited = append(edited, StageFileChange{Path: d.Path, Change: "edited"})
+		}
+	}
+	for _, d := range current {
+		if !recorded[d.Path] {
+			edited = append(edited, StageFileChange{Path: d.Path, Change: "added"})
+		}
+	}
+	return edited, nil
+}
+
+// HashResources returns the sha256 of resources. It does not depend on their order.
+func HashResources(resources []unstructured.Unstructured) (string, error) {
+	docs := make([]string, 0, len(resources))
+	for _, r := range resources {
+		data, err := json.Marshal(r.Object)
+		if err != nil {
+			return "", fmt.Errorf("marshal %s: %w", r.GetName(), err)
+		}
+		docs = append(docs, string(data))
+	}
+	sort.Strings(docs)
+	h := sha256.New()
+	for _, doc := range docs {
+		io.WriteString(h, doc)
+		io.WriteString(h, "\n")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// staleReason returns why a stage generated as recorded in m is stale for the input,
+// plugin, and optional flags in current, or an empty string when it is not.
+func (m *StageMetadata) staleReason(current *StageMetadata) string {
+	if m.InputHash != current.InputHash {
+		return "its input changed since its patches were generated"
+	}
+	switch {
+	case m.Plugin == nil && current.Plugin != nil:
+		return fmt.Sprintf("it now runs plugin %s", current.Plugin.Name)
+	case m.Plugin != nil && current.Plugin == nil:
+		return fmt.Sprintf("plugin %s is no longer run", m.Plugin.Name)
+	case m.Plugin != nil && *m.Plugin != *current.Plugin:
+		return fmt.Sprintf("plugin %s %s was replaced by %s %s", m.Plugin.Name, m.Plugin.Version, current.Plugin.Name, current.Plugin.Version)
+	}
+	if !sameFlags(m.OptionalFlags, current.OptionalFlags) {
+		return "its optional flags changed"
+	}
+	return ""
+}
+
+func sameFlags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// newStageMetadata returns the metadata of stage for a run of stagePlugin on
+// inputResources, read from inputs. Files are filled in once the stage is written.
+func (o *Orchestrator) newStageMetadata(stage Stage, stagePlugin cranelib.Plugin, inputs []string, inputResources []unstructured.Unstructured) (*StageMetadata, error) {
+	hash, err := HashResources(inputResources)
+	if err != nil {
+		return nil, fmt.Errorf("hash input resources: %w", err)
+	}
+	m := &StageMetadata{
+		CraneVersion: o.CraneVersion,
+		InputHash:    hash,
+	}
+	if stagePlugin != nil {
+		metadata := stagePlugin.Metadata()
+		m.Plugin = &StagePlugin{Name: metadata.Name, Version: metadata.Version}
+		m.OptionalFlags = o.resolveOptionalFlags(stage)
+	}
+	for _, input := range inputs {
+		if rel, err := filepath.Rel(o.TransformDir, input); err == nil {
+			input = rel
+		}
+		m.Inputs = append(m.Inputs, filepath.ToSlash(input))
+	}
+	return m, nil
+}
+
+// checkStage compares the stage in stageDir with current, the metadata of the run about
+// to regenerate it.
+func checkStage(stage Stage, stageDir string, current *StageMetadata) (StageStatus, error) {
+	status := StageStatus{Stage: stage.DirName}
+	previous, err := ReadStageMetadata(stageDir)
+	if err != nil || previous == nil {
+		status.Stale = "it has no " + file.StageMetadataFileName
+		return status, err
+	}
+	status.Metadata = previous
+	status.Stale = previous.staleReason(current)
+	status.Edited, err = VerifyStageFiles(stageDir, previous)
+	return status, err
+}
+
+// CheckStages compares the stages in transformDir with their metadata: it reads each
+// stage's recorded inputs again to tell whether the stage is stale, and lists the files
+// edited since crane transform wrote them. Stages without metadata are skipped. A stage
+// whose inputs cannot be read is logged and reported without a stale reason.
+func CheckStages(transformDir string, stages []Stage, log *logrus.Logger) ([]StageStatus, error) {
+	opts := file.PathOpts{TransformDir: transformDir}
+	var statuses []StageStatus
+	for _, stage := range stages {
+		stageDir := opts.GetStageDir(stage.DirName)
+		recorded, err := ReadStageMetadata(stageDir)
+		if err != nil {
+			return nil, err
+		}
+		if recorded == nil {
+			log.Debugf("Stage %s has no %s, not checking it", stage.DirName, file.StageMetadataFileName)
+			continue
+		}
+		status := StageStatus{Stage: stage.DirName, Metadata: recorded}
+		if status.Edited, err = VerifyStageFiles(stageDir, recorded); err != nil {
+			return nil, err
+		}
+		resources, err := loadRecordedInputs(transformDir, recorded.Inputs)
+		if err != nil {
+			log.Warnf("Stage %s: cannot read its input to check it is up to date: %v", stage.DirName, err)
+			statuses = append(statuses, status)
+			continue
+		}
+		current := *recorded
+		if current.InputHash, err = HashResources(resources); err != nil {
+			return nil, err
+		}
+		status.Stale = recorded.staleReason(&current)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// loadRecordedInputs loads the input resources of a stage from the inputs recorded in its
+// metadata, merged as RunMultiStage merges them.
+func loadRecordedInputs(transformDir string, inputs []string) ([]unstructured.Unstructured, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no inputs recorded")
+	}
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	o := &Orchestrator{Log: quiet}
+
+	dirs := make([]string, len(inputs))
+	for i, input := range inputs {
+		dirs[i] = filepath.FromSlash(input)
+		if !filepath.IsAbs(dirs[i]) {
+			dirs[i] = filepath.Join(transformDir, dirs[i])
+		}
+	}
+	if len(dirs) > 1 {
+		return o.mergeResourcesFromDirectories(Stage{}, dirs)
+	}
+	info, err := os.Stat(dirs[0])
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return o.loadResourcesFromDirectory(dirs[0])
+	}
+	fsys, err := archive.Open(dirs[0])
+	if err != nil {
+		return nil, err
+	}
+	return o.loadResourcesFromFS(fsys)
+}
@@ -0,0 +1,181 @@
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/crane/internal/file"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStageMetadata_StaleReason(t *testing.T) {
+	recorded := &StageMetadata{
+		InputHash:     "abc",
+		Plugin:        &StagePlugin{Name: "KubernetesPlugin", Version: "v1"},
+		OptionalFlags: map[string]string{"registry": "quay.io"},
+	}
+	tests := []struct {
+		name    string
+		current StageMetadata
+		want    string
+	}{
+		{
+			name:    "up to date",
+			current: StageMetadata{InputHash: "abc", Plugin: &StagePlugin{Name: "KubernetesPlugin", Version: "v1"}, OptionalFlags: map[string]string{"registry": "quay.io"}},
+		},
+		{
+			name:    "input changed",
+			current: StageMetadata{InputHash: "def", Plugin: &StagePlugin{Name: "KubernetesPlugin", Version: "v1"}, OptionalFlags: map[string]string{"registry": "quay.io"}},
+			want:    "input changed",
+		},
+		{
+			name:    "plugin upgraded",
+			current: StageMetadata{InputHash: "abc", Plugin: &StagePlugin{Name: "KubernetesPlugin", Version: "v2"}, OptionalFlags: map[string]string{"registry": "quay.io"}},
+			want:    "KubernetesPlugin v1 was replaced by KubernetesPlugin v2",
+		},
+		{
+			name:    "plugin removed",
+			current: StageMetadata{InputHash: "abc"},
+			want:    "no longer run",
+		},
+		{
+			name:    "optional flags changed",
+			current: StageMetadata{InputHash: "abc", Plugin: &StagePlugin{Name: "KubernetesPlugin", Version: "v1"}, OptionalFlags: map[string]string{"registry": "docker.io"}},
+			want:    "optional flags changed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := recorded.staleReason(&tt.current)
+			if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("staleReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunMultiStage_StageMetadata checks the metadata a run records, and that CheckStages
+// and StaleOnly runs tell stale stages and user-edited files apart.
+func TestRunMultiStage_StageMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	exportDir := filepath.Join(tmpDir, "export")
+	transformDir := filepath.Join(tmpDir, "transform")
+	writeExport := func(value string) {
+		t.Helper()
+		configMapYAML := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: default\ndata:\n  key: " + value + "\n"
+		if err := os.MkdirAll(filepath.Join(exportDir, "default"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(exportDir, "default", "configmap.yaml"), []byte(configMapYAML), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeExport("one")
+	if err := os.MkdirAll(filepath.Join(transformDir, "10_stage1"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	o := &Orchestrator{
+		Log:                logger,
+		ExportDir:          exportDir,
+		TransformDir:       transformDir,
+		PluginDir:          "/nonexistent",
+		CraneVersion:       "v9.9.9",
+		NewlyCreatedStages: map[string]bool{"10_stage1": true},
+	}
+	if err := o.RunMultiStage(StageSelector{}); err != nil {
+		t.Fatalf("RunMultiStage failed: %v", err)
+	}
+	o.NewlyCreatedStages = nil
+
+	opts := file.PathOpts{TransformDir: transformDir}
+	stageDir := opts.GetStageDir("10_stage1")
+	m, err := ReadStageMetadata(stageDir)
+	if err != nil || m == nil {
+		t.Fatalf("ReadStageMetadata = %v, %v", m, err)
+	}
+	if m.CraneVersion != "v9.9.9" || m.Plugin != nil || m.InputHash == "" {
+		t.Errorf("metadata = %+v", m)
+	}
+	if len(m.Inputs) != 1 || m.Inputs[0] != "../export" {
+		t.Errorf("Inputs = %v, want [../export]", m.Inputs)
+	}
+	var paths []string
+	for _, f := range m.Files {
+		paths = append(paths, f.Path)
+	}
+	if !strings.Contains(strings.Join(paths, ","), "kustomization.yaml") || strings.Contains(strings.Join(paths, ","), "output/") {
+		t.Errorf("Files = %v, want the stage files but not output/", paths)
+	}
+
+	stages, err := DiscoverStages(transformDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func() StageStatus {
+		t.Helper()
+		statuses, err := CheckStages(transformDir, stages, logger)
+		if err != nil || len(statuses) != 1 {
+			t.Fatalf("CheckStages = %v, %v", statuses, err)
+		}
+		return statuses[0]
+	}
+	if status := check(); status.Stale != "" || len(status.Edited) != 0 {
+		t.Errorf("fresh stage status = %+v", status)
+	}
+
+	// The export changes: the stage is stale and, with nothing edited, regenerated
+	// without --overwrite.
+	writeExport("two")
+	if status := check(); !strings.Contains(status.Stale, "input changed") {
+		t.Errorf("status after export change = %+v, want stale", status)
+	}
+	o.StaleOnly = true
+	if err := o.RunMultiStage(StageSelector{}); err != nil {
+		t.Fatalf("StaleOnly run failed: %v", err)
+	}
+	if status := check(); status.Stale != "" {
+		t.Errorf("status after StaleOnly run = %+v, want up to date", status)
+	}
+	output, err := os.ReadFile(filepath.Join(opts.GetStageOutputDir("10_stage1"), "default", "ConfigMap__v1_default_settings.yaml"))
+	if err != nil || !strings.Contains(string(output), "key: two") {
+		t.Errorf("stage output = %q, %v; want the new export data", output, err)
+	}
+
+	// A hand-edited stage that is up to date is kept as it is.
+	kustomizationPath := opts.GetKustomizationPath("10_stage1")
+	kustomization, err := os.ReadFile(kustomizationPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := append(kustomization, []byte("commonLabels:\n  edited: \"true\"\n")...)
+	if err := os.WriteFile(kustomizationPath, edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status := check(); len(status.Edited) != 1 || status.Edited[0].String() != "kustomization.yaml: edited" {
+		t.Errorf("Edited = %v, want the kustomization", status.Edited)
+	}
+	if err := o.RunMultiStage(StageSelector{}); err != nil {
+		t.Fatalf("StaleOnly run of an up-to-date stage failed: %v", err)
+	}
+	if data, _ := os.ReadFile(kustomizationPath); string(data) != string(edited) {
+		t.Errorf("up-to-date stage was rewritten")
+	}
+
+	// Once stale, it is only regenerated with --overwrite.
+	writeExport("three")
+	if err := o.RunMultiStage(StageSelector{}); err == nil || !strings.Contains(err.Error(), "kustomization.yaml: edited") {
+		t.Errorf("expected an error naming the edited file, got %v", err)
+	}
+	o.Overwrite = true
+	if err := o.RunMultiStage(StageSelector{}); err != nil {
+		t.Fatalf("StaleOnly run with Overwrite failed: %v", err)
+	}
+	if status := check(); status.Stale != "" || len(status.Edited) != 0 {
+		t.Errorf("status after overwrite = %+v", status)
+	}
+}
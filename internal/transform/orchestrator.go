@@ -2,11 +2,14 @@ package transform
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	cranelib "github.com/konveyor/crane-lib/transform"
@@ -15,7 +18,9 @@ import (
 	"github.com/konveyor/crane/internal/plugin"
 	"github.com/sirupsen/logrus"
 	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	errorsutil "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/yaml"
 )
 
@@ -31,6 +36,9 @@ type StageArtifact struct {
 type Orchestrator struct {
 	Log            *logrus.Logger
 	ExportDir      string
+	// ExportFS, when set, holds the export tree (e.g. an export archive) and is read
+	// instead of ExportDir by the first stage of the pipeline.
+	ExportFS       fs.FS
 	TransformDir   string
 	PluginDir      string
 	SkipPlugins    []string
@@ -43,6 +51,20 @@ type Orchestrator struct {
 	NewlyCreatedStages map[string]bool
 	// KustomizeArgs holds additional arguments for embedded kustomize (e.g. helm options)
 	KustomizeArgs []string
+	// StageInputs maps a stage directory name to the stage directories whose output the
+	// stage reads, from the instructions file graph. A stage mapped to an empty list reads
+	// the export; stages that are not in the map read the previous stage's output.
+	StageInputs map[string][]string
+	// Concurrency is the number of input resources a stage runs its plugin on at a time;
+	// 0 or 1 runs them one after another.
+	Concurrency int
+	// StaleOnly keeps the selected stages that are up to date with their input, plugin and
+	// optional flags, as recorded in their metadata, and regenerates the stale ones. A
+	// stale stage with no user-edited files is regenerated without Overwrite.
+	StaleOnly bool
+	// Merge regenerates stages that have metadata and three-way merges the user's edits
+	// to patches/ and kustomization.yaml back into them, instead of requiring Overwrite.
+	Merge bool
 }
 
 func (o *Orchestrator) validateStageOptionalFlags(stages []Stage) error {
@@ -124,7 +146,27 @@ func (o *Orchestrator) RunMultiStage(stageSelector StageSelector) error {
 
 		// Step 1: Determine input for this stage
 		var inputDir string
-		if i == 0 {
+		var mergedDirs []string
+		fromExport := false
+		if inputs, ok := o.StageInputs[stage.DirName]; ok {
+			if len(inputs) == 0 {
+				inputDir = o.ExportDir
+				fromExport = true
+				o.Log.Debugf("Stage %s input: export directory (%s)", stage.DirName, inputDir)
+			}
+			for _, input := range inputs {
+				dir := opts.GetStageOutputDir(input)
+				if _, err := os.Stat(dir); os.IsNotExist(err) {
+					return fmt.Errorf("stage %s requires output from stage %s, but output directory does not exist: %s",
+						stage.DirName, input, dir)
+				}
+				mergedDirs = append(mergedDirs, dir)
+			}
+			if len(mergedDirs) > 0 {
+				inputDir = strings.Join(mergedDirs, ", ")
+				o.Log.Debugf("Stage %s input: output of stage(s) %s", stage.DirName, strings.Join(inputs, ", "))
+			}
+		} else if i == 0 {
 			// First selected stage - check if it's actually the first in the full pipeline
 			// If not, use the previous stage's output instead of export
 			prevStage := GetPreviousStage(stages, stage)
@@ -141,6 +183,7 @@ func (o *Orchestrator) RunMultiStage(stageSelector StageSelector) error {
 			} else {
 				// This is the first stage in the pipeline - read from export directory
 				inputDir = o.ExportDir
+				fromExport = true
 				o.Log.Debugf("Stage %s input: export directory (%s)", stage.DirName, inputDir)
 			}
 		} else {
@@ -157,7 +200,16 @@ func (o *Orchestrator) RunMultiStage(stageSelector StageSelector) error {
 		}
 
 		// Step 2: Load input resources
-		inputResources, err := o.loadResourcesFromDirectory(inputDir)
+		var inputResources []unstructured.Unstructured
+		if fromExport && o.ExportFS != nil {
+			inputResources, err = o.loadResourcesFromFS(o.ExportFS)
+		} else if len(mergedDirs) > 1 {
+			inputResources, err = o.mergeResourcesFromDirectories(stage, mergedDirs)
+		} else if len(mergedDirs) == 1 {
+			inputResources, err = o.loadResourcesFromDirectory(mergedDirs[0])
+		} else {
+			inputResources, err = o.loadResourcesFromDirectory(inputDir)
+		}
 		if err != nil {
 			return fmt.Errorf("stage %s: failed to load input resources from %s: %w", stage.DirName, inputDir, err)
 		}
@@ -166,7 +218,29 @@ func (o *Orchestrator) RunMultiStage(stageSelector StageSelector) error {
 
 		// Step 3: Execute transform for this stage (generates transform artifacts)
 		// Note: Input resources will be written to input/ directory by executeStage via writer
-		if err := o.executeStage(stage, inputResources, allPlugins); err != nil {
+		stagePlugin, err := o.getPluginForStage(stage, allPlugins)
+		if err != nil {
+			return fmt.Errorf("stage %s: transform execution failed: %w", stage.DirName, err)
+		}
+		inputs := mergedDirs
+		if len(inputs) == 0 {
+			inputs = []string{inputDir}
+		}
+		record, err := o.newStageMetadata(stage, stagePlugin, inputs, inputResources)
+		if err != nil {
+			return fmt.Errorf("stage %s: %w", stage.DirName, err)
+		}
+		status, err := checkStage(stage, opts.GetStageDir(stage.DirName), record)
+		if err != nil {
+			return fmt.Errorf("stage %s: %w", stage.DirName, err)
+		}
+		var conflicts []string
+		if o.StaleOnly && status.Metadata != nil && status.Stale == "" {
+			o.Log.Infof("Stage %s is up to date, keeping it", stage.DirName)
+			for _, edit := range status.Edited {
+				o.Log.Infof("Stage %s: user-edited %s", stage.DirName, edit)
+			}
+		} else if conflicts, err = o.executeStage(stage, stagePlugin, inputResources, record, status); err != nil {
 			return fmt.Errorf("stage %s: transform execution failed: %w", stage.DirName, err)
 		}
 
@@ -186,56 +260,106 @@ func (o *Orchestrator) RunMultiStage(stageSelector StageSelector) error {
 		}
 
 		o.Log.Debugf("Stage %s: wrote output to %s", stage.DirName, stageOutputDir)
+
+		// Later stages would build on edits that are still in conflict
+		if len(conflicts) > 0 {
+			return fmt.Errorf("stage %s: %d merge conflict(s) in %s; resolve them, delete the %s files and re-run with --merge",
+				stage.DirName, len(conflicts), strings.Join(conflicts, ", "), ConflictSuffix)
+		}
 	}
 
 	o.Log.Infof("Successfully completed %d stage(s)", len(selectedStages))
 	return nil
 }
 
-// executeStage runs transform for a single stage
-func (o *Orchestrator) executeStage(stage Stage, inputResources []unstructured.Unstructured, allPlugins []cranelib.Plugin) error {
-	// Get the plugin for this stage (if any)
-	stagePlugin, err := o.getPluginForStage(stage, allPlugins)
-	if err != nil {
-		return err
-	}
-
-	// Transform all resources through the plugin (or pass-through if no plugin)
-	artifacts, err := o.transformResources(stage, stagePlugin, inputResources)
-	if err != nil {
-		return err
-	}
-
+// executeStage runs transform for a single stage and records it in the stage metadata.
+// status compares the stage on disk with record, the metadata of this run. With Merge,
+// it returns the conflict files left by merging user edits into the regenerated stage.
+func (o *Orchestrator) executeStage(stage Stage, stagePlugin cranelib.Plugin, inputResources []unstructured.Unstructured, record *StageMetadata, status StageStatus) ([]string, error) {
 	// Write stage output
 	opts := file.PathOpts{
 		TransformDir: o.TransformDir,
 		ExportDir:    o.ExportDir,
 	}
+	stageDir := opts.GetStageDir(stage.DirName)
 
 	// Determine write behavior based on stage type
 	// Newly created stages: always allow overwrite (just created, safe to populate)
+	// Merge runs: regenerate, then merge the user's edits back
 	// All other stages: respect --overwrite flag
 	var forceWrite bool
+	var yours map[string]string
 	if o.NewlyCreatedStages != nil && o.NewlyCreatedStages[stage.DirName] {
 		// Stage was just created in this run: safe to populate
 		forceWrite = true
 		o.Log.Debugf("Stage %s: allowing write (newly created in this run)", stage.DirName)
+	} else if o.Merge && status.Metadata != nil {
+		if err := checkMergeable(stage, status); err != nil {
+			return nil, err
+		}
+		// Without recorded content (and so without edits) there is nothing to merge
+		if status.Metadata.Generated != nil {
+			var err error
+			if yours, err = readMergeableFiles(stageDir); err != nil {
+				return nil, fmt.Errorf("failed to read stage files to merge: %w", err)
+			}
+		}
+		forceWrite = true
+		o.Log.Debugf("Stage %s: regenerating and merging user edits (--merge flag set)", stage.DirName)
 	} else {
 		// All stages (plugin and custom): respect --overwrite flag
 		forceWrite = o.Overwrite
 		if forceWrite {
 			o.Log.Debugf("Stage %s: allowing write (--overwrite flag set)", stage.DirName)
+		} else if o.StaleOnly && status.Metadata != nil && len(status.Edited) == 0 {
+			// Everything in the stage is as crane wrote it: nothing to lose
+			forceWrite = true
+			o.Log.Infof("Stage %s is stale (%s), regenerating it", stage.DirName, status.Stale)
+		} else if o.StaleOnly && status.Metadata != nil {
+			return nil, fmt.Errorf("stage %s is stale (%s) but has user-edited files (%s); use --merge to keep them or --overwrite to regenerate it",
+				stage.DirName, status.Stale, changePaths(status.Edited))
 		} else {
 			o.Log.Debugf("Stage %s: checking for empty directory (no --overwrite flag)", stage.DirName)
 		}
+		if forceWrite {
+			for _, edit := range status.Edited {
+				o.Log.Warnf("Stage %s: overwriting user-edited %s", stage.DirName, edit)
+			}
+		}
+	}
+
+	// Transform all resources through the plugin (or pass-through if no plugin)
+	artifacts, err := o.transformResources(stage, stagePlugin, inputResources)
+	if err != nil {
+		return nil, err
 	}
+	o.reportIgnoredOps(stage, artifacts)
 
 	writer := NewKustomizeWriter(opts, stage.DirName, o.Log)
 	if err := writer.WriteStage(artifacts, forceWrite); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	record.Files, err = DigestStageFiles(stageDir)
+	if err != nil {
+		return nil, err
+	}
+	record.Generated, err = readMergeableFiles(stageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []string
+	if yours != nil {
+		conflicts, err = mergeStageFiles(stageDir, status.Metadata.Generated, yours, record.Generated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge user edits: %w", err)
+		}
+		for _, conflict := range conflicts {
+			o.Log.Warnf("Stage %s: user edits conflict with the regenerated stage, see %s", stage.DirName, conflict)
+		}
+	}
+	return conflicts, WriteStageMetadata(stageDir, record)
 }
 
 // transformResources runs the plugin (if any) on all input resources
@@ -256,75 +380,186 @@ func (o *Orchestrator) transformResources(stage Stage, stagePlugin cranelib.Plug
 		OptionalFlags:    o.resolveOptionalFlags(stage),
 	}
 
+	results := o.runPlugins(runner, plugins, inputResources)
+	// Persistent plugins keep their processes for the whole stage; stop them now.
+	if closer, ok := stagePlugin.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			o.Log.Warnf("Stage %s: %v", stage.DirName, err)
+		}
+	}
+
+	// Artifacts are built in input order, whatever order the plugin runs finished in,
+	// so the stage directory is the same for any concurrency.
 	var artifacts []StageArtifact
+	var errs []error
+	for i, resource := range inputResources {
+		if results[i].err != nil {
+			errs = append(errs, fmt.Errorf("stage %s: failed to run transform for %s: %w", stage.DirName, o.formatResourceID(resource), results[i].err))
+			continue
+		}
+		resourceArtifacts, err := o.stageArtifacts(stage, resource, results[i].response)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		artifacts = append(artifacts, resourceArtifacts...)
+	}
+	if len(errs) > 0 {
+		return nil, errorsutil.NewAggregate(errs)
+	}
+
+	return artifacts, nil
+}
+
+// pluginResult is the response of a stage plugin for one input resource.
+type pluginResult struct {
+	response cranelib.RunnerResponse
+	err      error
+}
+
+// runPlugins runs plugins on every input resource, up to o.Concurrency resources at a
+// time, and returns the responses in input order.
+func (o *Orchestrator) runPlugins(runner cranelib.Runner, plugins []cranelib.Plugin, inputResources []unstructured.Unstructured) []pluginResult {
+	results := make([]pluginResult, len(inputResources))
+	concurrency := o.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(inputResources) {
+		concurrency = len(inputResources)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				response, err := runner.Run(inputResources[i], plugins)
+				results[i] = pluginResult{response: response, err: err}
+			}
+		}()
+	}
+	for i := range inputResources {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
 
-	for _, resource := range inputResources {
-		response, err := runner.Run(resource, plugins)
+// stageArtifacts returns the artifact of resource for the plugin response, followed by
+// the artifacts of the new resources the plugin generated from it.
+func (o *Orchestrator) stageArtifacts(stage Stage, resource unstructured.Unstructured, response cranelib.RunnerResponse) ([]StageArtifact, error) {
+	// Parse TransformFile (JSONPatch) to get patches
+	var patches jsonpatch.Patch
+	if len(response.TransformFile) > 2 && !response.HaveWhiteOut {
+		var err error
+		patches, err = jsonpatch.DecodePatch(response.TransformFile)
 		if err != nil {
 			resourceID := o.formatResourceID(resource)
-			return nil, fmt.Errorf("stage %s: failed to run transform for %s: %w", stage.DirName, resourceID, err)
+			return nil, fmt.Errorf("stage %s: failed to decode patches for %s: %w", stage.DirName, resourceID, err)
 		}
+	}
 
-		// Parse TransformFile (JSONPatch) to get patches
-		var patches jsonpatch.Patch
-		if len(response.TransformFile) > 2 && !response.HaveWhiteOut {
-			patches, err = jsonpatch.DecodePatch(response.TransformFile)
-			if err != nil {
-				resourceID := o.formatResourceID(resource)
-				return nil, fmt.Errorf("stage %s: failed to decode patches for %s: %w", stage.DirName, resourceID, err)
-			}
+	// Parse IgnoredPatches to get the operations the runner dropped as conflicting
+	ignoredOps := []cranelib.IgnoredOperation{}
+	if len(response.IgnoredPatches) > 0 {
+		if err := json.Unmarshal(response.IgnoredPatches, &ignoredOps); err != nil {
+			resourceID := o.formatResourceID(resource)
+			return nil, fmt.Errorf("stage %s: failed to decode ignored patches for %s: %w", stage.DirName, resourceID, err)
 		}
+	}
 
-		artifact := StageArtifact{
+	artifact := StageArtifact{
+		TransformArtifact: cranelib.TransformArtifact{
+			Resource:     resource,
+			HaveWhiteOut: response.HaveWhiteOut,
+			Patches:      patches,
+			IgnoredOps:   ignoredOps,
+			Target:       cranelib.DeriveTargetFromResource(resource),
+			PluginName:   stage.PluginName,
+		},
+	}
+
+	artifacts := []StageArtifact{artifact}
+
+	// Process new resources generated by the plugin
+	for i, newResource := range response.NewResources {
+		if newResource.GetKind() == "" {
+			return nil, fmt.Errorf("stage %s: new resource #%d missing kind", stage.DirName, i)
+		}
+		if newResource.GetName() == "" {
+			return nil, fmt.Errorf("stage %s: new resource #%d (%s) missing name", stage.DirName, i, newResource.GetKind())
+		}
+		if newResource.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("stage %s: new resource #%d (%s/%s) missing apiVersion", stage.DirName, i, newResource.GetKind(), newResource.GetName())
+		}
+
+		skeleton, newPatch, err := cranelib.SplitNewResourceToSkeletonAndPatch(newResource)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: failed to split new resource %s/%s: %w",
+				stage.DirName, newResource.GetKind(), newResource.GetName(), err)
+		}
+
+		newArtifact := StageArtifact{
 			TransformArtifact: cranelib.TransformArtifact{
-				Resource:     resource,
-				HaveWhiteOut: response.HaveWhiteOut,
-				Patches:      patches,
-				IgnoredOps:   []cranelib.IgnoredOperation{}, // TODO: Parse IgnoredPatches
-				Target:       cranelib.DeriveTargetFromResource(resource),
+				Resource:     skeleton,
+				HaveWhiteOut: false,
+				Patches:      newPatch,
+				IgnoredOps:   []cranelib.IgnoredOperation{},
+				Target:       cranelib.DeriveTargetFromResource(skeleton),
 				PluginName:   stage.PluginName,
 			},
+			IsNewResource: true,
 		}
+		artifacts = append(artifacts, newArtifact)
 
-		artifacts = append(artifacts, artifact)
-
-		// Process new resources generated by the plugin
-		for i, newResource := range response.NewResources {
-			if newResource.GetKind() == "" {
-				return nil, fmt.Errorf("stage %s: new resource #%d missing kind", stage.DirName, i)
-			}
-			if newResource.GetName() == "" {
-				return nil, fmt.Errorf("stage %s: new resource #%d (%s) missing name", stage.DirName, i, newResource.GetKind())
-			}
-			if newResource.GetAPIVersion() == "" {
-				return nil, fmt.Errorf("stage %s: new resource #%d (%s/%s) missing apiVersion", stage.DirName, i, newResource.GetKind(), newResource.GetName())
-			}
+		o.Log.Infof("Stage %s: plugin generated new resource: %s/%s/%s",
+			stage.DirName, newResource.GetKind(), newResource.GetNamespace(), newResource.GetName())
+	}
 
-			skeleton, newPatch, err := cranelib.SplitNewResourceToSkeletonAndPatch(newResource)
-			if err != nil {
-				return nil, fmt.Errorf("stage %s: failed to split new resource %s/%s: %w",
-					stage.DirName, newResource.GetKind(), newResource.GetName(), err)
-			}
+	return artifacts, nil
+}
 
-			newArtifact := StageArtifact{
-				TransformArtifact: cranelib.TransformArtifact{
-					Resource:     skeleton,
-					HaveWhiteOut: false,
-					Patches:      newPatch,
-					IgnoredOps:   []cranelib.IgnoredOperation{},
-					Target:       cranelib.DeriveTargetFromResource(skeleton),
-					PluginName:   stage.PluginName,
-				},
-				IsNewResource: true,
+// reportIgnoredOps logs, per resource, the patch operations of a stage that were not
+// applied because they conflicted with an operation that was. The writer records them in
+// the stage's ignored/ directory.
+func (o *Orchestrator) reportIgnoredOps(stage Stage, artifacts []StageArtifact) {
+	total := 0
+	for _, artifact := range artifacts {
+		if artifact.HaveWhiteOut || len(artifact.IgnoredOps) == 0 {
+			continue
+		}
+		var ops []string
+		for _, ignored := range artifact.IgnoredOps {
+			kept := describeOperation(ignored.SelectedPlugin)
+			for _, op := range ignored.IgnoredPlugins {
+				ops = append(ops, fmt.Sprintf("%s (conflicts with %s)", describeOperation(op), kept))
 			}
-			artifacts = append(artifacts, newArtifact)
-
-			o.Log.Infof("Stage %s: plugin generated new resource: %s/%s/%s",
-				stage.DirName, newResource.GetKind(), newResource.GetNamespace(), newResource.GetName())
 		}
+		total += len(ops)
+		o.Log.Warnf("Stage %s: %s: %d conflicting operation(s) not applied: %s",
+			stage.DirName, o.formatResourceID(artifact.Resource), len(ops), strings.Join(ops, "; "))
+	}
+	if total > 0 {
+		o.Log.Warnf("Stage %s: %d conflicting operation(s) not applied, recorded in %s/",
+			stage.DirName, total, filepath.Join(stage.DirName, file.IgnoredDirName))
 	}
+}
 
-	return artifacts, nil
+// describeOperation returns the operation, path, and plugin of op, e.g.
+// "replace /spec/replicas from OpenshiftPlugin".
+func describeOperation(op cranelib.PluginOperation) string {
+	kind, path := op.Operation.Kind(), "?"
+	if p, err := op.Operation.Path(); err == nil {
+		path = p
+	}
+	if op.PluginName == "" {
+		return fmt.Sprintf("%s %s", kind, path)
+	}
+	return fmt.Sprintf("%s %s from %s", kind, path, op.PluginName)
 }
 
 // formatResourceID returns a human-readable identifier for a resource
@@ -456,6 +691,53 @@ func (o *Orchestrator) loadResourcesFromDirectory(dir string) ([]unstructured.Un
 	return resources, nil
 }
 
+// mergeResourcesFromDirectories loads the resources of several stage outputs for a stage
+// that merges them. A resource found in more than one output is taken from the output
+// listed last, and a warning is logged when the copies differ.
+func (o *Orchestrator) mergeResourcesFromDirectories(stage Stage, dirs []string) ([]unstructured.Unstructured, error) {
+	var resources []unstructured.Unstructured
+	index := make(map[string]int)
+	source := make(map[string]string)
+	for _, dir := range dirs {
+		loaded, err := o.loadResourcesFromDirectory(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range loaded {
+			key := resource.GetNamespace() + "/" + file.GetResourceFilename(resource)
+			i, exists := index[key]
+			if !exists {
+				index[key] = len(resources)
+				source[key] = dir
+				resources = append(resources, resource)
+				continue
+			}
+			if !equality.Semantic.DeepEqual(resources[i].Object, resource.Object) {
+				o.Log.Warnf("Stage %s: %s differs between %s and %s; using %s",
+					stage.DirName, o.formatResourceID(resource), source[key], dir, dir)
+			}
+			resources[i] = resource
+			source[key] = dir
+		}
+	}
+	return resources, nil
+}
+
+// loadResourcesFromFS loads all Kubernetes resources from an export tree in fsys
+func (o *Orchestrator) loadResourcesFromFS(fsys fs.FS) ([]unstructured.Unstructured, error) {
+	files, err := file.ReadFilesFS(context.TODO(), fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []unstructured.Unstructured
+	for _, f := range files {
+		resources = append(resources, f.Unstructured)
+	}
+
+	return resources, nil
+}
+
 // writeResourcesToDirectory writes resources as individual YAML files to a directory
 func (o *Orchestrator) writeResourcesToDirectory(resources []unstructured.Unstructured, outputDir string) error {
 	// Clear output directory if it exists
@@ -2,14 +2,19 @@ package transform
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	cranelib "github.com/konveyor/crane-lib/transform"
 	"github.com/konveyor/crane/internal/file"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestFilterPluginsByStage(t *testing.T) {
@@ -2040,3 +2045,250 @@ resources: []
 		t.Errorf("Error message should mention at least one possible cause, got: %s", errMsg)
 	}
 }
+
+func TestRunMultiStage_ReadsExportFS(t *testing.T) {
+	transformDir := t.TempDir()
+	stageDir := filepath.Join(transformDir, "10_stage1")
+	if err := os.MkdirAll(stageDir, 0700); err != nil {
+		t.Fatalf("Failed to create stage dir: %v", err)
+	}
+	placeholder := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources: []
+`
+	if err := os.WriteFile(filepath.Join(stageDir, "kustomization.yaml"), []byte(placeholder), 0644); err != nil {
+		t.Fatalf("Failed to write kustomization: %v", err)
+	}
+
+	configMapYAML := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: from-archive
+  namespace: default
+`
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	o := &Orchestrator{
+		Log:          logger,
+		ExportDir:    filepath.Join(t.TempDir(), "export.tar.gz"),
+		ExportFS:     fstest.MapFS{"resources/default/cm.yaml": {Data: []byte(configMapYAML)}},
+		TransformDir: transformDir,
+		PluginDir:    "/nonexistent",
+		Overwrite:    true,
+	}
+	if err := o.RunMultiStage(StageSelector{}); err != nil {
+		t.Fatalf("RunMultiStage failed: %v", err)
+	}
+
+	opts := file.PathOpts{TransformDir: transformDir}
+	outputs, _ := filepath.Glob(filepath.Join(opts.GetStageOutputDir("10_stage1"), "default", "ConfigMap_*_from-archive.yaml"))
+	if len(outputs) != 1 {
+		t.Fatalf("expected the archived ConfigMap in stage output, got %v", outputs)
+	}
+}
+
+// TestRunMultiStage_StageInputs runs a branching and merging stage graph one stage at a
+// time, as crane transform does for an instructions file, and checks each stage read the
+// outputs it was given.
+func TestRunMultiStage_StageInputs(t *testing.T) {
+	tmpDir := t.TempDir()
+	exportDir := filepath.Join(tmpDir, "export")
+	transformDir := filepath.Join(tmpDir, "transform")
+	if err := os.MkdirAll(filepath.Join(exportDir, "default"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	configMapYAML := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: shared\n  namespace: default\ndata:\n  key: value\n"
+	if err := os.WriteFile(filepath.Join(exportDir, "default", "configmap.yaml"), []byte(configMapYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	o := &Orchestrator{
+		Log:          logger,
+		ExportDir:    exportDir,
+		TransformDir: transformDir,
+		PluginDir:    "/nonexistent",
+		Overwrite:    true,
+		StageInputs: map[string][]string{
+			"10_base":  {},
+			"20_prod":  {"10_base"},
+			"30_stage": {"10_base"},
+			"40_merge": {"20_prod", "30_stage"},
+		},
+	}
+	opts := file.PathOpts{TransformDir: transformDir, ExportDir: exportDir}
+	for _, stage := range []string{"10_base", "20_prod", "30_stage", "40_merge"} {
+		if err := os.MkdirAll(filepath.Join(transformDir, stage), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := o.RunMultiStage(StageSelector{Stages: []string{stage}}); err != nil {
+			t.Fatalf("stage %s: %v", stage, err)
+		}
+		if stage == "20_prod" {
+			// Only the prod branch gets this object, so the merge must union both branches.
+			extra := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: prod-only\n  namespace: default\n"
+			if err := os.WriteFile(filepath.Join(opts.GetStageOutputDir(stage), "default", "prod-only.yaml"), []byte(extra), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	merged, err := o.loadResourcesFromDirectory(opts.GetStageOutputDir("40_merge"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range merged {
+		names = append(names, r.GetName())
+	}
+	if strings.Join(names, ",") != "prod-only,shared" {
+		t.Errorf("merged stage output = %v, want prod-only and shared once each", names)
+	}
+
+	// The third stage reads the base stage, not the prod stage listed before it.
+	branch, err := o.loadResourcesFromDirectory(opts.GetStageOutputDir("30_stage"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branch) != 1 || branch[0].GetName() != "shared" {
+		t.Errorf("30_stage output = %v, want only shared", branch)
+	}
+
+	o.StageInputs["50_missing"] = []string{"45_absent"}
+	if err := os.MkdirAll(filepath.Join(transformDir, "50_missing"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.RunMultiStage(StageSelector{Stages: []string{"50_missing"}}); err == nil || !strings.Contains(err.Error(), "45_absent") {
+		t.Errorf("expected an error for a missing input stage output, got %v", err)
+	}
+}
+
+// annotatingPlugin annotates every resource with its name and fails for names starting
+// with "bad".
+type annotatingPlugin struct{}
+
+func (annotatingPlugin) Metadata() cranelib.PluginMetadata {
+	return cranelib.PluginMetadata{Name: "AnnotatePlugin", Version: "v1"}
+}
+
+func (annotatingPlugin) Run(request cranelib.PluginRequest) (cranelib.PluginResponse, error) {
+	name := request.GetName()
+	if strings.HasPrefix(name, "bad") {
+		return cranelib.PluginResponse{}, fmt.Errorf("cannot transform %s", name)
+	}
+	patch, err := jsonpatch.DecodePatch([]byte(fmt.Sprintf(`[{"op":"add","path":"/metadata/annotations","value":{"name":%q}}]`, name)))
+	if err != nil {
+		return cranelib.PluginResponse{}, err
+	}
+	return cranelib.PluginResponse{Version: "v1", Patches: patch}, nil
+}
+
+func TestTransformResources_Concurrency(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	stage := Stage{DirName: "10_AnnotatePlugin", Priority: 10, PluginName: "AnnotatePlugin"}
+
+	var inputs []unstructured.Unstructured
+	for i := 0; i < 50; i++ {
+		u := unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("default")
+		u.SetName(fmt.Sprintf("cm-%02d", i))
+		inputs = append(inputs, u)
+	}
+
+	sequential, err := (&Orchestrator{Log: logger}).transformResources(stage, annotatingPlugin{}, inputs)
+	if err != nil {
+		t.Fatalf("sequential transformResources: %v", err)
+	}
+	parallel, err := (&Orchestrator{Log: logger, Concurrency: 8}).transformResources(stage, annotatingPlugin{}, inputs)
+	if err != nil {
+		t.Fatalf("parallel transformResources: %v", err)
+	}
+	if len(parallel) != len(inputs) || !reflect.DeepEqual(sequential, parallel) {
+		t.Fatalf("parallel artifacts differ from sequential ones")
+	}
+	for i, a := range parallel {
+		if a.Resource.GetName() != inputs[i].GetName() {
+			t.Fatalf("artifact %d is %s, want %s", i, a.Resource.GetName(), inputs[i].GetName())
+		}
+	}
+
+	withBad := append([]unstructured.Unstructured{}, inputs...)
+	withBad[3].SetName("bad-one")
+	withBad[40].SetName("bad-two")
+	_, err = (&Orchestrator{Log: logger, Concurrency: 8}).transformResources(stage, annotatingPlugin{}, withBad)
+	if err == nil {
+		t.Fatal("expected an error for the failing resources")
+	}
+	for _, want := range []string{"ConfigMap/default/bad-one", "ConfigMap/default/bad-two"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestStageArtifacts_IgnoredOperations(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	stage := Stage{DirName: "10_KubernetesPlugin", Priority: 10, PluginName: "KubernetesPlugin"}
+	o := &Orchestrator{Log: logger}
+
+	resource := unstructured.Unstructured{}
+	resource.SetAPIVersion("apps/v1")
+	resource.SetKind("Deployment")
+	resource.SetNamespace("default")
+	resource.SetName("web")
+
+	response := cranelib.RunnerResponse{
+		TransformFile: []byte(`[{"op":"replace","path":"/spec/replicas","value":2}]`),
+		IgnoredPatches: []byte(`[{"selectedPlugin":{"pluginName":"KubernetesPlugin","operation":{"op":"replace","path":"/spec/replicas","value":2}},` +
+			`"ignoredPlugins":[{"pluginName":"KubernetesPlugin","operation":{"op":"replace","path":"/spec/replicas","value":3}}]}]`),
+	}
+	artifacts, err := o.stageArtifacts(stage, resource, response)
+	if err != nil {
+		t.Fatalf("stageArtifacts: %v", err)
+	}
+	if len(artifacts) != 1 || len(artifacts[0].IgnoredOps) != 1 || len(artifacts[0].IgnoredOps[0].IgnoredPlugins) != 1 {
+		t.Fatalf("artifacts = %+v, want one ignored operation", artifacts)
+	}
+	if got := describeOperation(artifacts[0].IgnoredOps[0].IgnoredPlugins[0]); got != "replace /spec/replicas from KubernetesPlugin" {
+		t.Errorf("describeOperation = %q", got)
+	}
+
+	tempDir := t.TempDir()
+	opts := file.PathOpts{TransformDir: filepath.Join(tempDir, "transform"), ExportDir: filepath.Join(tempDir, "export")}
+	if err := NewKustomizeWriter(opts, stage.DirName, logger).WriteStage(artifacts, false); err != nil {
+		t.Fatalf("WriteStage: %v", err)
+	}
+	entries, err := os.ReadDir(opts.GetIgnoredDir(stage.DirName))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ignored directory entries = %v, %v; want one file", entries, err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.GetPatchesDir(stage.DirName), entries[0].Name())); err != nil {
+		t.Errorf("ignored operations file %s is not named like the resource's patch: %v", entries[0].Name(), err)
+	}
+	ignored, err := os.ReadFile(filepath.Join(opts.GetIgnoredDir(stage.DirName), entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"selectedPlugin:", "ignoredPlugins:", "value: 3"} {
+		if !strings.Contains(string(ignored), want) {
+			t.Errorf("ignored operations file is missing %q:\n%s", want, ignored)
+		}
+	}
+	kustomization, err := os.ReadFile(opts.GetKustomizationPath(stage.DirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(kustomization), file.IgnoredDirName+"/") {
+		t.Errorf("kustomization.yaml references the ignored operations:\n%s", kustomization)
+	}
+
+	response.IgnoredPatches = []byte(`{"not":"a list"}`)
+	if _, err := o.stageArtifacts(stage, resource, response); err == nil || !strings.Contains(err.Error(), "failed to decode ignored patches for Deployment/default/web") {
+		t.Errorf("expected a decode error naming the resource, got %v", err)
+	}
+}
@@ -113,6 +113,10 @@ func (w *KustomizeWriter) WriteStage(artifacts []StageArtifact, force bool) erro
 		// Non-whiteout resources are active
 		activeResourcesMap[resourceID] = artifact.Resource
 
+		if err := w.writeIgnoredOps(artifact); err != nil {
+			return err
+		}
+
 		// Write patch if there are operations
 		if len(artifact.Patches) > 0 {
 			// Filter out remove operations for non-existent paths to prevent kustomize errors
@@ -397,6 +401,35 @@ func (w *KustomizeWriter) generateKustomizationWithComments(resources []string,
 	return []byte(result.String()), nil
 }
 
+// writeIgnoredOps writes the patch operations dropped for artifact to the stage's ignored/
+// directory, named like its patch file. It writes nothing when there are none.
+func (w *KustomizeWriter) writeIgnoredOps(artifact StageArtifact) error {
+	if len(artifact.IgnoredOps) == 0 {
+		return nil
+	}
+	ignoredDir := w.opts.GetIgnoredDir(w.stageName)
+	if err := os.MkdirAll(ignoredDir, 0700); err != nil {
+		return fmt.Errorf("failed to create ignored directory: %w", err)
+	}
+	ignoredYAML, err := yaml.Marshal(artifact.IgnoredOps)
+	if err != nil {
+		return fmt.Errorf("failed to serialize ignored operations for %s/%s/%s: %w",
+			artifact.Target.Kind, artifact.Target.Namespace, artifact.Target.Name, err)
+	}
+	filename := kustomize.GeneratePatchFilename(
+		artifact.Target.Group,
+		artifact.Target.Version,
+		artifact.Target.Kind,
+		artifact.Target.Name,
+		artifact.Target.Namespace,
+	)
+	ignoredPath := filepath.Join(ignoredDir, filename)
+	if err := os.WriteFile(ignoredPath, ignoredYAML, 0644); err != nil {
+		return fmt.Errorf("failed to write ignored operations file %s: %w", ignoredPath, err)
+	}
+	return nil
+}
+
 // checkStageDirectory checks if a stage directory exists and is non-empty
 // Returns an error if the directory exists and contains files (preventing accidental overwrites)
 func (w *KustomizeWriter) checkStageDirectory(stageDir string) error {
@@ -49,6 +49,11 @@ func MatchResultsFromIndex(entries []ManifestEntry, index DiscoveryIndex, log lo
 		results = append(results, result)
 	}
 
+	var warnings []string
+	for _, entry := range entries {
+		warnings = append(warnings, entry.Warnings...)
+	}
+
 	compatible, incompatible := 0, 0
 	for _, r := range results {
 		if r.Status == StatusOK {
@@ -63,6 +68,7 @@ func MatchResultsFromIndex(entries []ManifestEntry, index DiscoveryIndex, log lo
 		TotalScanned: len(results),
 		Compatible:   compatible,
 		Incompatible: incompatible,
+		Warnings:     warnings,
 	}
 }
 
@@ -49,6 +49,9 @@ func FormatTable(w io.Writer, report *ValidationReport) {
 	table.Render()
 	fmt.Fprintf(w, "\nSummary: %d scanned, %d compatible, %d incompatible\n",
 		report.TotalScanned, report.Compatible, report.Incompatible)
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
 	if report.HasIncompatible() {
 		fmt.Fprintf(w, "Result: FAILED — %d resource(s) incompatible with target cluster\n", report.Incompatible)
 	} else {
@@ -124,3 +124,12 @@ func TestFormatJSON_RoundTrip(t *testing.T) {
 		t.Fatalf("round-trip mismatch: got %+v, want %+v", decoded, *original)
 	}
 }
+
+func TestFormatTable_Warnings(t *testing.T) {
+	report := &ValidationReport{Warnings: []string{"Secret app/db in export/a.yaml has redacted values: data.password"}}
+	var buf bytes.Buffer
+	FormatTable(&buf, report)
+	if !strings.Contains(buf.String(), "Warning: Secret app/db") {
+		t.Errorf("table output missing warning, got:\n%s", buf.String())
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/konveyor/crane/internal/file"
+	"github.com/konveyor/crane/internal/secrets"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/yaml"
@@ -17,6 +20,10 @@ import (
 // ScanOptions configures which directories to scan for Kubernetes manifests.
 type ScanOptions struct {
 	Dirs []string
+	// FS is scanned in addition to Dirs, e.g. an export archive opened with
+	// archive.Open. Its files are reported as "<FSName>:<path>".
+	FS     fs.FS
+	FSName string
 }
 
 // manifestMeta holds the minimal fields we unmarshal from each YAML document.
@@ -24,7 +31,9 @@ type manifestMeta struct {
 	APIVersion string `json:"apiVersion"`
 	Kind       string `json:"kind"`
 	Metadata   struct {
-		Namespace string `json:"namespace"`
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
 	} `json:"metadata"`
 }
 
@@ -36,87 +45,19 @@ func ScanManifests(opts ScanOptions, log logrus.FieldLogger) ([]ManifestEntry, e
 
 	for _, dir := range opts.Dirs {
 		log.Debugf("Scanning directory: %s", dir)
-		if err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				if d.Name() == "failures" {
-					log.Debugf("Skipping failures/ directory: %s", path)
-					return filepath.SkipDir
-				}
-				return nil
-			}
-			ext := strings.ToLower(filepath.Ext(path))
-			if ext != ".yaml" && ext != ".yml" && ext != ".json" {
-				log.Debugf("Skipping non-manifest file: %s", path)
-				return nil
-			}
-
-			log.Debugf("Reading manifest file: %s", path)
-			data, err := os.ReadFile(path)
-			if err != nil {
-				return fmt.Errorf("read %s: %w", path, err)
-			}
-
-			docDecoder := yaml.NewDocumentDecoder(io.NopCloser(bytes.NewReader(data)))
-			docIdx := 0
-			for {
-				buf := make([]byte, len(data)+256)
-				n, err := docDecoder.Read(buf)
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					log.Warnf("skipping unparseable document #%d in %s: %v", docIdx+1, path, err)
-					docIdx++
-					continue
-				}
-				doc := buf[:n]
-				docIdx++
-				if len(bytes.TrimSpace(doc)) == 0 {
-					continue
-				}
-				decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(doc), n+256)
-				var meta manifestMeta
-				if err := decoder.Decode(&meta); err != nil {
-					if err != io.EOF {
-						log.Warnf("skipping unparseable document #%d in %s: %v", docIdx, path, err)
-					}
-					continue
-				}
-				if meta.APIVersion == "" || meta.Kind == "" {
-					log.Debugf("  Skipping document #%d in %s: missing apiVersion or kind", docIdx, path)
-					continue
-				}
-
-				gv, err := schema.ParseGroupVersion(meta.APIVersion)
-				if err != nil {
-					log.Warnf("skipping invalid apiVersion %q in %s: %v", meta.APIVersion, path, err)
-					continue
-				}
-
-				key := fmt.Sprintf("%s/%s/%s/%s", gv.Group, gv.Version, meta.Kind, meta.Metadata.Namespace)
-				if entry, ok := index[key]; ok {
-					entry.SourceFiles = append(entry.SourceFiles, path)
-					log.Debugf("  Duplicate GVK+ns %s (additional source: %s)", key, path)
-				} else {
-					index[key] = &ManifestEntry{
-						APIVersion:  meta.APIVersion,
-						Kind:        meta.Kind,
-						Group:       gv.Group,
-						Version:     gv.Version,
-						Namespace:   meta.Metadata.Namespace,
-						SourceFiles: []string{path},
-					}
-					log.Debugf("  Found %s/%s (namespace: %q) in %s", meta.APIVersion, meta.Kind, meta.Metadata.Namespace, path)
-				}
-			}
-			return nil
-		}); err != nil {
+		dir := dir
+		sourcePath := func(p string) string { return filepath.Join(dir, filepath.FromSlash(p)) }
+		if err := scanFS(index, os.DirFS(dir), sourcePath, log); err != nil {
 			return nil, fmt.Errorf("walking %s: %w", dir, err)
 		}
 	}
+	if opts.FS != nil {
+		log.Debugf("Scanning %s", opts.FSName)
+		sourcePath := func(p string) string { return opts.FSName + ":" + p }
+		if err := scanFS(index, opts.FS, sourcePath, log); err != nil {
+			return nil, fmt.Errorf("walking %s: %w", opts.FSName, err)
+		}
+	}
 
 	entries := make([]ManifestEntry, 0, len(index))
 	for _, e := range index {
@@ -139,3 +80,95 @@ func ScanManifests(opts ScanOptions, log logrus.FieldLogger) ([]ManifestEntry, e
 	return entries, nil
 }
 
+// scanFS adds the manifests found in fsys to index. sourcePath maps a path in fsys to
+// the path reported in logs and ManifestEntry.SourceFiles.
+func scanFS(index map[string]*ManifestEntry, fsys fs.FS, sourcePath func(string) string, log logrus.FieldLogger) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		path := sourcePath(name)
+		if d.IsDir() {
+			if d.Name() == file.FailuresDirName || file.IsDerivedExportDir(name) {
+				log.Debugf("Skipping %s/ directory: %s", d.Name(), path)
+				return fs.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			log.Debugf("Skipping non-manifest file: %s", path)
+			return nil
+		}
+
+		log.Debugf("Reading manifest file: %s", path)
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+
+		docDecoder := yaml.NewDocumentDecoder(io.NopCloser(bytes.NewReader(data)))
+		docIdx := 0
+		for {
+			buf := make([]byte, len(data)+256)
+			n, err := docDecoder.Read(buf)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Warnf("skipping unparseable document #%d in %s: %v", docIdx+1, path, err)
+				docIdx++
+				continue
+			}
+			doc := buf[:n]
+			docIdx++
+			if len(bytes.TrimSpace(doc)) == 0 {
+				continue
+			}
+			decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(doc), n+256)
+			var meta manifestMeta
+			if err := decoder.Decode(&meta); err != nil {
+				if err != io.EOF {
+					log.Warnf("skipping unparseable document #%d in %s: %v", docIdx, path, err)
+				}
+				continue
+			}
+			if meta.APIVersion == "" || meta.Kind == "" {
+				log.Debugf("  Skipping document #%d in %s: missing apiVersion or kind", docIdx, path)
+				continue
+			}
+
+			gv, err := schema.ParseGroupVersion(meta.APIVersion)
+			if err != nil {
+				log.Warnf("skipping invalid apiVersion %q in %s: %v", meta.APIVersion, path, err)
+				continue
+			}
+
+			key := fmt.Sprintf("%s/%s/%s/%s", gv.Group, gv.Version, meta.Kind, meta.Metadata.Namespace)
+			entry, ok := index[key]
+			if ok {
+				entry.SourceFiles = append(entry.SourceFiles, path)
+				log.Debugf("  Duplicate GVK+ns %s (additional source: %s)", key, path)
+			} else {
+				entry = &ManifestEntry{
+					APIVersion:  meta.APIVersion,
+					Kind:        meta.Kind,
+					Group:       gv.Group,
+					Version:     gv.Version,
+					Namespace:   meta.Metadata.Namespace,
+					SourceFiles: []string{path},
+				}
+				index[key] = entry
+				log.Debugf("  Found %s/%s (namespace: %q) in %s", meta.APIVersion, meta.Kind, meta.Metadata.Namespace, path)
+			}
+			if meta.APIVersion == "v1" && meta.Kind == "Secret" {
+				if fields := secrets.RedactedFields(meta.Metadata.Annotations); len(fields) > 0 {
+					warning := fmt.Sprintf("Secret %s/%s in %s has redacted values: %s", meta.Metadata.Namespace, meta.Metadata.Name, path, strings.Join(fields, ", "))
+					log.Warn(warning)
+					entry.Warnings = append(entry.Warnings, warning)
+				}
+			}
+		}
+		return nil
+	})
+}
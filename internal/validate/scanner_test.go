@@ -3,7 +3,9 @@ package validate
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/sirupsen/logrus"
 )
@@ -236,9 +238,74 @@ metadata:
 	}
 }
 
+func TestScanManifests_FS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"resources/prod/deploy.yaml":                       {Data: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: prod\n")},
+		"failures/prod/pods.yaml":                          {Data: []byte("apiVersion: v1\nkind: Pod\n")},
+		"pruned/prod.yaml":                                 {Data: []byte("- apiVersion: v1\n  kind: Pod\n")},
+		"versions/apps/v1beta2/resources/prod/deploy.yaml": {Data: []byte("apiVersion: apps/v1beta2\nkind: Deployment\nmetadata:\n  name: web\n  namespace: prod\n")},
+		"resources/pruned/cm.yaml":                         {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n  namespace: pruned\n")},
+	}
+	entries, err := ScanManifests(ScanOptions{FS: fsys, FSName: "export.tar.gz"}, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(entries), entries)
+	}
+	if entries[0].Namespace != "pruned" {
+		t.Fatalf("expected the pruned namespace to be scanned, got %+v", entries[0])
+	}
+	if got := entries[1].SourceFiles; len(got) != 1 || got[0] != "export.tar.gz:resources/prod/deploy.yaml" {
+		t.Fatalf("SourceFiles = %v", got)
+	}
+}
+
 func writeFile(t *testing.T, path, content string) {
 	t.Helper()
 	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestScanManifests_WarnsOnRedactedSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "secret.yaml"), `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: app
+  annotations:
+    crane.konveyor.io/redacted-fields: data.password,data.user
+data:
+  password: ""
+  user: ""
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: plain
+  namespace: app
+data:
+  token: dG9rZW4=
+`)
+	entries, err := ScanManifests(ScanOptions{Dirs: []string{dir}}, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if len(entries[0].Warnings) != 1 {
+		t.Fatalf("got warnings %v, want one for the redacted Secret", entries[0].Warnings)
+	}
+	if w := entries[0].Warnings[0]; !strings.Contains(w, "app/db") || !strings.Contains(w, "data.password, data.user") {
+		t.Errorf("unexpected warning %q", w)
+	}
+
+	report := MatchResultsFromIndex(entries, DiscoveryIndex{}, testLogger())
+	if len(report.Warnings) != 1 {
+		t.Errorf("report warnings = %v, want the redacted Secret", report.Warnings)
+	}
+}
@@ -10,6 +10,7 @@ type ManifestEntry struct {
 	Version     string   // parsed from APIVersion (e.g. "v1")
 	Namespace   string   // from metadata.namespace; empty for cluster-scoped
 	SourceFiles []string // which files contributed this entry
+	Warnings    []string // e.g. Secrets exported with redacted values
 }
 
 // ValidationStatus indicates whether a GVK is compatible with the target cluster.
@@ -40,6 +41,7 @@ type ValidationReport struct {
 	TotalScanned       int                `json:"totalScanned" yaml:"totalScanned"`
 	Compatible         int                `json:"compatible" yaml:"compatible"`
 	Incompatible       int                `json:"incompatible" yaml:"incompatible"`
+	Warnings           []string           `json:"warnings,omitempty" yaml:"warnings,omitempty"` // issues that do not fail validation
 }
 
 // HasIncompatible returns true if any resources are incompatible with the target.